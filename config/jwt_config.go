@@ -2,24 +2,60 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	logs "briefcash-jwt/internal/helper/loghelper"
 
 	env "github.com/joho/godotenv"
 )
 
+// Private key file identified by its key id (kid)
+type KeyFile struct {
+	KeyID string
+	Path  string
+}
+
 type Config struct {
-	JWTSecret     string
-	DbAddress     string
-	DbUsername    string
-	DbPassword    string
-	DbPort        string
-	DbName        string
-	RedisAddress  string
-	RedisPort     string
-	RedisPassword string
-	AppPort       string
+	JWTSecret              string
+	ValidationMode         string
+	RevocationSyncInterval time.Duration
+	DbAddress              string
+	DbUsername             string
+	DbPassword             string
+	DbPort                 string
+	DbName                 string
+	RedisAddress           string
+	RedisPort              string
+	RedisPassword          string
+	AppPort                string
+	JWEKeys                []KeyFile
+	TokenFormat            string
+	PasetoSecretKey        string
+	PasetoLocalKey         string
+	DPoPProofMaxAge        time.Duration
+	TLSCertFile            string
+	TLSKeyFile             string
+	TLSClientCAFile        string
+	TLSClientAuth          string
+	ExchangeAudiences      map[string][]string
+	ExchangeTTL            time.Duration
+	TransactionTokenTTL    time.Duration
+	AdminAPIKeys           map[string]string
+	MerchantKeyGrace       time.Duration
+	MerchantKeyRequired    bool
+	MerchantSyncInterval   time.Duration
+	TraceExporter          string
+	TrustedProxies         []netip.Prefix
+
+	TokenPurgeInterval    time.Duration
+	TokenPurgeRetention   time.Duration
+	TokenPurgeBatchSize   int
+	TokenPurgeArchive     bool
+	TokenHistoryRetention time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -30,22 +66,95 @@ func LoadConfig() (*Config, error) {
 
 	// Set credentials to struct
 	cfg := &Config{
-		JWTSecret:     os.Getenv("JWT_SECRET"),
-		DbAddress:     os.Getenv("DB_ADDRESS"),
-		DbPort:        os.Getenv("DB_PORT"),
-		DbUsername:    os.Getenv("DB_USERNAME"),
-		DbPassword:    os.Getenv("DB_PASSWORD"),
-		DbName:        os.Getenv("DB_NAME"),
-		RedisAddress:  os.Getenv("REDIS_ADDRESS"),
-		RedisPort:     os.Getenv("REDIS_PORT"),
-		RedisPassword: os.Getenv("REDIS_PASSWORD"),
+		JWTSecret: os.Getenv("JWT_SECRET"),
+		ValidationMode: func() string {
+			if value := os.Getenv("TOKEN_VALIDATION_MODE"); value != "" {
+				return value
+			}
+			return "stateful"
+		}(),
+		RevocationSyncInterval: getEnvDuration("REVOCATION_SYNC_INTERVAL", time.Minute),
+		TokenFormat: func() string {
+			if value := os.Getenv("TOKEN_FORMAT"); value != "" {
+				return value
+			}
+			return "jwt"
+		}(),
+		PasetoSecretKey: os.Getenv("PASETO_V4_SECRET_KEY"),
+		PasetoLocalKey:  os.Getenv("PASETO_V4_LOCAL_KEY"),
+		DPoPProofMaxAge: getEnvDuration("DPOP_PROOF_MAX_AGE", time.Minute),
+		DbAddress:       os.Getenv("DB_ADDRESS"),
+		DbPort:          os.Getenv("DB_PORT"),
+		DbUsername:      os.Getenv("DB_USERNAME"),
+		DbPassword:      os.Getenv("DB_PASSWORD"),
+		DbName:          os.Getenv("DB_NAME"),
+		RedisAddress:    os.Getenv("REDIS_ADDRESS"),
+		RedisPort:       os.Getenv("REDIS_PORT"),
+		RedisPassword:   os.Getenv("REDIS_PASSWORD"),
 		AppPort: func() string {
 			if value := os.Getenv("APP_PORT"); value != "" {
 				return value
 			}
 			return ":8080"
 		}(),
+		TokenPurgeInterval:    getEnvDuration("TOKEN_PURGE_INTERVAL", time.Hour),
+		TokenPurgeRetention:   getEnvDuration("TOKEN_PURGE_RETENTION", 24*time.Hour),
+		TokenPurgeBatchSize:   getEnvInt("TOKEN_PURGE_BATCH_SIZE", 1000),
+		TokenPurgeArchive:     getEnvBool("TOKEN_PURGE_ARCHIVE", true),
+		TokenHistoryRetention: getEnvDuration("TOKEN_HISTORY_RETENTION", 365*24*time.Hour),
+		TLSCertFile:           os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:            os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile:       os.Getenv("TLS_CLIENT_CA_FILE"),
+		ExchangeTTL:           getEnvDuration("TOKEN_EXCHANGE_TTL", 5*time.Minute),
+		TransactionTokenTTL:   getEnvDuration("TRANSACTION_TOKEN_TTL", 2*time.Minute),
+		MerchantKeyGrace:      getEnvDuration("MERCHANT_KEY_ROTATION_GRACE", 24*time.Hour),
+		MerchantKeyRequired:   getEnvBool("MERCHANT_KEY_REQUIRED", false),
+		MerchantSyncInterval:  getEnvDuration("MERCHANT_RECONCILE_INTERVAL", 5*time.Minute),
+		TLSClientAuth: func() string {
+			if value := os.Getenv("TLS_CLIENT_AUTH"); value != "" {
+				return value
+			}
+			return "optional"
+		}(),
+		TraceExporter: func() string {
+			if value := os.Getenv("TRACE_EXPORTER"); value != "" {
+				return value
+			}
+			return "none"
+		}(),
+	}
+
+	// Encryption keys for jwe token, format "kid1=/path/key1.pem,kid2=/path/key2.pem", first key is active
+	jweKeys, err := parseKeyFiles(os.Getenv("JWE_KEYS"))
+	if err != nil {
+		logs.Logger.WithError(err).Error("Invalid JWE_KEYS value")
+		return nil, err
+	}
+	cfg.JWEKeys = jweKeys
+
+	// Token exchange audiences, format "inquiry=inquiry:read;transfer=transfer:create transfer:read"
+	exchangeAudiences, err := parseAudienceScopes(os.Getenv("TOKEN_EXCHANGE_AUDIENCES"))
+	if err != nil {
+		logs.Logger.WithError(err).Error("Invalid TOKEN_EXCHANGE_AUDIENCES value")
+		return nil, err
 	}
+	cfg.ExchangeAudiences = exchangeAudiences
+
+	// Static admin keys, format "ops=key1,deploy=key2", name identifies the caller in logs
+	adminKeys, err := parseNamedValues(os.Getenv("ADMIN_API_KEYS"))
+	if err != nil {
+		logs.Logger.WithError(err).Error("Invalid ADMIN_API_KEYS value")
+		return nil, err
+	}
+	cfg.AdminAPIKeys = adminKeys
+
+	// Proxies allowed to forward client address and original request, format "10.0.0.0/8,192.168.1.10"
+	trustedProxies, err := parseProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		logs.Logger.WithError(err).Error("Invalid TRUSTED_PROXIES value")
+		return nil, err
+	}
+	cfg.TrustedProxies = trustedProxies
 
 	// Validate jwt secret and db host
 	if cfg.JWTSecret == "" {
@@ -53,6 +162,31 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET is not set in environment")
 	}
 
+	if cfg.ValidationMode != "stateful" && cfg.ValidationMode != "stateless" {
+		logs.Logger.Error("TOKEN_VALIDATION_MODE must be stateful or stateless")
+		return nil, fmt.Errorf("TOKEN_VALIDATION_MODE must be stateful or stateless")
+	}
+
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		logs.Logger.Error("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		logs.Logger.Error("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+
+	if cfg.TLSClientAuth != "optional" && cfg.TLSClientAuth != "required" {
+		logs.Logger.Error("TLS_CLIENT_AUTH must be optional or required")
+		return nil, fmt.Errorf("TLS_CLIENT_AUTH must be optional or required")
+	}
+
+	if cfg.TraceExporter != "none" && cfg.TraceExporter != "otlp" && cfg.TraceExporter != "stdout" {
+		logs.Logger.Error("TRACE_EXPORTER must be none, otlp or stdout")
+		return nil, fmt.Errorf("TRACE_EXPORTER must be none, otlp or stdout")
+	}
+
 	if cfg.DbAddress == "" {
 		logs.Logger.Error("DB_HOST is not set in environment")
 		return nil, fmt.Errorf("DB_HOST is not set in environment")
@@ -60,3 +194,129 @@ func LoadConfig() (*Config, error) {
 
 	return cfg, nil
 }
+
+// Read duration from environment (e.g. "30m", "24h"), fallback to default value when empty or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		logs.Logger.Warnf("Invalid %s value %q, using default %s", key, value, fallback)
+		return fallback
+	}
+
+	return duration
+}
+
+// Read positive integer from environment, fallback to default value when empty or invalid
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		logs.Logger.Warnf("Invalid %s value %q, using default %d", key, value, fallback)
+		return fallback
+	}
+
+	return number
+}
+
+// Read boolean from environment, fallback to default value when empty or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		logs.Logger.Warnf("Invalid %s value %q, using default %t", key, value, fallback)
+		return fallback
+	}
+
+	return flag
+}
+
+// Parse comma separated "kid=path" pairs
+func parseKeyFiles(value string) ([]KeyFile, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var keys []KeyFile
+	for _, pair := range strings.Split(value, ",") {
+		keyID, path, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || keyID == "" || path == "" {
+			return nil, fmt.Errorf("invalid key entry %q, expected kid=path", pair)
+		}
+		keys = append(keys, KeyFile{KeyID: keyID, Path: path})
+	}
+
+	return keys, nil
+}
+
+// Parse semicolon separated "audience=scope1 scope2" entries
+func parseAudienceScopes(value string) (map[string][]string, error) {
+	audiences := make(map[string][]string)
+	if strings.TrimSpace(value) == "" {
+		return audiences, nil
+	}
+
+	for _, entry := range strings.Split(value, ";") {
+		audience, scopes, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || audience == "" || strings.TrimSpace(scopes) == "" {
+			return nil, fmt.Errorf("invalid audience entry %q, expected audience=scope", entry)
+		}
+		audiences[audience] = strings.Fields(scopes)
+	}
+
+	return audiences, nil
+}
+
+// Parse comma separated "name=value" pairs
+func parseNamedValues(value string) (map[string]string, error) {
+	values := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return values, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		name, entry, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" || entry == "" {
+			return nil, fmt.Errorf("invalid entry for %q, expected name=value", name)
+		}
+		values[name] = entry
+	}
+
+	return values, nil
+}
+
+// Parse comma separated IP addresses or CIDR ranges, single address is a range of one
+func parseProxies(value string) ([]netip.Prefix, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy entry %q, expected IP address or CIDR range", entry)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
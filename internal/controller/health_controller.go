@@ -0,0 +1,35 @@
+package controller
+
+import (
+	dto "briefcash-jwt/internal/dto"
+	service "briefcash-jwt/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	Readiness *service.Readiness
+}
+
+func NewHealthController(readiness *service.Readiness) *HealthController {
+	return &HealthController{readiness}
+}
+
+// Liveness probe, only tells the process is serving requests, dependencies are not checked
+func (c *HealthController) Alive(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, dto.HealthResponse{Status: service.HealthStatusAlive})
+}
+
+// Readiness probe, reports every dependency check with its latency, fails while any of them is down
+func (c *HealthController) Ready(ctx *gin.Context) {
+	result := c.Readiness.Check(ctx.Request.Context())
+
+	status := http.StatusOK
+	if result.Status != service.HealthStatusReady {
+		status = http.StatusServiceUnavailable
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(status, result)
+}
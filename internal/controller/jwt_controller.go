@@ -7,6 +7,7 @@ import (
 	middleware "briefcash-jwt/internal/middleware"
 	service "briefcash-jwt/internal/service"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -45,12 +46,32 @@ func (c *TokenController) GenerateToken(ctx *gin.Context) {
 		return
 	}
 
+	tokenRequest.ClientIP = ctx.ClientIP()
+	tokenRequest.UserAgent = ctx.Request.UserAgent()
+
 	log.WithFields(logrus.Fields{
 		"step":     "generate_token",
 		"user_id":  tokenRequest.UserID,
 		"jwt_type": tokenRequest.Type,
 	}).Info("Processing generate JWT Token")
-	token, err := c.TokenService.GenerateToken(ctx.Request.Context(), tokenRequest)
+	bindingCtx := service.WithTokenBinding(ctx.Request.Context(), middleware.RequestBinding(ctx))
+	token, err := c.TokenService.GenerateToken(bindingCtx, tokenRequest)
+	if errors.Is(err, service.ErrInvalidDPoPProof) {
+		log.WithField("step", "generate_token").WithError(err).Warn("DPoP proof rejected")
+		invalidDPoPProof(ctx, http.StatusBadRequest)
+		return
+	}
+
+	if errors.Is(err, service.ErrInvalidClient) {
+		log.WithField("step", "generate_token").WithError(err).Warn("Merchant api key rejected")
+		ctx.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid merchant api key or secret",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
 	if err != nil {
 		log.WithField("step", "generate_token").WithError(err).Error("Failed to generate JWT Token")
 		ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
@@ -76,8 +97,9 @@ func (c *TokenController) ValidateToken(ctx *gin.Context) {
 		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("JWT Token successfully validated")
 	}()
 
-	log.WithField("step", "jwt_context").Info("Retrieving JWT Token from context")
-	tokenString, ok := middleware.GetTokenFromContext(ctx.Request.Context())
+	// Token and its proof of possession are validated by AuthMiddleware
+	log.WithField("step", "jwt_context").Info("Retrieving validated JWT Token from context")
+	token, ok := middleware.GetValidatedTokenFromContext(ctx.Request.Context())
 	if !ok {
 		log.WithField("step", "jwt_context").Error("JWT Token not found in context")
 		ctx.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
@@ -88,22 +110,6 @@ func (c *TokenController) ValidateToken(ctx *gin.Context) {
 		return
 	}
 
-	log.WithFields(logrus.Fields{
-		"step":  "jwt_validation",
-		"token": securityhelper.MaskToken(tokenString),
-	}).Info("Validating JWT Token")
-	token, err := c.TokenService.ValidateToken(ctx.Request.Context(), tokenString)
-
-	if err != nil {
-		log.WithField("step", "jwt_validation").WithError(err).Error("JWT Token failed to validate")
-		ctx.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
-			Status:  false,
-			Message: "JWT Token mismatched",
-			Data:    map[string]any{},
-		})
-		return
-	}
-
 	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
 		Status:  true,
 		Message: "SUCCESS",
@@ -187,7 +193,24 @@ func (c *TokenController) RefreshToken(ctx *gin.Context) {
 		"jwt_type": req.Type,
 	}).Info("Processing generate JWT Refresh Token")
 
-	token, err := c.TokenService.RefreshToken(ctx.Request.Context(), req.RefreshToken)
+	bindingCtx := service.WithTokenBinding(ctx.Request.Context(), middleware.RequestBinding(ctx))
+	token, err := c.TokenService.RefreshToken(bindingCtx, req.RefreshToken)
+	if errors.Is(err, service.ErrInvalidDPoPProof) {
+		log.WithField("step", "refresh_token").WithError(err).Warn("DPoP proof rejected")
+		invalidDPoPProof(ctx, http.StatusBadRequest)
+		return
+	}
+
+	if errors.Is(err, service.ErrCertificateMismatch) {
+		log.WithField("step", "refresh_token").WithError(err).Warn("Client certificate does not match token")
+		ctx.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Client certificate does not match token",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
 	if err != nil {
 		log.WithField("step", "refresh_token").WithError(err).Error("Failed to generate JWT Refresh Token")
 		ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
@@ -204,3 +227,144 @@ func (c *TokenController) RefreshToken(ctx *gin.Context) {
 		Data:    token,
 	})
 }
+
+// Issue token for service account using client credentials
+func (c *TokenController) GenerateServiceToken(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "service_token_controller")
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Service token request completed")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.ServiceTokenRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":            "generate_service_token",
+		"service_account": req.ClientID,
+		"scope":           req.Scope,
+		"client_ip":       ctx.ClientIP(),
+	}).Info("Processing generate service account token")
+
+	bindingCtx := service.WithTokenBinding(ctx.Request.Context(), middleware.RequestBinding(ctx))
+	token, err := c.TokenService.GenerateServiceToken(bindingCtx, req)
+	if err != nil {
+		log.WithField("step", "generate_service_token").WithError(err).Warn("Failed to generate service account token")
+
+		status, message := http.StatusInternalServerError, "Failed to generate service token, internal error"
+		switch {
+		case errors.Is(err, service.ErrInvalidRequest), errors.Is(err, service.ErrInvalidScope):
+			status, message = http.StatusBadRequest, err.Error()
+		case errors.Is(err, service.ErrInvalidClient):
+			status, message = http.StatusUnauthorized, "Invalid client credentials"
+		case errors.Is(err, service.ErrInvalidDPoPProof):
+			invalidDPoPProof(ctx, http.StatusBadRequest)
+			return
+		}
+
+		ctx.JSON(status, dto.JwtDataResponse{
+			Status:  false,
+			Message: message,
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    token,
+	})
+}
+
+// Exchange merchant token for a delegated token (RFC 8693), body may be form or JSON encoded
+func (c *TokenController) ExchangeToken(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "jwt_exchange_controller")
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Token exchange request completed")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding token exchange payload to Struct")
+	var req dto.TokenExchangeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode token exchange payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "invalid_request",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":     "exchange_token",
+		"audience": req.Audience,
+		"scope":    req.Scope,
+	}).Info("Processing token exchange")
+
+	bindingCtx := service.WithTokenBinding(ctx.Request.Context(), middleware.RequestBinding(ctx))
+	token, err := c.TokenService.ExchangeToken(bindingCtx, req)
+	if err != nil {
+		log.WithField("step", "exchange_token").WithError(err).Warn("Token exchange rejected")
+
+		status, code := http.StatusInternalServerError, "server_error"
+		switch {
+		case errors.Is(err, service.ErrUnsupportedGrantType):
+			status, code = http.StatusBadRequest, "unsupported_grant_type"
+		case errors.Is(err, service.ErrInvalidRequest):
+			status, code = http.StatusBadRequest, "invalid_request"
+		case errors.Is(err, service.ErrInvalidTarget):
+			status, code = http.StatusBadRequest, "invalid_target"
+		case errors.Is(err, service.ErrInvalidScope):
+			status, code = http.StatusBadRequest, "invalid_scope"
+		case errors.Is(err, service.ErrInvalidGrant):
+			status, code = http.StatusBadRequest, "invalid_grant"
+		}
+
+		ctx.JSON(status, dto.JwtDataResponse{
+			Status:  false,
+			Message: code,
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    token,
+	})
+}
+
+// Publish public encryption keys in JWKS format
+func (c *TokenController) EncryptionKeys(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, c.TokenService.EncryptionKeys())
+}
+
+// Respond with invalid_dpop_proof error, so client can retry with a new proof (RFC 9449 section 7.1)
+func invalidDPoPProof(ctx *gin.Context, status int) {
+	ctx.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
+	ctx.JSON(status, dto.JwtDataResponse{
+		Status:  false,
+		Message: "Invalid DPoP proof",
+		Data:    map[string]any{},
+	})
+}
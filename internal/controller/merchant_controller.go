@@ -1,10 +1,14 @@
 package controller
 
 import (
+	dto "briefcash-jwt/internal/dto"
 	jsonHelper "briefcash-jwt/internal/helper/jsonhelper"
 	logger "briefcash-jwt/internal/helper/loghelper"
+	middleware "briefcash-jwt/internal/middleware"
 	service "briefcash-jwt/internal/service"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 )
@@ -25,11 +29,13 @@ func (s *MerchantController) SyncMerchantCode(w http.ResponseWriter, r *http.Req
 		"endpoint": "/api/v1/merchant/sync",
 		"method":   r.Method,
 		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
 	})
 
 	logs.Info("Start syncing merchant code from db to redis")
 
-	if err := s.svc.CachingCode(ctx); err != nil {
+	result, err := s.svc.CachingCode(ctx)
+	if err != nil {
 		logs.WithFields(map[string]interface{}{
 			"duration_ms": time.Since(start).Milliseconds(),
 			"error":       err.Error(),
@@ -40,9 +46,11 @@ func (s *MerchantController) SyncMerchantCode(w http.ResponseWriter, r *http.Req
 
 	logs.WithFields(map[string]interface{}{
 		"duration_ms": time.Since(start).Milliseconds(),
+		"added":       len(result.Added),
+		"removed":     len(result.Removed),
 	}).Info("Merchant code sync completed successfully")
 
-	jsonHelper.WriteJson(w, http.StatusOK, "Succesfully synced merchant code")
+	writeMerchantData(w, result)
 }
 
 func (s *MerchantController) AddMerchantCode(w http.ResponseWriter, r *http.Request) {
@@ -55,6 +63,7 @@ func (s *MerchantController) AddMerchantCode(w http.ResponseWriter, r *http.Requ
 		"endpoint": "/api/v1/merchant/add",
 		"method":   r.Method,
 		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
 	})
 
 	var request struct {
@@ -87,7 +96,11 @@ func (s *MerchantController) AddMerchantCode(w http.ResponseWriter, r *http.Requ
 			"duration_ms": time.Since(start).Milliseconds(),
 			"error":       err.Error(),
 		}).Error("Failed to add merchant code to redis")
-		jsonHelper.WriteJsonError(w, http.StatusInternalServerError, "Failed to add merchant code")
+		if errors.Is(err, service.ErrMerchantCacheStale) {
+			jsonHelper.WriteJson(w, http.StatusAccepted, "Merchant code updated in db, redis sync pending")
+			return
+		}
+		jsonHelper.WriteJsonError(w, merchantErrorStatus(err), "Failed to add merchant code")
 		return
 	}
 
@@ -107,6 +120,7 @@ func (s *MerchantController) RemoveMerchantCode(w http.ResponseWriter, r *http.R
 		"endpoint": "/api/v1/merchant/remove",
 		"method":   r.Method,
 		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
 	})
 
 	var request struct {
@@ -138,7 +152,11 @@ func (s *MerchantController) RemoveMerchantCode(w http.ResponseWriter, r *http.R
 			"duration_ms": time.Since(start).Milliseconds(),
 			"error":       err.Error(),
 		}).Error("Failed to remove merchant code from redis")
-		jsonHelper.WriteJsonError(w, http.StatusInternalServerError, "Failed to remove merchant code")
+		if errors.Is(err, service.ErrMerchantCacheStale) {
+			jsonHelper.WriteJson(w, http.StatusAccepted, "Merchant code updated in db, redis sync pending")
+			return
+		}
+		jsonHelper.WriteJsonError(w, merchantErrorStatus(err), "Failed to remove merchant code")
 		return
 	}
 
@@ -148,3 +166,232 @@ func (s *MerchantController) RemoveMerchantCode(w http.ResponseWriter, r *http.R
 
 	jsonHelper.WriteJson(w, http.StatusOK, "Merchant code successfully removed from redis")
 }
+
+func (s *MerchantController) SuspendMerchant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+
+	logs := logger.Logger.WithFields(map[string]interface{}{
+		"endpoint": "/api/v1/merchant/suspend",
+		"method":   r.Method,
+		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
+	})
+
+	var request dto.MerchantSuspendRequest
+	if err := decodeMerchantRequest(r, &request); err != nil {
+		logs.WithField("error", err.Error()).Warn("Invalid body request while suspending merchant")
+		jsonHelper.WriteJsonError(w, http.StatusBadRequest, "Invalid body request")
+		return
+	}
+	request.SuspendedBy = adminCaller(ctx)
+
+	logs = logs.WithField("merchant_code", request.MerchantCode)
+	logs.Info("Suspending merchant")
+
+	merchant, err := s.svc.SuspendMerchant(ctx, request)
+	if err != nil {
+		logs.WithFields(map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"error":       err.Error(),
+		}).Error("Failed to suspend merchant")
+		if errors.Is(err, service.ErrMerchantCacheStale) {
+			jsonHelper.WriteJson(w, http.StatusAccepted, "Merchant suspended in db, redis sync pending")
+			return
+		}
+		writeMerchantError(w, err, "Failed to suspend merchant")
+		return
+	}
+
+	logs.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Merchant successfully suspended")
+
+	writeMerchantData(w, merchant)
+}
+
+func (s *MerchantController) CreateMerchant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+
+	logs := logger.Logger.WithFields(map[string]interface{}{
+		"endpoint": "/api/v1/merchant/create",
+		"method":   r.Method,
+		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
+	})
+
+	var request dto.MerchantCreateRequest
+	if err := decodeMerchantRequest(r, &request); err != nil {
+		logs.WithField("error", err.Error()).Warn("Invalid body request while creating merchant")
+		jsonHelper.WriteJsonError(w, http.StatusBadRequest, "Invalid body request")
+		return
+	}
+
+	logs = logs.WithField("merchant_code", request.Code)
+	logs.Info("Creating merchant")
+
+	merchant, err := s.svc.CreateMerchant(ctx, request)
+	if err != nil {
+		logs.WithFields(map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"error":       err.Error(),
+		}).Error("Failed to create merchant")
+		writeMerchantError(w, err, "Failed to create merchant")
+		return
+	}
+
+	logs.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Merchant successfully created")
+
+	writeMerchantData(w, merchant)
+}
+
+func (s *MerchantController) UpdateMerchant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+
+	logs := logger.Logger.WithFields(map[string]interface{}{
+		"endpoint": "/api/v1/merchant/update",
+		"method":   r.Method,
+		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
+	})
+
+	var request dto.MerchantUpdateRequest
+	if err := decodeMerchantRequest(r, &request); err != nil {
+		logs.WithField("error", err.Error()).Warn("Invalid body request while updating merchant")
+		jsonHelper.WriteJsonError(w, http.StatusBadRequest, "Invalid body request")
+		return
+	}
+
+	logs = logs.WithField("merchant_code", request.Code)
+	logs.Info("Updating merchant")
+
+	merchant, err := s.svc.UpdateMerchant(ctx, request)
+	if err != nil {
+		logs.WithFields(map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"error":       err.Error(),
+		}).Error("Failed to update merchant")
+		writeMerchantError(w, err, "Failed to update merchant")
+		return
+	}
+
+	logs.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Merchant successfully updated")
+
+	writeMerchantData(w, merchant)
+}
+
+func (s *MerchantController) GetMerchant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+
+	logs := logger.Logger.WithFields(map[string]interface{}{
+		"endpoint": "/api/v1/merchant/detail",
+		"method":   r.Method,
+		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
+	})
+
+	var request dto.MerchantGetRequest
+	if err := decodeMerchantRequest(r, &request); err != nil || request.Code == "" {
+		logs.Warn("Invalid body request while retrieving merchant")
+		jsonHelper.WriteJsonError(w, http.StatusBadRequest, "merchant_code is required")
+		return
+	}
+
+	merchant, err := s.svc.GetMerchant(ctx, request.Code)
+	if err != nil {
+		logs.WithFields(map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"error":       err.Error(),
+		}).Error("Failed to retrieve merchant")
+		writeMerchantError(w, err, "Failed to retrieve merchant")
+		return
+	}
+
+	logs.WithField("duration_ms", time.Since(start).Milliseconds()).Info("Merchant successfully retrieved")
+
+	writeMerchantData(w, merchant)
+}
+
+func (s *MerchantController) ListMerchants(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	start := time.Now()
+
+	logs := logger.Logger.WithFields(map[string]interface{}{
+		"endpoint": "/api/v1/merchant/list",
+		"method":   r.Method,
+		"trace_id": r.Header.Get("X-Request-ID"),
+		"admin":    adminCaller(ctx),
+	})
+
+	var request dto.MerchantListRequest
+	if err := decodeMerchantRequest(r, &request); err != nil {
+		logs.WithField("error", err.Error()).Warn("Invalid body request while listing merchants")
+		jsonHelper.WriteJsonError(w, http.StatusBadRequest, "Invalid body request")
+		return
+	}
+
+	merchants, err := s.svc.ListMerchants(ctx, request)
+	if err != nil {
+		logs.WithFields(map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"error":       err.Error(),
+		}).Error("Failed to list merchants")
+		writeMerchantError(w, err, "Failed to list merchants")
+		return
+	}
+
+	logs.WithFields(map[string]interface{}{
+		"duration_ms": time.Since(start).Milliseconds(),
+		"total":       merchants.Total,
+	}).Info("Merchant list successfully retrieved")
+
+	writeMerchantData(w, merchants)
+}
+
+func decodeMerchantRequest(r *http.Request, destination any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(destination)
+}
+
+func writeMerchantData(w http.ResponseWriter, data any) {
+	jsonHelper.WriteJson(w, http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    data,
+	})
+}
+
+// Validation errors carry their own message, other failures are reported generically
+func writeMerchantError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRequest):
+		jsonHelper.WriteJsonError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrMerchantExists):
+		jsonHelper.WriteJsonError(w, http.StatusConflict, "Merchant already exists")
+	case errors.Is(err, service.ErrMerchantNotFound):
+		jsonHelper.WriteJsonError(w, http.StatusNotFound, "Merchant not found")
+	case errors.Is(err, service.ErrMerchantNotActive):
+		jsonHelper.WriteJsonError(w, http.StatusConflict, "Merchant is not active")
+	default:
+		jsonHelper.WriteJsonError(w, http.StatusInternalServerError, message)
+	}
+}
+
+func merchantErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrMerchantNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrMerchantAlreadyActive), errors.Is(err, service.ErrMerchantNotActive):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Admin identity set by admin middleware, attributed to every merchant action
+func adminCaller(ctx context.Context) string {
+	admin, _ := middleware.GetAdminFromContext(ctx)
+	return admin
+}
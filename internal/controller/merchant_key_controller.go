@@ -0,0 +1,174 @@
+package controller
+
+import (
+	dto "briefcash-jwt/internal/dto"
+	loghelper "briefcash-jwt/internal/helper/loghelper"
+	service "briefcash-jwt/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MerchantKeyController struct {
+	KeyService service.MerchantKeyService
+}
+
+func NewMerchantKeyController(s service.MerchantKeyService) *MerchantKeyController {
+	return &MerchantKeyController{s}
+}
+
+func (c *MerchantKeyController) GenerateKey(ctx *gin.Context) {
+	c.issueKey(ctx, "generate", c.KeyService.GenerateKey)
+}
+
+func (c *MerchantKeyController) RotateKey(ctx *gin.Context) {
+	c.issueKey(ctx, "rotate", c.KeyService.RotateKey)
+}
+
+func (c *MerchantKeyController) issueKey(ctx *gin.Context, action string, issue func(ctx context.Context, merchantCode string) (*dto.MerchantKeyResponse, error)) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "merchant_key_"+action+"_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Infof("Merchant api key %s request completed", action)
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.MerchantKeyRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil || req.MerchantCode == "" {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "merchant_code is required",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":          action + "_api_key",
+		"merchant_code": req.MerchantCode,
+	}).Infof("Processing %s merchant api key", action)
+	key, err := issue(ctx.Request.Context(), req.MerchantCode)
+	if err != nil {
+		log.WithField("step", action+"_api_key").WithError(err).Errorf("Failed to %s merchant api key", action)
+		writeMerchantKeyError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    key,
+	})
+}
+
+func (c *MerchantKeyController) RevokeKey(ctx *gin.Context) {
+	start := time.Now()
+	admin := adminCaller(ctx.Request.Context())
+	log := loghelper.Logger.WithField("service", "merchant_key_revoke_controller").WithField("admin", admin)
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Merchant api key revoke request completed")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.MerchantKeyRevokeRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+	req.RevokedBy = admin
+
+	log.WithFields(logrus.Fields{
+		"step":          "revoke_api_key",
+		"merchant_code": req.MerchantCode,
+	}).Info("Processing revoke merchant api key")
+	result, err := c.KeyService.RevokeKey(ctx.Request.Context(), req)
+	if err != nil {
+		log.WithField("step", "revoke_api_key").WithError(err).Error("Failed to revoke merchant api key")
+		writeMerchantKeyError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    result,
+	})
+}
+
+func (c *MerchantKeyController) ListKeys(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "merchant_key_list_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Merchant api key list request completed")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.MerchantKeyRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil || req.MerchantCode == "" {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "merchant_code is required",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	keys, err := c.KeyService.ListKeys(ctx.Request.Context(), req.MerchantCode)
+	if err != nil {
+		log.WithField("step", "list_api_key").WithError(err).Error("Failed to list merchant api keys")
+		writeMerchantKeyError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    keys,
+	})
+}
+
+func writeMerchantKeyError(ctx *gin.Context, err error) {
+	status, message := http.StatusInternalServerError, "Failed to process merchant api key, internal error"
+
+	switch {
+	case errors.Is(err, service.ErrInvalidRequest):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrMerchantNotFound):
+		status, message = http.StatusNotFound, "Merchant not found"
+	case errors.Is(err, service.ErrMerchantKeyNotFound):
+		status, message = http.StatusNotFound, "Merchant api key not found"
+	case errors.Is(err, service.ErrMerchantKeyExists):
+		status, message = http.StatusConflict, "Merchant already has an active api key, rotate it instead"
+	}
+
+	ctx.JSON(status, dto.JwtDataResponse{
+		Status:  false,
+		Message: message,
+		Data:    map[string]any{},
+	})
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	dto "briefcash-jwt/internal/dto"
+	loghelper "briefcash-jwt/internal/helper/loghelper"
+	service "briefcash-jwt/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ServiceAccountController struct {
+	AccountService service.ServiceAccountService
+}
+
+func NewServiceAccountController(s service.ServiceAccountService) *ServiceAccountController {
+	return &ServiceAccountController{s}
+}
+
+func (c *ServiceAccountController) CreateServiceAccount(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "service_account_create_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Service account create request completed")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.ServiceAccountCreateRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":            "create_service_account",
+		"service_account": req.ClientID,
+	}).Info("Processing create service account")
+	account, err := c.AccountService.CreateServiceAccount(ctx.Request.Context(), req)
+	if err != nil {
+		log.WithField("step", "create_service_account").WithError(err).Error("Failed to create service account")
+		switch {
+		case errors.Is(err, service.ErrInvalidRequest):
+			ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+				Status:  false,
+				Message: err.Error(),
+				Data:    map[string]any{},
+			})
+		case errors.Is(err, service.ErrServiceAccountExists):
+			ctx.JSON(http.StatusConflict, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Service account already exists",
+				Data:    map[string]any{},
+			})
+		default:
+			ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Failed to create service account, internal error",
+				Data:    map[string]any{},
+			})
+		}
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    account,
+	})
+}
+
+func (c *ServiceAccountController) EnableServiceAccount(ctx *gin.Context) {
+	c.setServiceAccountActive(ctx, true)
+}
+
+func (c *ServiceAccountController) DisableServiceAccount(ctx *gin.Context) {
+	c.setServiceAccountActive(ctx, false)
+}
+
+func (c *ServiceAccountController) setServiceAccountActive(ctx *gin.Context, active bool) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "service_account_status_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Service account status request completed")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.ServiceAccountStatusRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil || req.ClientID == "" {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "client_id is required",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":            "service_account_status",
+		"service_account": req.ClientID,
+		"active":          active,
+	}).Info("Processing service account status")
+	if err := c.AccountService.SetServiceAccountActive(ctx.Request.Context(), req.ClientID, active); err != nil {
+		log.WithField("step", "service_account_status").WithError(err).Error("Failed to update service account status")
+		if errors.Is(err, service.ErrServiceAccountNotFound) {
+			ctx.JSON(http.StatusNotFound, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Service account not found",
+				Data:    map[string]any{},
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Failed to update service account, internal error",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    "service account updated",
+	})
+}
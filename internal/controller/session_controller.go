@@ -0,0 +1,206 @@
+package controller
+
+import (
+	dto "briefcash-jwt/internal/dto"
+	loghelper "briefcash-jwt/internal/helper/loghelper"
+	service "briefcash-jwt/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type SessionController struct {
+	TokenService service.TokenService
+}
+
+func NewSessionController(s service.TokenService) *SessionController {
+	return &SessionController{s}
+}
+
+func (c *SessionController) ListSessions(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "session_list_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Active sessions successfully retrieved")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.SessionListRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	if req.MerchantSettingsID == "" {
+		log.WithField("step", "decode_payload").Warn("Merchant settings id is empty")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "merchant_settings_id is required",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":                 "list_sessions",
+		"merchant_settings_id": req.MerchantSettingsID,
+	}).Info("Processing list active sessions")
+	sessions, err := c.TokenService.ListActiveSessions(ctx.Request.Context(), req.MerchantSettingsID)
+	if err != nil {
+		log.WithField("step", "list_sessions").WithError(err).Error("Failed to list active sessions")
+		ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Failed to list active sessions, internal error",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    sessions,
+	})
+}
+
+func (c *SessionController) TerminateSession(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "session_terminate_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Session successfully terminated")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.SessionTerminateRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	if req.SessionID <= 0 {
+		log.WithField("step", "decode_payload").Warn("Session id is empty")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "session_id is required",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":       "terminate_session",
+		"session_id": req.SessionID,
+	}).Info("Processing terminate session")
+	if err := c.TokenService.TerminateSession(ctx.Request.Context(), req.SessionID); err != nil {
+		log.WithField("step", "terminate_session").WithError(err).Error("Failed to terminate session")
+		if errors.Is(err, service.ErrSessionNotFound) {
+			ctx.JSON(http.StatusNotFound, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Session not found",
+				Data:    map[string]any{},
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Failed to terminate session, internal error",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    "session terminated",
+	})
+}
+
+func (c *SessionController) SessionHistory(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "session_history_controller").WithField("admin", adminCaller(ctx.Request.Context()))
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Session history successfully retrieved")
+	}()
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.SessionHistoryRequest
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&req); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	if req.MerchantSettingsID == "" || req.From == "" || req.To == "" {
+		log.WithField("step", "decode_payload").Warn("Mandatory field is empty")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "merchant_settings_id, from and to are required",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":                 "session_history",
+		"merchant_settings_id": req.MerchantSettingsID,
+		"from":                 req.From,
+		"to":                   req.To,
+	}).Info("Processing session history lookup")
+	histories, err := c.TokenService.ListSessionHistory(ctx.Request.Context(), req)
+	if err != nil {
+		log.WithField("step", "session_history").WithError(err).Error("Failed to retrieve session history")
+		if errors.Is(err, service.ErrInvalidRequest) {
+			ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+				Status:  false,
+				Message: err.Error(),
+				Data:    map[string]any{},
+			})
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Failed to retrieve session history, internal error",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    histories,
+	})
+}
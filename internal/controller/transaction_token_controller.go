@@ -0,0 +1,142 @@
+package controller
+
+import (
+	dto "briefcash-jwt/internal/dto"
+	loghelper "briefcash-jwt/internal/helper/loghelper"
+	middleware "briefcash-jwt/internal/middleware"
+	service "briefcash-jwt/internal/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sirupsen/logrus"
+)
+
+type TransactionTokenController struct {
+	TransactionService service.TransactionTokenService
+}
+
+func NewTransactionTokenController(s service.TransactionTokenService) *TransactionTokenController {
+	return &TransactionTokenController{s}
+}
+
+func (c *TransactionTokenController) IssueTransactionToken(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "transaction_token_issue_controller")
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Transaction token request completed")
+	}()
+
+	log.WithField("step", "jwt_context").Info("Retrieving validated JWT Token from context")
+	accessToken, ok := middleware.GetValidatedTokenFromContext(ctx.Request.Context())
+	if !ok {
+		log.WithField("step", "jwt_context").Error("JWT Token not found in context")
+		ctx.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+			Status:  false,
+			Message: "JWT Token not found",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.TransactionTokenRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":          "issue_transaction_token",
+		"merchant_code": req.MerchantCode,
+		"amount":        req.Amount,
+	}).Info("Processing issue transaction token")
+	token, err := c.TransactionService.IssueTransactionToken(ctx.Request.Context(), accessToken, req)
+	if err != nil {
+		log.WithField("step", "issue_transaction_token").WithError(err).Warn("Failed to issue transaction token")
+		transactionTokenError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    token,
+	})
+}
+
+func (c *TransactionTokenController) ConsumeTransactionToken(ctx *gin.Context) {
+	start := time.Now()
+	log := loghelper.Logger.WithField("service", "transaction_token_consume_controller")
+
+	defer func() {
+		log.WithField("elapsed_time", time.Since(start).Milliseconds()).Info("Transaction token consume request completed")
+	}()
+
+	log.WithField("step", "jwt_context").Info("Retrieving validated JWT Token from context")
+	accessToken, ok := middleware.GetValidatedTokenFromContext(ctx.Request.Context())
+	if !ok {
+		log.WithField("step", "jwt_context").Error("JWT Token not found in context")
+		ctx.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+			Status:  false,
+			Message: "JWT Token not found",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithField("step", "decode_payload").Info("Decoding JSON payload to Struct")
+	var req dto.TransactionTokenConsumeRequest
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		log.WithField("step", "decode_payload").WithError(err).Error("Failed to decode JSON payload")
+		ctx.JSON(http.StatusBadRequest, dto.JwtDataResponse{
+			Status:  false,
+			Message: "Invalid request body",
+			Data:    map[string]any{},
+		})
+		return
+	}
+
+	log.WithFields(logrus.Fields{
+		"step":          "consume_transaction_token",
+		"merchant_code": req.MerchantCode,
+		"amount":        req.Amount,
+	}).Info("Processing consume transaction token")
+	result, err := c.TransactionService.ConsumeTransactionToken(ctx.Request.Context(), accessToken, req)
+	if err != nil {
+		log.WithField("step", "consume_transaction_token").WithError(err).Warn("Transaction token rejected")
+		transactionTokenError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dto.JwtDataResponse{
+		Status:  true,
+		Message: "SUCCESS",
+		Data:    result,
+	})
+}
+
+func transactionTokenError(ctx *gin.Context, err error) {
+	status, message := http.StatusInternalServerError, "Failed to process transaction token, internal error"
+	switch {
+	case errors.Is(err, service.ErrInvalidRequest):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrTransactionTokenRejected):
+		status, message = http.StatusUnauthorized, err.Error()
+	}
+
+	ctx.JSON(status, dto.JwtDataResponse{
+		Status:  false,
+		Message: message,
+		Data:    map[string]any{},
+	})
+}
@@ -1,8 +1,12 @@
 package dto
 
 type JwtRequest struct {
-	UserID string `json:"user_id"`
-	Type   string `json:"type"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	APIKey    string `json:"api_key,omitempty"`
+	APISecret string `json:"api_secret,omitempty"`
+	ClientIP  string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type JwtRefreshToken struct {
@@ -4,6 +4,8 @@ type JwtResponse struct {
 	UserID       string `json:"user_id"`
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	TokenFormat  string `json:"token_format"`
+	TokenType    string `json:"token_type"`
 	CreatedAt    string `json:"created_at"`
 	ExpiresAt    string `json:"expires_at"`
 }
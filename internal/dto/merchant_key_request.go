@@ -0,0 +1,11 @@
+package dto
+
+type MerchantKeyRequest struct {
+	MerchantCode string `json:"merchant_code"`
+}
+
+type MerchantKeyRevokeRequest struct {
+	MerchantCode string `json:"merchant_code"`
+	APIKey       string `json:"api_key"`
+	RevokedBy    string `json:"-"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+type MerchantKeyResponse struct {
+	MerchantCode string `json:"merchant_code"`
+	APIKey       string `json:"api_key"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"created_at"`
+	ExpiresAt    string `json:"expires_at,omitempty"`
+	RevokedAt    string `json:"revoked_at,omitempty"`
+
+	// Only returned once when key is generated, never stored in plain text
+	APISecret string `json:"api_secret,omitempty"`
+}
+
+type MerchantKeyRevokeResponse struct {
+	MerchantCode  string `json:"merchant_code"`
+	APIKey        string `json:"api_key"`
+	RevokedAt     string `json:"revoked_at"`
+	RevokedTokens int    `json:"revoked_tokens"`
+}
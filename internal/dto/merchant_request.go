@@ -0,0 +1,70 @@
+package dto
+
+type MerchantCreateRequest struct {
+	Code        string                  `json:"merchant_code"`
+	CompanyName string                  `json:"company_name"`
+	Address     string                  `json:"address"`
+	Email       string                  `json:"email"`
+	Phone       string                  `json:"phone"`
+	Website     string                  `json:"website"`
+	Details     MerchantDetailsRequest  `json:"details"`
+	Settings    MerchantSettingsRequest `json:"settings"`
+}
+
+type MerchantDetailsRequest struct {
+	DirectorName     string `json:"director_name"`
+	DirectorIDType   string `json:"director_id_type"`
+	DirectorIDNumber string `json:"director_id_number"`
+	Country          string `json:"country"`
+	TaxNumber        string `json:"tax_number"`
+	BusinessType     string `json:"business_type"`
+	LegalID          string `json:"legal_id"`
+}
+
+type MerchantSettingsRequest struct {
+	ChannelID   string `json:"channel_id"`
+	PartnerID   string `json:"partner_id"`
+	TokenFormat string `json:"token_format"`
+}
+
+// Omitted fields are left unchanged
+type MerchantUpdateRequest struct {
+	Code        string                        `json:"merchant_code"`
+	CompanyName *string                       `json:"company_name"`
+	Address     *string                       `json:"address"`
+	Email       *string                       `json:"email"`
+	Phone       *string                       `json:"phone"`
+	Website     *string                       `json:"website"`
+	Details     *MerchantDetailsUpdateRequest `json:"details"`
+}
+
+type MerchantDetailsUpdateRequest struct {
+	DirectorName     *string `json:"director_name"`
+	DirectorIDType   *string `json:"director_id_type"`
+	DirectorIDNumber *string `json:"director_id_number"`
+	Country          *string `json:"country"`
+	TaxNumber        *string `json:"tax_number"`
+	BusinessType     *string `json:"business_type"`
+	LegalID          *string `json:"legal_id"`
+}
+
+type MerchantGetRequest struct {
+	Code string `json:"merchant_code"`
+}
+
+// Status is active or inactive, dates use yyyy-mm-dd and both ends are inclusive
+type MerchantListRequest struct {
+	Status     string `json:"status"`
+	JoinedFrom string `json:"joined_from"`
+	JoinedTo   string `json:"joined_to"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+}
+
+// Suspended until is RFC 3339, empty keeps merchant suspended until reactivated through /merchant/add
+type MerchantSuspendRequest struct {
+	MerchantCode   string `json:"merchant_code"`
+	Reason         string `json:"reason"`
+	SuspendedUntil string `json:"suspended_until"`
+	SuspendedBy    string `json:"-"`
+}
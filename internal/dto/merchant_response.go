@@ -0,0 +1,53 @@
+package dto
+
+type MerchantResponse struct {
+	Code        string                      `json:"merchant_code"`
+	CompanyName string                      `json:"company_name"`
+	Address     string                      `json:"address"`
+	Email       string                      `json:"email"`
+	Phone       string                      `json:"phone"`
+	Website     string                      `json:"website"`
+	IsActive    bool                        `json:"is_active"`
+	DateJoined  string                      `json:"date_joined"`
+	Details     *MerchantDetailsResponse    `json:"details,omitempty"`
+	Settings    *MerchantSettingsResponse   `json:"settings,omitempty"`
+	Suspension  *MerchantSuspensionResponse `json:"suspension,omitempty"`
+}
+
+type MerchantSuspensionResponse struct {
+	Reason         string `json:"reason"`
+	SuspendedBy    string `json:"suspended_by"`
+	SuspendedAt    string `json:"suspended_at"`
+	SuspendedUntil string `json:"suspended_until,omitempty"`
+}
+
+type MerchantDetailsResponse struct {
+	DirectorName     string `json:"director_name"`
+	DirectorIDType   string `json:"director_id_type"`
+	DirectorIDNumber string `json:"director_id_number"`
+	Country          string `json:"country"`
+	TaxNumber        string `json:"tax_number"`
+	BusinessType     string `json:"business_type"`
+	LegalID          string `json:"legal_id"`
+}
+
+// API credentials are never returned here
+type MerchantSettingsResponse struct {
+	ChannelID   string `json:"channel_id"`
+	PartnerID   string `json:"partner_id"`
+	TokenFormat string `json:"token_format"`
+}
+
+type MerchantListResponse struct {
+	Merchants []MerchantResponse `json:"merchants"`
+	Total     int64              `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
+
+// Result of merchant resync, codes that became active and inactive in redis
+type MerchantSyncResponse struct {
+	Total   int      `json:"total"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
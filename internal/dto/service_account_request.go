@@ -0,0 +1,17 @@
+package dto
+
+type ServiceAccountCreateRequest struct {
+	ClientID string   `json:"client_id"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+}
+
+type ServiceAccountStatusRequest struct {
+	ClientID string `json:"client_id"`
+}
+
+type ServiceTokenRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	Scope        string `json:"scope"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+type ServiceAccountResponse struct {
+	ClientID string   `json:"client_id"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+	IsActive bool     `json:"is_active"`
+
+	// Only returned once when account is created, never stored in plain text
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type ServiceTokenResponse struct {
+	ClientID    string `json:"client_id"`
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	TokenFormat string `json:"token_format"`
+	Scope       string `json:"scope"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+type SessionListRequest struct {
+	MerchantSettingsID string `json:"merchant_settings_id"`
+}
+
+type SessionTerminateRequest struct {
+	SessionID int64 `json:"session_id"`
+}
+
+type SessionHistoryRequest struct {
+	MerchantSettingsID string `json:"merchant_settings_id"`
+	From               string `json:"from"`
+	To                 string `json:"to"`
+	Limit              int    `json:"limit"`
+	Offset             int    `json:"offset"`
+}
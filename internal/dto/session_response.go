@@ -0,0 +1,24 @@
+package dto
+
+type SessionResponse struct {
+	SessionID          int64  `json:"session_id"`
+	MerchantSettingsID string `json:"merchant_settings_id"`
+	CreatedAt          string `json:"created_at"`
+	ExpiresAt          string `json:"expires_at"`
+	LastRefreshedAt    string `json:"last_refreshed_at"`
+	ClientIP           string `json:"client_ip"`
+	UserAgent          string `json:"user_agent"`
+}
+
+type SessionHistoryResponse struct {
+	SessionID          int64  `json:"session_id"`
+	MerchantSettingsID string `json:"merchant_settings_id"`
+	AccessTokenHash    string `json:"access_token_hash"`
+	CreatedAt          string `json:"created_at"`
+	ExpiresAt          string `json:"expires_at"`
+	LastRefreshedAt    string `json:"last_refreshed_at"`
+	ArchivedAt         string `json:"archived_at"`
+	ClientIP           string `json:"client_ip"`
+	UserAgent          string `json:"user_agent"`
+	IsRevoke           bool   `json:"is_revoke"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+// Token exchange request (RFC 8693 section 2.1), accepted as form or JSON body
+type TokenExchangeRequest struct {
+	GrantType          string `json:"grant_type" form:"grant_type"`
+	SubjectToken       string `json:"subject_token" form:"subject_token"`
+	SubjectTokenType   string `json:"subject_token_type" form:"subject_token_type"`
+	ActorToken         string `json:"actor_token" form:"actor_token"`
+	ActorTokenType     string `json:"actor_token_type" form:"actor_token_type"`
+	Audience           string `json:"audience" form:"audience"`
+	Scope              string `json:"scope" form:"scope"`
+	RequestedTokenType string `json:"requested_token_type" form:"requested_token_type"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// Token exchange response (RFC 8693 section 2.2), exchanged token has no refresh token
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+	Scope           string `json:"scope"`
+	Audience        string `json:"audience"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "encoding/json"
+
+// Transfer the token is bound to, amount is a decimal string to keep the hash stable
+type TransactionTokenRequest struct {
+	MerchantCode string          `json:"merchant_code"`
+	Amount       string          `json:"amount"`
+	Payload      json.RawMessage `json:"payload"`
+}
+
+type TransactionTokenConsumeRequest struct {
+	Token        string          `json:"transaction_token"`
+	MerchantCode string          `json:"merchant_code"`
+	Amount       string          `json:"amount"`
+	Payload      json.RawMessage `json:"payload"`
+}
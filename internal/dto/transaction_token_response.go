@@ -0,0 +1,16 @@
+package dto
+
+type TransactionTokenResponse struct {
+	Token       string `json:"transaction_token"`
+	PayloadHash string `json:"payload_hash"`
+	Amount      string `json:"amount"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+type TransactionTokenConsumeResponse struct {
+	MerchantCode string `json:"merchant_code"`
+	PayloadHash  string `json:"payload_hash"`
+	Amount       string `json:"amount"`
+	IssuedAt     string `json:"issued_at"`
+	ConsumedAt   string `json:"consumed_at"`
+}
@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+type JwtTokenHistory struct {
+	ID               int64      `gorm:"column:id;primaryKey"`
+	MerchantID       string     `gorm:"column:merchant_settings_id"`
+	AccessTokenHash  string     `gorm:"column:access_token_hash"`
+	RefreshTokenHash string     `gorm:"column:refresh_token_hash"`
+	ClientIP         string     `gorm:"column:client_ip"`
+	UserAgent        string     `gorm:"column:user_agent"`
+	CreatedAt        time.Time  `gorm:"column:created_at;primaryKey"`
+	ExpiresAt        time.Time  `gorm:"column:expires_at"`
+	RefreshedAt      *time.Time `gorm:"column:refreshed_at"`
+	IsRevoke         bool       `gorm:"column:is_revoke"`
+	ArchivedAt       time.Time  `gorm:"column:archived_at"`
+}
@@ -3,11 +3,21 @@ package entity
 import "time"
 
 type JwtToken struct {
-	ID           int64     `gorm:"column:id;primaryKey;autoIncrement"`
-	MerchantID   string    `gorm:"column:merchant_settings_id"`
-	AccessToken  string    `gorm:"column:access_token"`
-	RefreshToken string    `gorm:"column:refresh_token"`
-	ExpiresAt    time.Time `gorm:"column:expires_at"`
-	CreatedAt    time.Time `gorm:"column:created_at"`
-	IsRevoke     bool      `gorm:"column:is_revoke"`
+	ID               int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	MerchantID       string     `gorm:"column:merchant_settings_id"`
+	AccessToken      string     `gorm:"column:access_token"`
+	RefreshToken     string     `gorm:"column:refresh_token"`
+	ExpiresAt        time.Time  `gorm:"column:expires_at"`
+	RefreshExpiresAt *time.Time `gorm:"column:refresh_expires_at"`
+	CreatedAt        time.Time  `gorm:"column:created_at"`
+	RefreshedAt      *time.Time `gorm:"column:refreshed_at"`
+	ClientIP         string     `gorm:"column:client_ip"`
+	UserAgent        string     `gorm:"column:user_agent"`
+	TokenFormat      string     `gorm:"column:token_format"`
+	Claims           *string    `gorm:"column:claims"`
+	DPoPJKT          string     `gorm:"column:dpop_jkt"`
+	CertThumbprint   string     `gorm:"column:cert_thumbprint"`
+	ServiceAccountID *string    `gorm:"column:service_account_id"`
+	APIKeyID         *int64     `gorm:"column:api_key_id"`
+	IsRevoke         bool       `gorm:"column:is_revoke"`
 }
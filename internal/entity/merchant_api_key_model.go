@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type MerchantAPIKey struct {
+	ID           int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	MerchantCode string     `gorm:"column:merchant_code"`
+	APIKey       string     `gorm:"column:api_key"`
+	SecretHash   string     `gorm:"column:secret_hash"`
+	CreatedAt    time.Time  `gorm:"column:created_at"`
+	ExpiresAt    *time.Time `gorm:"column:expires_at"`
+	RevokedAt    *time.Time `gorm:"column:revoked_at"`
+	RevokedBy    *string    `gorm:"column:revoked_by"`
+}
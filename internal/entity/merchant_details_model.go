@@ -0,0 +1,13 @@
+package entity
+
+type MerchantDetails struct {
+	ID               int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	MerchantCode     string `gorm:"column:merchant_code"`
+	DirectorName     string `gorm:"column:director_name"`
+	DirectorIDType   string `gorm:"column:director_id_type"`
+	DirectorIDNumber string `gorm:"column:director_id_number"`
+	Country          string `gorm:"column:merchant_country"`
+	TaxNumber        string `gorm:"column:merchant_tax_number"`
+	BusinessType     string `gorm:"column:business_type"`
+	LegalID          string `gorm:"column:merchant_legal_id"`
+}
@@ -11,4 +11,10 @@ type Merchant struct {
 	Website     string     `gorm:"column:website"`
 	IsActive    bool       `gorm:"column:is_active"`
 	DateJoined  *time.Time `gorm:"column:date_joined"`
+
+	// Set while merchant is suspended, suspended_until empty means until reactivated manually
+	SuspendedReason *string    `gorm:"column:suspended_reason"`
+	SuspendedBy     *string    `gorm:"column:suspended_by"`
+	SuspendedAt     *time.Time `gorm:"column:suspended_at"`
+	SuspendedUntil  *time.Time `gorm:"column:suspended_until"`
 }
@@ -0,0 +1,10 @@
+package entity
+
+type MerchantSettings struct {
+	ID           int64  `gorm:"column:id;primaryKey;autoIncrement"`
+	MerchantCode string `gorm:"column:merchant_code"`
+	APIKey       string `gorm:"column:api_key"`
+	ChannelID    string `gorm:"column:channel_id"`
+	PartnerID    string `gorm:"column:partner_id"`
+	TokenFormat  string `gorm:"column:token_format"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+type ServiceAccount struct {
+	ID         int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	ClientID   string     `gorm:"column:client_id"`
+	Name       string     `gorm:"column:name"`
+	SecretHash string     `gorm:"column:secret_hash"`
+	Scopes     string     `gorm:"column:scopes"`
+	IsActive   bool       `gorm:"column:is_active"`
+	CreatedAt  time.Time  `gorm:"column:created_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at"`
+}
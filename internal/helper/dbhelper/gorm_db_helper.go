@@ -36,6 +36,8 @@ func NewGormAdapter(gormCfg GormConfig) (*GormAdapter, error) {
 		PreferSimpleProtocol: false,
 	}), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Connections are opened lazily, unreachable database at boot only delays readiness
+		DisableAutomaticPing: true,
 	})
 
 	if err != nil {
@@ -43,6 +45,17 @@ func NewGormAdapter(gormCfg GormConfig) (*GormAdapter, error) {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
 
+	if err := db.Use(queryMetrics{}); err != nil {
+		logs.Logger.WithError(err).Error("Failed to register query metrics")
+		return nil, fmt.Errorf("failed to register query metrics: %w", err)
+	}
+
+	// Query is traced with placeholders only, bound values may hold tokens and api keys
+	if err := db.Use(queryTracing{}); err != nil {
+		logs.Logger.WithError(err).Error("Failed to register query tracing")
+		return nil, fmt.Errorf("failed to register query tracing: %w", err)
+	}
+
 	// Create generic function
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -50,20 +63,34 @@ func NewGormAdapter(gormCfg GormConfig) (*GormAdapter, error) {
 		return nil, fmt.Errorf("failed to get generic database: %w", err)
 	}
 
+	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	adapter := &GormAdapter{DB: db}
+
 	// Ping database connection
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	if err := sqlDB.PingContext(ctx); err != nil {
-		logs.Logger.WithError(err).Error("Failed to ping database")
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	if err := adapter.Ping(ctx); err != nil {
+		logs.Logger.WithError(err).Warn("Database is not reachable yet, starting degraded")
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	return adapter, nil
+}
+
+func (h *GormAdapter) Ping(ctx context.Context) error {
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get generic database: %w", err)
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
 
-	return &GormAdapter{DB: db}, nil
+	return nil
 }
 
 func (h *GormAdapter) AutoMigrate(models ...interface{}) error {
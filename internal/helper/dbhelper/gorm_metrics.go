@@ -0,0 +1,86 @@
+package dbhelper
+
+import (
+	"errors"
+	"time"
+
+	metrics "briefcash-jwt/internal/helper/metricshelper"
+
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+// Time every gorm query, reported by operation and table
+type queryMetrics struct{}
+
+func (queryMetrics) Name() string {
+	return "query_metrics"
+}
+
+func (queryMetrics) Initialize(db *gorm.DB) error {
+	return registerAround(db, "metrics", func(string) func(*gorm.DB) { return startQuery }, finishQuery)
+}
+
+// Register callbacks running before and after every statement of each gorm operation
+func registerAround(db *gorm.DB, prefix string, before, after func(operation string) func(*gorm.DB)) error {
+	callback := db.Callback()
+	processors := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", callback.Create().Before("*").Register, callback.Create().After("*").Register},
+		{"query", callback.Query().Before("*").Register, callback.Query().After("*").Register},
+		{"update", callback.Update().Before("*").Register, callback.Update().After("*").Register},
+		{"delete", callback.Delete().Before("*").Register, callback.Delete().After("*").Register},
+		{"row", callback.Row().Before("*").Register, callback.Row().After("*").Register},
+		{"raw", callback.Raw().Before("*").Register, callback.Raw().After("*").Register},
+	}
+
+	for _, processor := range processors {
+		if err := processor.before(prefix+":before_"+processor.operation, before(processor.operation)); err != nil {
+			return err
+		}
+		if err := processor.after(prefix+":after_"+processor.operation, after(processor.operation)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func finishQuery(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		metrics.ObserveQuery(operation, queryTable(db), time.Since(start), queryError(db))
+	}
+}
+
+func queryTable(db *gorm.DB) string {
+	if db.Statement.Table == "" {
+		return "unknown"
+	}
+	return db.Statement.Table
+}
+
+// Lookup without result is an expected answer, not a failed query
+func queryError(db *gorm.DB) error {
+	if errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return db.Error
+}
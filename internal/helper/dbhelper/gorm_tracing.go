@@ -0,0 +1,59 @@
+package dbhelper
+
+import (
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const querySpanKey = "tracing:query_span"
+
+// Trace every gorm query as child of span carried by statement context.
+// Statement is recorded with placeholders only, bound values may hold tokens and api keys.
+type queryTracing struct{}
+
+func (queryTracing) Name() string {
+	return "query_tracing"
+}
+
+func (queryTracing) Initialize(db *gorm.DB) error {
+	return registerAround(db, "tracing", startQuerySpan, finishQuerySpan)
+}
+
+func startQuerySpan(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+
+		ctx, span := tracing.StartClient(db.Statement.Context, "db."+operation,
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+func finishQuerySpan(string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(querySpanKey)
+		if !ok {
+			return
+		}
+
+		span, ok := value.(trace.Span)
+		if !ok {
+			return
+		}
+
+		span.SetAttributes(
+			attribute.String("db.collection.name", queryTable(db)),
+			attribute.String("db.query.text", db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		tracing.End(span, queryError(db))
+	}
+}
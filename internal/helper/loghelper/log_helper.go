@@ -9,7 +9,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var Logger *logrus.Logger
+var Logger = logrus.New()
 
 // Build log initializer
 func InitLogger(logFile string, level logrus.Level) {
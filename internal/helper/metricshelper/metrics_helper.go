@@ -0,0 +1,136 @@
+package metricshelper
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "briefcash_jwt"
+
+	TokenIssued    = "issued"
+	TokenRefreshed = "refreshed"
+	TokenValidated = "validated"
+	TokenRevoked   = "revoked"
+
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	CacheHit   = "hit"
+	CacheMiss  = "miss"
+	CacheError = "error"
+)
+
+// Registry holding every collector of the service, exposed on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Handled http requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of handled http requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	tokenOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_operations_total",
+		Help:      "Tokens issued, refreshed, validated and revoked by outcome.",
+	}, []string{"operation", "outcome"})
+
+	tokenCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_cache_lookups_total",
+		Help:      "Redis lookups of token state during validation, a miss falls back to database.",
+	}, []string{"result"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of database queries by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table", "outcome"})
+
+	activeSessions = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_sessions",
+		Help:      "Non revoked and non expired tokens stored in database.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		tokenOperations,
+		tokenCacheLookups,
+		dbQueryDuration,
+		activeSessions,
+	)
+}
+
+// Handler serving every registered collector in prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Route is the registered route pattern, never the raw path, so label cardinality stays bounded
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func RecordToken(operation string, err error) {
+	tokenOperations.WithLabelValues(operation, outcome(err)).Inc()
+}
+
+func RecordCacheLookup(result string) {
+	tokenCacheLookups.WithLabelValues(result).Inc()
+}
+
+func ObserveQuery(operation, table string, duration time.Duration, err error) {
+	dbQueryDuration.WithLabelValues(operation, table, outcome(err)).Observe(duration.Seconds())
+}
+
+func SetActiveSessions(count int64) {
+	activeSessions.Set(float64(count))
+}
+
+// Register gauge read on every scrape, value must be cheap to compute
+func RegisterGaugeFunc(name, help string, value func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, value))
+}
+
+// Register counter read on every scrape from stats kept elsewhere, labels tell series of one name apart
+func RegisterCounterFunc(name, help string, labels map[string]string, value func() float64) {
+	Registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace:   namespace,
+		Name:        name,
+		Help:        help,
+		ConstLabels: labels,
+	}, value))
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
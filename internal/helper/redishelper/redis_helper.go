@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -34,18 +35,33 @@ func NewRedisAdapter(cfg *config.Config) (*redisAdapter, error) {
 		WriteTimeout: 2 * time.Second,
 	})
 
-	// Ping Redis connection with context
+	// Command is traced without its arguments, keys and values hold tokens
+	if err := redisotel.InstrumentTracing(client, redisotel.WithDBStatement(false)); err != nil {
+		logs.Logger.WithError(err).Error("Failed to instrument redis tracing")
+		return nil, fmt.Errorf("failed to instrument redis tracing: %w", err)
+	}
+
+	adapter := &redisAdapter{Client: client}
+
+	// Client reconnects on its own, unreachable redis at boot only delays readiness
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	if err := client.Ping(ctx).Err(); err != nil {
-		logs.Logger.WithError(err).Error("Failed to connect to redis")
-		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	if err := adapter.Ping(ctx); err != nil {
+		logs.Logger.WithError(err).Warn("Redis is not reachable yet, starting degraded")
+		return adapter, nil
 	}
 
 	logs.Logger.Info("Connected to Redis successfully")
 
-	return &redisAdapter{Client: client}, nil
+	return adapter, nil
+}
+
+func (r *redisAdapter) Ping(ctx context.Context) error {
+	if err := r.Client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return nil
 }
 
 func (r *redisAdapter) Close() error {
@@ -1,9 +1,12 @@
 package securityhelper
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Mask access token, using asterisk character for 4 digits in the beginning and 4 digits in the last
@@ -19,3 +22,26 @@ func HashToken(token string) string {
 	sum := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(sum[:8])
 }
+
+// Generate random identifier encoded as hex string from n random bytes
+func RandomID(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Hash client secret using bcrypt, only the hash is stored
+func HashSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash secret: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Compare client secret with its bcrypt hash in constant time
+func VerifySecret(hash, secret string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(secret)) == nil
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"briefcash-jwt/internal/dto"
+	logs "briefcash-jwt/internal/helper/loghelper"
+	service "briefcash-jwt/internal/service"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const adminKey contextKey = "admin"
+
+// Identity of admin caller, attributed to every admin action in logs
+func GetAdminFromContext(ctx context.Context) (string, bool) {
+	admin, ok := ctx.Value(adminKey).(string)
+	return admin, ok
+}
+
+// Middleware function to authenticate admin caller, using X-Admin-Key header
+// with a configured static key, or a service account token holding admin scope
+func (m *Middleware) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := logs.Logger.WithFields(logrus.Fields{
+			"path":      c.FullPath(),
+			"method":    c.Request.Method,
+			"client_ip": c.ClientIP(),
+		})
+
+		admin, ok := m.authenticateAdmin(c)
+		if !ok {
+			log.Warn("Admin authentication failed")
+			c.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Admin authentication required",
+				Data:    map[string]any{},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), adminKey, admin))
+		c.Set("admin", admin)
+
+		log = log.WithField("admin", admin)
+		log.Info("Admin request authenticated")
+
+		c.Next()
+
+		log.WithField("status", c.Writer.Status()).Info("Admin request completed")
+	}
+}
+
+func (m *Middleware) authenticateAdmin(c *gin.Context) (string, bool) {
+	if key := c.GetHeader("X-Admin-Key"); key != "" {
+		return m.matchAdminKey(key)
+	}
+
+	scheme, authToken, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || authToken == "" || (scheme != service.TokenTypeBearer && scheme != service.TokenTypeDPoP) || m.tokens == nil {
+		return "", false
+	}
+
+	ctx := service.WithTokenBinding(c.Request.Context(), RequestBinding(c))
+	token, err := m.tokens.ValidateToken(ctx, authToken)
+	if err != nil || !service.IsAdminToken(token) {
+		return "", false
+	}
+
+	// Scheme must match token binding (RFC 9449 section 7.1), client is told to use DPoP scheme
+	if scheme != service.AuthorizationScheme(token) {
+		c.Header("WWW-Authenticate", `DPoP error="invalid_token"`)
+		return "", false
+	}
+
+	// Account disabled or deleted after token was issued loses admin access right away
+	subject, _ := token.Claims.GetSubject()
+	if subject == "" || !m.tokens.IsServiceAccountActive(ctx, subject) {
+		return "", false
+	}
+
+	return "service_account:" + subject, true
+}
+
+// Compare key digest against every configured key, so lookup time doesn't depend on which key matched
+func (m *Middleware) matchAdminKey(key string) (string, bool) {
+	presented := sha256.Sum256([]byte(key))
+
+	matched := ""
+	for name, configured := range m.adminKeys {
+		expected := sha256.Sum256([]byte(configured))
+		if subtle.ConstantTimeCompare(presented[:], expected[:]) == 1 {
+			matched = name
+		}
+	}
+
+	if matched == "" {
+		return "", false
+	}
+
+	return "api_key:" + matched, true
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	service "briefcash-jwt/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token service accepting any token as admin token of one service account
+type stubTokenService struct {
+	service.TokenService
+	active bool
+
+	// DPoP key thumbprint the token is bound to, token is unbound when empty
+	jkt string
+
+	validations int
+}
+
+func (s *stubTokenService) ValidateToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
+	s.validations++
+	claims := jwt.MapClaims{"sub": "ops-console", "type": "service", "scope": "admin"}
+	if s.jkt != "" {
+		claims["cnf"] = map[string]interface{}{"jkt": s.jkt}
+	}
+	return &jwt.Token{Claims: claims}, nil
+}
+
+func (s *stubTokenService) IsServiceAccountActive(ctx context.Context, clientID string) bool {
+	return s.active && clientID == "ops-console"
+}
+
+func newAdminRouter(adminKeys map[string]string) (*gin.Engine, *string) {
+	return newAdminRouterWithTokens(adminKeys, nil)
+}
+
+func newAdminRouterWithTokens(adminKeys map[string]string, tokens service.TokenService) (*gin.Engine, *string) {
+	gin.SetMode(gin.TestMode)
+
+	var caller string
+	mw := NewMiddleware(nil, tokens, adminKeys)
+	router := gin.New()
+	router.POST("/api/v1/merchant/sync", mw.AdminMiddleware(), func(c *gin.Context) {
+		caller, _ = GetAdminFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	return router, &caller
+}
+
+func TestAdminMiddleware_APIKey(t *testing.T) {
+	router, caller := newAdminRouter(map[string]string{"ops": "0p5-s3cret"})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/merchant/sync", nil)
+	req.Header.Set("X-Admin-Key", "0p5-s3cret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	if *caller != "api_key:ops" {
+		t.Fatalf("Expected caller api_key:ops, got %q", *caller)
+	}
+}
+
+func TestAdminMiddleware_Rejected(t *testing.T) {
+	router, _ := newAdminRouter(map[string]string{"ops": "0p5-s3cret"})
+
+	for _, header := range []string{"X-Admin-Key", "Authorization"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/merchant/sync", nil)
+		req.Header.Set(header, "wrong")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for %s, got %d", header, rec.Code)
+		}
+	}
+}
+
+func TestAdminMiddleware_ServiceAccountToken(t *testing.T) {
+	for _, active := range []bool{true, false} {
+		router, caller := newAdminRouterWithTokens(nil, &stubTokenService{active: active})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/merchant/sync", nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if active && (rec.Code != http.StatusOK || *caller != "service_account:ops-console") {
+			t.Fatalf("Expected active account to pass, got status %d and caller %q", rec.Code, *caller)
+		}
+
+		if !active && rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected disabled account to be rejected, got status %d", rec.Code)
+		}
+	}
+}
+
+func TestAdminMiddleware_SchemeMustMatchBinding(t *testing.T) {
+	cases := []struct {
+		scheme string
+		jkt    string
+		status int
+	}{
+		{scheme: "Bearer", status: http.StatusOK},
+		{scheme: "DPoP", jkt: "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I", status: http.StatusOK},
+		{scheme: "Bearer", jkt: "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I", status: http.StatusUnauthorized},
+		{scheme: "DPoP", status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		router, _ := newAdminRouterWithTokens(nil, &stubTokenService{active: true, jkt: tc.jkt})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/merchant/sync", nil)
+		req.Header.Set("Authorization", tc.scheme+" admin-token")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Fatalf("Expected status %d for %s scheme with jkt %q, got %d", tc.status, tc.scheme, tc.jkt, rec.Code)
+		}
+
+		if tc.status == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "DPoP") {
+			t.Fatalf("Expected DPoP challenge for %s scheme with jkt %q", tc.scheme, tc.jkt)
+		}
+	}
+}
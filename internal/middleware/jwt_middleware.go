@@ -2,29 +2,47 @@ package middleware
 
 import (
 	"briefcash-jwt/internal/dto"
+	logs "briefcash-jwt/internal/helper/loghelper"
+	clock "briefcash-jwt/internal/helper/timehelper"
 	service "briefcash-jwt/internal/service"
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
 )
 
 type contextKey string
 
-const tokenKey contextKey = "token"
+const (
+	tokenKey          contextKey = "token"
+	validatedTokenKey contextKey = "validated_token"
+)
 
 func GetTokenFromContext(ctx context.Context) (string, bool) {
 	token, ok := ctx.Value(tokenKey).(string)
 	return token, ok
 }
 
+// Token validated by AuthMiddleware, handlers read its claims instead of validating it again
+func GetValidatedTokenFromContext(ctx context.Context) (*jwt.Token, bool) {
+	token, ok := ctx.Value(validatedTokenKey).(*jwt.Token)
+	return token, ok && token != nil
+}
+
 type Middleware struct {
-	svc service.MerchantService
+	svc       service.MerchantService
+	tokens    service.TokenService
+	adminKeys map[string]string
 }
 
-func NewMiddleware(svc service.MerchantService) *Middleware {
-	return &Middleware{svc}
+// adminKeys maps key name to static admin API key, name is used to attribute admin actions
+func NewMiddleware(svc service.MerchantService, tokens service.TokenService, adminKeys map[string]string) *Middleware {
+	return &Middleware{svc, tokens, adminKeys}
 }
 
 // Middleware function to validate http header and payload
@@ -46,18 +64,18 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Validate bearer variable
-		if !strings.HasPrefix(auth, "Bearer ") {
+		// Validate bearer variable, DPoP bound token uses DPoP scheme (RFC 9449 section 7.1)
+		scheme, authToken, found := strings.Cut(auth, " ")
+		if !found || (scheme != service.TokenTypeBearer && scheme != service.TokenTypeDPoP) {
 			c.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
 				Status:  false,
-				Message: "Invalid authorization format, should be 'Bearer '",
+				Message: "Invalid authorization format, should be 'Bearer ' or 'DPoP '",
 				Data:    map[string]any{},
 			})
 			c.Abort()
 			return
 		}
 
-		authToken := strings.TrimPrefix(auth, "Bearer ")
 		if authToken == "" {
 			c.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
 				Status:  false,
@@ -68,8 +86,54 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Token is validated once here, DPoP proof can't be presented twice
+		ctx := service.WithTokenBinding(c.Request.Context(), RequestBinding(c))
+		token, err := m.tokens.ValidateToken(ctx, authToken)
+		if errors.Is(err, service.ErrInvalidDPoPProof) {
+			dpopChallenge(c, "invalid_dpop_proof", "Invalid DPoP proof")
+			return
+		}
+
+		if errors.Is(err, service.ErrAudienceMismatch) {
+			c.JSON(http.StatusForbidden, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Token is not valid for this audience or scope",
+				Data:    map[string]any{},
+			})
+			c.Abort()
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+				Status:  false,
+				Message: "JWT Token mismatched",
+				Data:    map[string]any{},
+			})
+			c.Abort()
+			return
+		}
+
+		// DPoP bound token sent as bearer token, or bearer token sent with DPoP scheme (RFC 9449 section 7.1)
+		if scheme != service.AuthorizationScheme(token) {
+			dpopChallenge(c, "invalid_token", "Authorization scheme does not match token binding")
+			return
+		}
+
+		// Service account is not a merchant, it skips active merchant check
+		if service.IsServiceToken(token) {
+			logs.Logger.WithFields(logrus.Fields{
+				"path":      c.FullPath(),
+				"client_ip": c.ClientIP(),
+			}).Info("Service account request, skipping merchant code check")
+			m.storeToken(c, authToken, token)
+			c.Next()
+			return
+		}
+
 		// Validate payload request
-		if err := c.ShouldBindJSON(&payload); err != nil {
+		// Body is cached, so handler can still bind the same request body
+		if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
 			c.JSON(http.StatusBadRequest, dto.JwtDataResponse{
 				Status:  false,
 				Message: "Invalid body request",
@@ -90,7 +154,7 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		isValid, err := m.svc.ValidateMerchantCode(c.Request.Context(), payload.Code)
+		merchant, err := m.svc.ValidateMerchantCode(c.Request.Context(), payload.Code)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, dto.JwtDataResponse{
 				Status:  false,
@@ -101,7 +165,26 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if !isValid {
+		// Suspended merchant is told why and until when, so it can follow up with ops
+		if merchant.Status == service.MerchantStatusSuspended {
+			data := map[string]any{
+				"status": merchant.Status,
+				"reason": merchant.Reason,
+			}
+			if merchant.SuspendedUntil != nil {
+				data["suspended_until"] = clock.FormatTimeToISO7(*merchant.SuspendedUntil)
+			}
+
+			c.JSON(http.StatusForbidden, dto.JwtDataResponse{
+				Status:  false,
+				Message: "Merchant is suspended",
+				Data:    data,
+			})
+			c.Abort()
+			return
+		}
+
+		if merchant.Status != service.MerchantStatusActive {
 			c.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
 				Status:  false,
 				Message: "Invalid or inactive merchant code",
@@ -111,8 +194,78 @@ func (m *Middleware) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Store access token to context
-		c.Set("token", authToken)
+		m.storeToken(c, authToken, token)
 		c.Next()
 	}
 }
+
+// Tell client to retry with DPoP scheme and a valid proof
+func dpopChallenge(c *gin.Context, code string, message string) {
+	c.Header("WWW-Authenticate", `DPoP error="`+code+`"`)
+	c.JSON(http.StatusUnauthorized, dto.JwtDataResponse{
+		Status:  false,
+		Message: message,
+		Data:    map[string]any{},
+	})
+	c.Abort()
+}
+
+// Store access token, its validated claims and its proof of possession to context
+func (m *Middleware) storeToken(c *gin.Context, authToken string, token *jwt.Token) {
+	ctx := context.WithValue(c.Request.Context(), tokenKey, authToken)
+	ctx = context.WithValue(ctx, validatedTokenKey, token)
+	c.Request = c.Request.WithContext(service.WithTokenBinding(ctx, RequestBinding(c)))
+	c.Set("token", authToken)
+}
+
+// Build proof of possession from DPoP header, request target and mutual TLS client certificate.
+// Gateway validating a token on behalf of another request pass its target with X-Original-Method and X-Original-URL,
+// and the audience and scopes of the protected service with X-Original-Audience and X-Required-Scope.
+func RequestBinding(c *gin.Context) service.TokenBinding {
+	binding := service.TokenBinding{
+		DPoPProof: c.GetHeader("DPoP"),
+		Method:    c.Request.Method,
+		URL:       requestURL(c),
+	}
+
+	// Only certificate verified on this connection is trusted, forwarded certificate headers are ignored
+	if tlsState := c.Request.TLS; tlsState != nil && len(tlsState.VerifiedChains) > 0 {
+		binding.CertThumbprint = service.CertificateThumbprint(tlsState.PeerCertificates[0])
+	}
+
+	// Original request is taken from headers only when set by a trusted proxy, any client could send them
+	if !fromTrustedProxy(c) {
+		return binding
+	}
+
+	if method := c.GetHeader("X-Original-Method"); method != "" {
+		binding.Method = method
+	}
+
+	if original := c.GetHeader("X-Original-URL"); original != "" {
+		binding.URL = original
+	}
+
+	binding.Audience = c.GetHeader("X-Original-Audience")
+	binding.Scopes = strings.Fields(c.GetHeader("X-Required-Scope"))
+
+	return binding
+}
+
+func requestURL(c *gin.Context) string {
+	trusted := fromTrustedProxy(c)
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	} else if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" && trusted {
+		scheme = proto
+	}
+
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" && trusted {
+		host = forwarded
+	}
+
+	return scheme + "://" + host + c.Request.URL.Path
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddleware_SchemeMustMatchBinding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		scheme string
+		jkt    string
+	}{
+		{scheme: "Bearer", jkt: "0ZcOCORZNYy-DWpqq30jZyJGHTN0d2HglBV3uiguA4I"},
+		{scheme: "DPoP"},
+	}
+
+	for _, tc := range cases {
+		mw := NewMiddleware(nil, &stubTokenService{jkt: tc.jkt}, nil)
+		router := gin.New()
+		router.POST("/api/v1/token/validate", mw.AuthMiddleware(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/token/validate", nil)
+		req.Header.Set("Authorization", tc.scheme+" access-token")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401 for %s scheme with jkt %q, got %d", tc.scheme, tc.jkt, rec.Code)
+		}
+
+		if rec.Header().Get("WWW-Authenticate") != `DPoP error="invalid_token"` {
+			t.Fatalf("Expected DPoP challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAuthMiddleware_ServiceTokenValidatedOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens := &stubTokenService{}
+	mw := NewMiddleware(nil, tokens, nil)
+	router := gin.New()
+	router.POST("/api/v1/token/validate", mw.AuthMiddleware(), func(c *gin.Context) {
+		if _, ok := GetValidatedTokenFromContext(c.Request.Context()); !ok {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	// Service account skips merchant code check, so no body is needed
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/token/validate", nil)
+	req.Header.Set("Authorization", "Bearer service-token")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	if tokens.validations != 1 {
+		t.Fatalf("Expected token validated once, got %d", tokens.validations)
+	}
+}
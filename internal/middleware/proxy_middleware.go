@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/netip"
+
+	"github.com/gin-gonic/gin"
+)
+
+const trustedProxyKey = "trusted_proxy"
+
+// Middleware function to mark request whose direct peer is a configured proxy,
+// same list must be given to gin SetTrustedProxies so ClientIP follows the same rule
+func TrustedProxyMiddleware(proxies []netip.Prefix) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if remote, err := netip.ParseAddr(c.RemoteIP()); err == nil {
+			remote = remote.Unmap()
+			for _, proxy := range proxies {
+				if proxy.Contains(remote) {
+					c.Set(trustedProxyKey, true)
+					break
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func fromTrustedProxy(c *gin.Context) bool {
+	return c.GetBool(trustedProxyKey)
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	service "briefcash-jwt/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestBinding_ForwardedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	cases := []struct {
+		remote string
+		method string
+		url    string
+	}{
+		{"203.0.113.7:41000", http.MethodGet, "http://jwt.internal/api/v1/token/validate"},
+		{"10.1.2.3:41000", http.MethodPost, "https://api.briefcash.id/api/v1/transfer"},
+	}
+
+	for _, tc := range cases {
+		var binding service.TokenBinding
+		router := gin.New()
+		router.Use(TrustedProxyMiddleware(proxies))
+		router.GET("/api/v1/token/validate", func(c *gin.Context) {
+			binding = RequestBinding(c)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "http://jwt.internal/api/v1/token/validate", nil)
+		req.RemoteAddr = tc.remote
+		req.Header.Set("X-Original-Method", http.MethodPost)
+		req.Header.Set("X-Original-URL", "https://api.briefcash.id/api/v1/transfer")
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "api.briefcash.id")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if binding.Method != tc.method || binding.URL != tc.url {
+			t.Fatalf("Expected %s %s from %s, got %s %s", tc.method, tc.url, tc.remote, binding.Method, binding.URL)
+		}
+	}
+}
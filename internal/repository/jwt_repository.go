@@ -2,18 +2,37 @@ package repository
 
 import (
 	jwt "briefcash-jwt/internal/entity"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+var (
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
 type JwtRepository interface {
 	Save(ctx context.Context, jwt *jwt.JwtToken) error
 	FindByRefreshToken(ctx context.Context, refreshToken string) (*jwt.JwtToken, error)
 	FindByAccessToken(ctx context.Context, accessToken string) (*jwt.JwtToken, error)
-	DeleteByAccessToken(ctx context.Context, accessToken string) error
+	RevokeByAccessToken(ctx context.Context, accessToken string) error
+	FindByID(ctx context.Context, id int64) (*jwt.JwtToken, error)
+	FindActiveByMerchantID(ctx context.Context, merchantID string) ([]jwt.JwtToken, error)
+	FindActiveByAPIKeyID(ctx context.Context, apiKeyID int64) ([]jwt.JwtToken, error)
+	FindActiveByServiceAccountID(ctx context.Context, clientID string) ([]jwt.JwtToken, error)
+	CountActive(ctx context.Context) (int64, error)
+	DeleteExpiredBatch(ctx context.Context, before time.Time, limit int) (int64, error)
+	ArchiveExpiredBatch(ctx context.Context, before time.Time, limit int) (int64, error)
+	FindExpiredRange(ctx context.Context, before time.Time) (*time.Time, *time.Time, error)
+	EnsureHistoryPartitions(ctx context.Context, from, to time.Time) error
+	DropHistoryPartitionsBefore(ctx context.Context, before time.Time) ([]string, error)
+	FindHistory(ctx context.Context, merchantID string, from, to time.Time, limit, offset int) ([]jwt.JwtTokenHistory, error)
 	WithTransaction(tx *gorm.DB) JwtRepository
 }
 
@@ -26,19 +45,25 @@ func NewJwtRepository(db *gorm.DB) JwtRepository {
 }
 
 // Save jwt token
-func (r *jwtRepository) Save(ctx context.Context, jwt *jwt.JwtToken) error {
+func (r *jwtRepository) Save(ctx context.Context, jwt *jwt.JwtToken) (err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.Save")
+	defer func() { tracing.End(span, err) }()
+
 	return r.db.WithContext(ctx).Table("jwt_token").Create(jwt).Error
 }
 
 // Get jwt token find by access token
-func (r *jwtRepository) FindByAccessToken(ctx context.Context, accessToken string) (*jwt.JwtToken, error) {
+func (r *jwtRepository) FindByAccessToken(ctx context.Context, accessToken string) (_ *jwt.JwtToken, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindByAccessToken")
+	defer func() { tracing.End(span, failure(err, ErrAccessTokenNotFound)) }()
+
 	var token jwt.JwtToken
-	err := r.db.WithContext(ctx).Table("jwt_token").
+	err = r.db.WithContext(ctx).Table("jwt_token").
 		Where("access_token = ?", accessToken).
 		First(&token).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, fmt.Errorf("access token not found")
+		return nil, ErrAccessTokenNotFound
 	}
 
 	if err != nil {
@@ -49,7 +74,10 @@ func (r *jwtRepository) FindByAccessToken(ctx context.Context, accessToken strin
 }
 
 // Get jwt token find by refresh token
-func (r *jwtRepository) FindByRefreshToken(ctx context.Context, refreshToken string) (*jwt.JwtToken, error) {
+func (r *jwtRepository) FindByRefreshToken(ctx context.Context, refreshToken string) (_ *jwt.JwtToken, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindByRefreshToken")
+	defer func() { tracing.End(span, failure(err, gorm.ErrRecordNotFound)) }()
+
 	var tkn jwt.JwtToken
 	if err := r.db.WithContext(ctx).Table("jwt_token").
 		Where("refresh_token = ?", refreshToken).First(&tkn).Error; err != nil {
@@ -58,12 +86,241 @@ func (r *jwtRepository) FindByRefreshToken(ctx context.Context, refreshToken str
 	return &tkn, nil
 }
 
-// Delete jwt token by access token
-func (r *jwtRepository) DeleteByAccessToken(ctx context.Context, accessToken string) error {
+// Mark jwt token revoked by access token, row is moved to history by purge job
+func (r *jwtRepository) RevokeByAccessToken(ctx context.Context, accessToken string) (err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.RevokeByAccessToken")
+	defer func() { tracing.End(span, err) }()
+
 	return r.db.WithContext(ctx).Table("jwt_token").
-		Where("access_token = ?", accessToken).Delete(&jwt.JwtToken{}).Error
+		Where("access_token = ?", accessToken).Update("is_revoke", true).Error
+}
+
+// Get jwt token find by session id
+func (r *jwtRepository) FindByID(ctx context.Context, id int64) (_ *jwt.JwtToken, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindByID")
+	defer func() { tracing.End(span, failure(err, ErrSessionNotFound)) }()
+
+	var token jwt.JwtToken
+	err = r.db.WithContext(ctx).Table("jwt_token").
+		Where("id = ?", id).
+		First(&token).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Get list of non revoked and non expired jwt token for a merchant
+func (r *jwtRepository) FindActiveByMerchantID(ctx context.Context, merchantID string) (_ []jwt.JwtToken, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindActiveByMerchantID")
+	defer func() { tracing.End(span, err) }()
+
+	var tokens []jwt.JwtToken
+	if err := r.db.WithContext(ctx).Table("jwt_token").
+		Where("merchant_settings_id = ? AND expires_at > ? AND is_revoke = ?", merchantID, time.Now(), false).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Count non revoked and non expired jwt token of every merchant and service account
+func (r *jwtRepository) CountActive(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.CountActive")
+	defer func() { tracing.End(span, err) }()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Table("jwt_token").
+		Where("expires_at > ? AND is_revoke = ?", time.Now(), false).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Get list of non revoked and non expired jwt token issued with a merchant api key
+func (r *jwtRepository) FindActiveByAPIKeyID(ctx context.Context, apiKeyID int64) (_ []jwt.JwtToken, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindActiveByAPIKeyID")
+	defer func() { tracing.End(span, err) }()
+
+	var tokens []jwt.JwtToken
+	if err := r.db.WithContext(ctx).Table("jwt_token").
+		Where("api_key_id = ? AND expires_at > ? AND is_revoke = ?", apiKeyID, time.Now(), false).
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Get list of non revoked and non expired jwt token issued to a service account
+func (r *jwtRepository) FindActiveByServiceAccountID(ctx context.Context, clientID string) (_ []jwt.JwtToken, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindActiveByServiceAccountID")
+	defer func() { tracing.End(span, err) }()
+
+	var tokens []jwt.JwtToken
+	if err := r.db.WithContext(ctx).Table("jwt_token").
+		Where("service_account_id = ? AND expires_at > ? AND is_revoke = ?", clientID, time.Now(), false).
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Delete one batch of jwt token whose refresh token expired, or revoked, before the given time
+func (r *jwtRepository) DeleteExpiredBatch(ctx context.Context, before time.Time, limit int) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.DeleteExpiredBatch")
+	defer func() { tracing.End(span, err) }()
+
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM jwt_token WHERE id IN (
+		SELECT id FROM jwt_token
+		WHERE COALESCE(refresh_expires_at, expires_at) < ? OR (is_revoke = ? AND created_at < ?)
+		ORDER BY id
+		LIMIT ?
+	)`, before, true, before, limit)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Move one batch of jwt token whose refresh token expired, or revoked, before the given time into history table.
+// Token values are stored as SHA-256 hash, history partition must exist before calling this.
+func (r *jwtRepository) ArchiveExpiredBatch(ctx context.Context, before time.Time, limit int) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.ArchiveExpiredBatch")
+	defer func() { tracing.End(span, err) }()
+
+	result := r.db.WithContext(ctx).Exec(`WITH moved AS (
+		DELETE FROM jwt_token WHERE id IN (
+			SELECT id FROM jwt_token
+			WHERE COALESCE(refresh_expires_at, expires_at) < ? OR (is_revoke = ? AND created_at < ?)
+			ORDER BY id
+			LIMIT ?
+		)
+		RETURNING id, merchant_settings_id, access_token, refresh_token, client_ip, user_agent,
+			created_at, expires_at, refreshed_at, is_revoke
+	)
+	INSERT INTO jwt_token_history (id, merchant_settings_id, access_token_hash, refresh_token_hash,
+		client_ip, user_agent, created_at, expires_at, refreshed_at, is_revoke, archived_at)
+	SELECT id, merchant_settings_id,
+		encode(sha256(convert_to(COALESCE(access_token, ''), 'UTF8')), 'hex'),
+		encode(sha256(convert_to(COALESCE(refresh_token, ''), 'UTF8')), 'hex'),
+		client_ip, user_agent, COALESCE(created_at, expires_at, now()), expires_at, refreshed_at,
+		COALESCE(is_revoke, false), now()
+	FROM moved`, before, true, before, limit)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Get oldest and newest creation time of jwt token eligible for archiving, nil when nothing to archive
+func (r *jwtRepository) FindExpiredRange(ctx context.Context, before time.Time) (_ *time.Time, _ *time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindExpiredRange")
+	defer func() { tracing.End(span, err) }()
+
+	var bounds struct {
+		MinCreated *time.Time `gorm:"column:min_created"`
+		MaxCreated *time.Time `gorm:"column:max_created"`
+	}
+
+	if err := r.db.WithContext(ctx).Raw(`SELECT
+		MIN(COALESCE(created_at, expires_at, now())) AS min_created,
+		MAX(COALESCE(created_at, expires_at, now())) AS max_created
+	FROM jwt_token
+	WHERE COALESCE(refresh_expires_at, expires_at) < ? OR (is_revoke = ? AND created_at < ?)`, before, true, before).
+		Scan(&bounds).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return bounds.MinCreated, bounds.MaxCreated, nil
+}
+
+// Create monthly history partition for every month between from and to
+func (r *jwtRepository) EnsureHistoryPartitions(ctx context.Context, from, to time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.EnsureHistoryPartitions")
+	defer func() { tracing.End(span, err) }()
+
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		statement := fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s PARTITION OF jwt_token_history FOR VALUES FROM ('%s') TO ('%s')`,
+			historyPartitionName(month), month.Format("2006-01-02"), month.AddDate(0, 1, 0).Format("2006-01-02"),
+		)
+
+		if err := r.db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create history partition %s: %w", historyPartitionName(month), err)
+		}
+	}
+
+	return nil
+}
+
+// Drop history partition whose whole month is older than the given time, return dropped partition names
+func (r *jwtRepository) DropHistoryPartitionsBefore(ctx context.Context, before time.Time) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.DropHistoryPartitionsBefore")
+	defer func() { tracing.End(span, err) }()
+
+	var partitions []string
+	if err := r.db.WithContext(ctx).Raw(`SELECT child.relname
+	FROM pg_inherits
+	JOIN pg_class parent ON parent.oid = pg_inherits.inhparent
+	JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+	WHERE parent.relname = 'jwt_token_history'`).
+		Scan(&partitions).Error; err != nil {
+		return nil, err
+	}
+
+	cutoff := historyPartitionName(monthStart(before))
+	var dropped []string
+	for _, partition := range partitions {
+		if !strings.HasPrefix(partition, "jwt_token_history_") || partition >= cutoff {
+			continue
+		}
+
+		if err := r.db.WithContext(ctx).Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", partition)).Error; err != nil {
+			return dropped, fmt.Errorf("failed to drop history partition %s: %w", partition, err)
+		}
+		dropped = append(dropped, partition)
+	}
+
+	return dropped, nil
+}
+
+// Get archived jwt token for a merchant created within date range
+func (r *jwtRepository) FindHistory(ctx context.Context, merchantID string, from, to time.Time, limit, offset int) (_ []jwt.JwtTokenHistory, err error) {
+	ctx, span := tracing.Start(ctx, "JwtRepository.FindHistory")
+	defer func() { tracing.End(span, err) }()
+
+	var histories []jwt.JwtTokenHistory
+	if err := r.db.WithContext(ctx).Table("jwt_token_history").
+		Where("merchant_settings_id = ? AND created_at >= ? AND created_at < ?", merchantID, from, to).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&histories).Error; err != nil {
+		return nil, err
+	}
+	return histories, nil
 }
 
 func (r *jwtRepository) WithTransaction(trx *gorm.DB) JwtRepository {
 	return &jwtRepository{db: trx}
 }
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func historyPartitionName(month time.Time) string {
+	return fmt.Sprintf("jwt_token_history_%04d_%02d", month.Year(), int(month.Month()))
+}
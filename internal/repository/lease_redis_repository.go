@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Release and renew only when the lease is still owned by the caller
+var (
+	releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+type LeaseRepository interface {
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, owner string) error
+}
+
+type leaseRepository struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+func NewLeaseRepository(client *redis.Client) LeaseRepository {
+	return &leaseRepository{
+		client:    client,
+		keyPrefix: "lease:",
+	}
+}
+
+func (r *leaseRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "LeaseRepository.Acquire")
+	defer func() { tracing.End(span, err) }()
+
+	acquired, err := r.client.SetNX(ctx, r.keyPrefix+name, owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, err)
+	}
+	return acquired, nil
+}
+
+func (r *leaseRepository) Renew(ctx context.Context, name, owner string, ttl time.Duration) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "LeaseRepository.Renew")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := renewLeaseScript.Run(ctx, r.client, []string{r.keyPrefix + name}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease %s: %w", name, err)
+	}
+	return result == 1, nil
+}
+
+func (r *leaseRepository) Release(ctx context.Context, name, owner string) (err error) {
+	ctx, span := tracing.Start(ctx, "LeaseRepository.Release")
+	defer func() { tracing.End(span, err) }()
+
+	if err := releaseLeaseScript.Run(ctx, r.client, []string{r.keyPrefix + name}, owner).Err(); err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	jwt "briefcash-jwt/internal/entity"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MerchantAPIKeyRepository interface {
+	Create(ctx context.Context, key *jwt.MerchantAPIKey) error
+	GetByAPIKey(ctx context.Context, apiKey string) (*jwt.MerchantAPIKey, error)
+	ListByMerchant(ctx context.Context, merchantCode string) ([]jwt.MerchantAPIKey, error)
+	ListUsableByMerchant(ctx context.Context, merchantCode string, now time.Time) ([]jwt.MerchantAPIKey, error)
+	CountByMerchant(ctx context.Context, merchantCode string) (int64, error)
+	SetExpiresAt(ctx context.Context, id int64, expiresAt time.Time) error
+	Revoke(ctx context.Context, id int64, revokedAt time.Time, revokedBy string) error
+	WithTransaction(tx *gorm.DB) MerchantAPIKeyRepository
+}
+
+type merchantAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantAPIKeyRepository(db *gorm.DB) MerchantAPIKeyRepository {
+	return &merchantAPIKeyRepository{db}
+}
+
+func (r *merchantAPIKeyRepository) Create(ctx context.Context, key *jwt.MerchantAPIKey) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.Create")
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Table("merchant_api_key").Create(key).Error
+}
+
+// Get key by its public identifier, nil when not issued
+func (r *merchantAPIKeyRepository) GetByAPIKey(ctx context.Context, apiKey string) (_ *jwt.MerchantAPIKey, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.GetByAPIKey")
+	defer func() { tracing.End(span, err) }()
+
+	var key jwt.MerchantAPIKey
+
+	if err := r.db.WithContext(ctx).Table("merchant_api_key").Where("api_key = ?", apiKey).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// Get every key of merchant including revoked ones, newest first
+func (r *merchantAPIKeyRepository) ListByMerchant(ctx context.Context, merchantCode string) (_ []jwt.MerchantAPIKey, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.ListByMerchant")
+	defer func() { tracing.End(span, err) }()
+
+	var keys []jwt.MerchantAPIKey
+	if err := r.db.WithContext(ctx).Table("merchant_api_key").
+		Where("merchant_code = ?", merchantCode).
+		Order("created_at DESC").
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Get keys that can still authenticate, locked so concurrent rotations are serialized
+func (r *merchantAPIKeyRepository) ListUsableByMerchant(ctx context.Context, merchantCode string, now time.Time) (_ []jwt.MerchantAPIKey, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.ListUsableByMerchant")
+	defer func() { tracing.End(span, err) }()
+
+	var keys []jwt.MerchantAPIKey
+	if err := r.db.WithContext(ctx).Table("merchant_api_key").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("merchant_code = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", merchantCode, now).
+		Order("created_at DESC").
+		Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Count every key ever issued to merchant, revoked and expired ones included
+func (r *merchantAPIKeyRepository) CountByMerchant(ctx context.Context, merchantCode string) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.CountByMerchant")
+	defer func() { tracing.End(span, err) }()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Table("merchant_api_key").
+		Where("merchant_code = ?", merchantCode).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *merchantAPIKeyRepository) SetExpiresAt(ctx context.Context, id int64, expiresAt time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.SetExpiresAt")
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Table("merchant_api_key").Where("id = ?", id).Update("expires_at", expiresAt).Error
+}
+
+// Mark key revoked, returns gorm.ErrRecordNotFound when key doesn't exist or is already revoked
+func (r *merchantAPIKeyRepository) Revoke(ctx context.Context, id int64, revokedAt time.Time, revokedBy string) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantAPIKeyRepository.Revoke")
+	defer func() { tracing.End(span, err) }()
+
+	result := r.db.WithContext(ctx).Table("merchant_api_key").
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]any{"revoked_at": revokedAt, "revoked_by": revokedBy})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *merchantAPIKeyRepository) WithTransaction(trx *gorm.DB) MerchantAPIKeyRepository {
+	return &merchantAPIKeyRepository{db: trx}
+}
@@ -2,57 +2,161 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
 	"github.com/redis/go-redis/v9"
 )
 
 type MerchantRedisRepository interface {
-	SetActiveMerchantCode(ctx context.Context, mCodes []string) error
+	SetActiveMerchantCode(ctx context.Context, mCodes []string) (*MerchantCodeDiff, error)
 	IsMerchantCodeActive(ctx context.Context, mCode string) (bool, error)
 	AddMerchantCode(ctx context.Context, mCode string) error
 	RemoveMerchantCode(ctx context.Context, mCode string) error
 	GetAllMerchantCode(ctx context.Context) ([]string, error)
+	SuspendMerchantCode(ctx context.Context, mCode string, suspension MerchantSuspension) error
+	GetSuspension(ctx context.Context, mCode string) (*MerchantSuspension, error)
+	Subscribe(ctx context.Context, subscribed func(), handler func(change MerchantChange)) error
+	QueueTokenRevocation(ctx context.Context, mCode string) error
+	PopTokenRevocations(ctx context.Context, count int) ([]string, error)
+}
+
+const (
+	MerchantChangeAdd    = "add"
+	MerchantChangeRemove = "remove"
+	MerchantChangeSync   = "sync"
+)
+
+// Swap staged set in place of active set in one step, readers see either the old or the new set.
+// Returns codes only in staged set and codes only in active set, then announces the sync.
+var swapMerchantSetScript = redis.NewScript(`
+local added = redis.call("SDIFF", KEYS[2], KEYS[1])
+local removed = redis.call("SDIFF", KEYS[1], KEYS[2])
+redis.call("RENAME", KEYS[2], KEYS[1])
+redis.call("PERSIST", KEYS[1])
+redis.call("PUBLISH", ARGV[1], ARGV[2])
+return {added, removed}`)
+
+// Empty the active set, every code in it is reported as removed, then announces the sync
+var clearMerchantSetScript = redis.NewScript(`
+local removed = redis.call("SMEMBERS", KEYS[1])
+redis.call("DEL", KEYS[1])
+redis.call("PUBLISH", ARGV[1], ARGV[2])
+return removed`)
+
+// Codes which became active and inactive by a sync
+type MerchantCodeDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// Change of active merchant set published to every replica, sync means the whole set was replaced
+type MerchantChange struct {
+	Action string `json:"action"`
+	Code   string `json:"code,omitempty"`
+}
+
+// Reason of merchant suspension, kept in redis so rejected requests can be told why
+type MerchantSuspension struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until,omitempty"`
 }
 
 type merchantRedisRepository struct {
-	client     *redis.Client
-	keyPrefix  string
-	expiration time.Duration
+	client       *redis.Client
+	keyPrefix    string
+	suspendedKey string
+	revokeKey    string
+	channel      string
+	expiration   time.Duration
 }
 
 func NewMerchantRedisRepository(client *redis.Client) MerchantRedisRepository {
 	return &merchantRedisRepository{
-		client:     client,
-		keyPrefix:  "active_merchants",
-		expiration: 0,
+		client:       client,
+		keyPrefix:    "active_merchants",
+		suspendedKey: "suspended_merchants",
+		revokeKey:    "merchant_token_revocations",
+		channel:      "merchant_changes",
+		expiration:   0,
 	}
 }
 
-func (r *merchantRedisRepository) SetActiveMerchantCode(ctx context.Context, mCodes []string) error {
+// Replace active merchant set atomically. New set is staged under a temporary key, then renamed over the active key.
+func (r *merchantRedisRepository) SetActiveMerchantCode(ctx context.Context, mCodes []string) (_ *MerchantCodeDiff, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.SetActiveMerchantCode")
+	defer func() { tracing.End(span, err) }()
+
 	if len(mCodes) == 0 {
-		return fmt.Errorf("list of merchant code is empty")
+		return r.clearActiveMerchantCode(ctx)
 	}
 
-	key := r.keyPrefix
+	suffix, err := mask.RandomID(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage merchant codes: %w", err)
+	}
+	staging := r.keyPrefix + ":staging:" + suffix
+
 	members := make([]any, len(mCodes))
 	for index, value := range mCodes {
 		members[index] = value
 	}
 
-	if err := r.client.Del(ctx, key).Err(); err != nil {
-		return fmt.Errorf("failed to clear previous merchant codes in redis: %w", err)
+	// Staging key expires on its own if the swap never happens
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, staging, members...)
+		pipe.Expire(ctx, staging, time.Minute)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage merchant codes: %w", err)
 	}
 
-	if err := r.client.SAdd(ctx, key, members...).Err(); err != nil {
-		return fmt.Errorf("failed to set active merchant codes: %w", err)
+	payload, err := json.Marshal(MerchantChange{Action: MerchantChangeSync})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merchant change: %w", err)
 	}
 
-	return nil
+	result, err := swapMerchantSetScript.Run(ctx, r.client, []string{r.keyPrefix, staging}, r.channel, payload).Slice()
+	if err != nil {
+		r.client.Del(context.WithoutCancel(ctx), staging)
+		return nil, fmt.Errorf("failed to set active merchant codes: %w", err)
+	}
+
+	diff := &MerchantCodeDiff{Added: []string{}, Removed: []string{}}
+	if len(result) == 2 {
+		diff.Added = toStrings(result[0])
+		diff.Removed = toStrings(result[1])
+	}
+
+	return diff, nil
 }
 
-func (r *merchantRedisRepository) IsMerchantCodeActive(ctx context.Context, mCode string) (bool, error) {
+// Redis has no empty set, so no active merchant means no key at all
+func (r *merchantRedisRepository) clearActiveMerchantCode(ctx context.Context) (*MerchantCodeDiff, error) {
+	payload, err := json.Marshal(MerchantChange{Action: MerchantChangeSync})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merchant change: %w", err)
+	}
+
+	removed, err := clearMerchantSetScript.Run(ctx, r.client, []string{r.keyPrefix}, r.channel, payload).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set active merchant codes: %w", err)
+	}
+
+	return &MerchantCodeDiff{Added: []string{}, Removed: toStrings(removed)}, nil
+}
+
+func (r *merchantRedisRepository) IsMerchantCodeActive(ctx context.Context, mCode string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.IsMerchantCodeActive")
+	defer func() { tracing.End(span, err) }()
+
 	key := r.keyPrefix
 	exists, err := r.client.SIsMember(ctx, key, mCode).Result()
 
@@ -63,25 +167,43 @@ func (r *merchantRedisRepository) IsMerchantCodeActive(ctx context.Context, mCod
 	return exists, nil
 }
 
-func (r *merchantRedisRepository) AddMerchantCode(ctx context.Context, mCode string) error {
+func (r *merchantRedisRepository) AddMerchantCode(ctx context.Context, mCode string) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.AddMerchantCode")
+	defer func() { tracing.End(span, err) }()
+
 	key := r.keyPrefix
 
-	if err := r.client.SAdd(ctx, key, mCode).Err(); err != nil {
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, key, mCode)
+		pipe.HDel(ctx, r.suspendedKey, mCode)
+		return r.publish(ctx, pipe, MerchantChange{Action: MerchantChangeAdd, Code: mCode})
+	})
+	if err != nil {
 		return fmt.Errorf("failed to add merchant code to redis: %w", err)
 	}
 
 	return nil
 }
 
-func (r *merchantRedisRepository) RemoveMerchantCode(ctx context.Context, mCode string) error {
+func (r *merchantRedisRepository) RemoveMerchantCode(ctx context.Context, mCode string) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.RemoveMerchantCode")
+	defer func() { tracing.End(span, err) }()
+
 	key := r.keyPrefix
-	if err := r.client.SRem(ctx, key, mCode).Err(); err != nil {
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, key, mCode)
+		return r.publish(ctx, pipe, MerchantChange{Action: MerchantChangeRemove, Code: mCode})
+	})
+	if err != nil {
 		return fmt.Errorf("failed to remove merchant code from redis: %w", err)
 	}
 	return nil
 }
 
-func (r *merchantRedisRepository) GetAllMerchantCode(ctx context.Context) ([]string, error) {
+func (r *merchantRedisRepository) GetAllMerchantCode(ctx context.Context) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.GetAllMerchantCode")
+	defer func() { tracing.End(span, err) }()
+
 	key := r.keyPrefix
 	codes, err := r.client.SMembers(ctx, key).Result()
 
@@ -91,3 +213,130 @@ func (r *merchantRedisRepository) GetAllMerchantCode(ctx context.Context) ([]str
 
 	return codes, nil
 }
+
+// Drop merchant code from active set and record why, replicas follow through the published removal
+func (r *merchantRedisRepository) SuspendMerchantCode(ctx context.Context, mCode string, suspension MerchantSuspension) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.SuspendMerchantCode")
+	defer func() { tracing.End(span, err) }()
+
+	payload, err := json.Marshal(suspension)
+	if err != nil {
+		return fmt.Errorf("failed to encode merchant suspension: %w", err)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SRem(ctx, r.keyPrefix, mCode)
+		pipe.HSet(ctx, r.suspendedKey, mCode, payload)
+		return r.publish(ctx, pipe, MerchantChange{Action: MerchantChangeRemove, Code: mCode})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to suspend merchant code in redis: %w", err)
+	}
+
+	return nil
+}
+
+// Returns nil when merchant is not suspended
+func (r *merchantRedisRepository) GetSuspension(ctx context.Context, mCode string) (_ *MerchantSuspension, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.GetSuspension")
+	defer func() { tracing.End(span, err) }()
+
+	payload, err := r.client.HGet(ctx, r.suspendedKey, mCode).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get merchant suspension from redis: %w", err)
+	}
+
+	var suspension MerchantSuspension
+	if err := json.Unmarshal(payload, &suspension); err != nil {
+		return nil, fmt.Errorf("failed to decode merchant suspension: %w", err)
+	}
+
+	return &suspension, nil
+}
+
+// Queue merchant whose tokens must be revoked, every replica queues the same code but the set keeps it once
+func (r *merchantRedisRepository) QueueTokenRevocation(ctx context.Context, mCode string) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.QueueTokenRevocation")
+	defer func() { tracing.End(span, err) }()
+
+	if err = r.client.SAdd(ctx, r.revokeKey, mCode).Err(); err != nil {
+		return fmt.Errorf("failed to queue merchant token revocation: %w", err)
+	}
+	return nil
+}
+
+// Take up to count queued merchants, each code is handed to one caller only
+func (r *merchantRedisRepository) PopTokenRevocations(ctx context.Context, count int) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRedisRepository.PopTokenRevocations")
+	defer func() { tracing.End(span, err) }()
+
+	codes, err := r.client.SPopN(ctx, r.revokeKey, int64(count)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop merchant token revocations: %w", err)
+	}
+	return codes, nil
+}
+
+// Listen to merchant change channel until context is cancelled.
+// Subscribed is called once subscription is confirmed, changes published while it runs are delivered after it returns.
+func (r *merchantRedisRepository) Subscribe(ctx context.Context, subscribed func(), handler func(change MerchantChange)) error {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe merchant change channel: %w", err)
+	}
+
+	if subscribed != nil {
+		subscribed()
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("merchant change channel closed")
+			}
+
+			var change MerchantChange
+			if err := json.Unmarshal([]byte(message.Payload), &change); err != nil || change.Action == "" {
+				continue
+			}
+			handler(change)
+		}
+	}
+}
+
+func (r *merchantRedisRepository) publish(ctx context.Context, client redis.Cmdable, change MerchantChange) error {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to encode merchant change: %w", err)
+	}
+
+	if err := client.Publish(ctx, r.channel, payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish merchant change: %w", err)
+	}
+
+	return nil
+}
+
+func toStrings(value any) []string {
+	items, _ := value.([]any)
+	codes := make([]string, 0, len(items))
+	for _, item := range items {
+		if code, ok := item.(string); ok {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
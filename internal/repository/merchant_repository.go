@@ -2,15 +2,37 @@ package repository
 
 import (
 	jwt "briefcash-jwt/internal/entity"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
 	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MerchantRepository interface {
 	GetAllActiveCode(ctx context.Context) ([]string, error)
 	GetByCode(ctx context.Context, userID string) (*jwt.Merchant, error)
+	GetByCodeForUpdate(ctx context.Context, code string) (*jwt.Merchant, error)
+	SetActive(ctx context.Context, code string, active bool) error
+	GetSuspensionEndedCodes(ctx context.Context, now time.Time) ([]string, error)
+	Create(ctx context.Context, merchant *jwt.Merchant) error
+	Update(ctx context.Context, code string, fields map[string]any) error
+	List(ctx context.Context, filter MerchantFilter) ([]jwt.Merchant, int64, error)
+	CreateDetails(ctx context.Context, details *jwt.MerchantDetails) error
+	UpdateDetails(ctx context.Context, code string, fields map[string]any) error
+	GetDetailsByCode(ctx context.Context, code string) (*jwt.MerchantDetails, error)
+	WithTransaction(tx *gorm.DB) MerchantRepository
+}
+
+// Optional merchant list filters, nil fields are not applied
+type MerchantFilter struct {
+	IsActive   *bool
+	JoinedFrom *time.Time
+	JoinedTo   *time.Time
+	Limit      int
+	Offset     int
 }
 
 type merchantRepository struct {
@@ -21,7 +43,10 @@ func NewMerchantRepository(db *gorm.DB) MerchantRepository {
 	return &merchantRepository{db}
 }
 
-func (m *merchantRepository) GetAllActiveCode(ctx context.Context) ([]string, error) {
+func (m *merchantRepository) GetAllActiveCode(ctx context.Context) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.GetAllActiveCode")
+	defer func() { tracing.End(span, err) }()
+
 	var merchant jwt.Merchant
 	var listOfCodes []string
 
@@ -32,7 +57,10 @@ func (m *merchantRepository) GetAllActiveCode(ctx context.Context) ([]string, er
 	return listOfCodes, nil
 }
 
-func (m *merchantRepository) GetByCode(ctx context.Context, code string) (*jwt.Merchant, error) {
+func (m *merchantRepository) GetByCode(ctx context.Context, code string) (_ *jwt.Merchant, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.GetByCode")
+	defer func() { tracing.End(span, err) }()
+
 	var merchant jwt.Merchant
 
 	if err := m.db.Table("merchant").WithContext(ctx).Where("code = ?", code).First(&merchant).Error; err != nil {
@@ -44,3 +72,157 @@ func (m *merchantRepository) GetByCode(ctx context.Context, code string) (*jwt.M
 
 	return &merchant, nil
 }
+
+// Lock merchant row until the surrounding transaction ends, only meaningful inside WithTransaction
+func (m *merchantRepository) GetByCodeForUpdate(ctx context.Context, code string) (_ *jwt.Merchant, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.GetByCodeForUpdate")
+	defer func() { tracing.End(span, err) }()
+
+	var merchant jwt.Merchant
+
+	if err := m.db.WithContext(ctx).Table("merchant").Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&merchant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &merchant, nil
+}
+
+// Activating merchant also lifts its suspension
+func (m *merchantRepository) SetActive(ctx context.Context, code string, active bool) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.SetActive")
+	defer func() { tracing.End(span, err) }()
+
+	fields := map[string]any{"is_active": active}
+	if active {
+		fields["suspended_reason"] = nil
+		fields["suspended_by"] = nil
+		fields["suspended_at"] = nil
+		fields["suspended_until"] = nil
+	}
+
+	result := m.db.WithContext(ctx).Table("merchant").Where("code = ?", code).Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Get code of suspended merchants whose suspension ended at or before now
+func (m *merchantRepository) GetSuspensionEndedCodes(ctx context.Context, now time.Time) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.GetSuspensionEndedCodes")
+	defer func() { tracing.End(span, err) }()
+
+	var codes []string
+
+	if err := m.db.WithContext(ctx).Table("merchant").
+		Where("is_active = ? AND suspended_until IS NOT NULL AND suspended_until <= ?", false, now).
+		Order("code ASC").
+		Pluck("code", &codes).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (m *merchantRepository) Create(ctx context.Context, merchant *jwt.Merchant) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.Create")
+	defer func() { tracing.End(span, err) }()
+
+	return m.db.WithContext(ctx).Table("merchant").Create(merchant).Error
+}
+
+// Update given columns of merchant, returns gorm.ErrRecordNotFound when code doesn't exist
+func (m *merchantRepository) Update(ctx context.Context, code string, fields map[string]any) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.Update")
+	defer func() { tracing.End(span, err) }()
+
+	result := m.db.WithContext(ctx).Table("merchant").Where("code = ?", code).Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// List merchants ordered by date joined, also returns total rows matching the filter
+func (m *merchantRepository) List(ctx context.Context, filter MerchantFilter) (_ []jwt.Merchant, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.List")
+	defer func() { tracing.End(span, err) }()
+
+	query := m.db.WithContext(ctx).Table("merchant")
+
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.JoinedFrom != nil {
+		query = query.Where("date_joined >= ?", *filter.JoinedFrom)
+	}
+	if filter.JoinedTo != nil {
+		query = query.Where("date_joined < ?", *filter.JoinedTo)
+	}
+
+	// Reused for count and page query
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var merchants []jwt.Merchant
+	if err := query.Order("date_joined DESC, code ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&merchants).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return merchants, total, nil
+}
+
+func (m *merchantRepository) CreateDetails(ctx context.Context, details *jwt.MerchantDetails) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.CreateDetails")
+	defer func() { tracing.End(span, err) }()
+
+	return m.db.WithContext(ctx).Table("merchant_details").Create(details).Error
+}
+
+// Details row is optional for merchants onboarded before the API existed, so no row is not an error
+func (m *merchantRepository) UpdateDetails(ctx context.Context, code string, fields map[string]any) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.UpdateDetails")
+	defer func() { tracing.End(span, err) }()
+
+	return m.db.WithContext(ctx).Table("merchant_details").Where("merchant_code = ?", code).Updates(fields).Error
+}
+
+func (m *merchantRepository) GetDetailsByCode(ctx context.Context, code string) (_ *jwt.MerchantDetails, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantRepository.GetDetailsByCode")
+	defer func() { tracing.End(span, err) }()
+
+	var details jwt.MerchantDetails
+
+	if err := m.db.WithContext(ctx).Table("merchant_details").Where("merchant_code = ?", code).First(&details).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &details, nil
+}
+
+func (m *merchantRepository) WithTransaction(trx *gorm.DB) MerchantRepository {
+	return &merchantRepository{db: trx}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	jwt "briefcash-jwt/internal/entity"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type MerchantSettingsRepository interface {
+	GetByMerchantCode(ctx context.Context, mCode string) (*jwt.MerchantSettings, error)
+	Create(ctx context.Context, settings *jwt.MerchantSettings) error
+	WithTransaction(tx *gorm.DB) MerchantSettingsRepository
+}
+
+type merchantSettingsRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantSettingsRepository(db *gorm.DB) MerchantSettingsRepository {
+	return &merchantSettingsRepository{db}
+}
+
+func (m *merchantSettingsRepository) GetByMerchantCode(ctx context.Context, mCode string) (_ *jwt.MerchantSettings, err error) {
+	ctx, span := tracing.Start(ctx, "MerchantSettingsRepository.GetByMerchantCode")
+	defer func() { tracing.End(span, err) }()
+
+	var settings jwt.MerchantSettings
+
+	if err := m.db.WithContext(ctx).Table("merchant_settings").Where("merchant_code = ?", mCode).First(&settings).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (m *merchantSettingsRepository) Create(ctx context.Context, settings *jwt.MerchantSettings) (err error) {
+	ctx, span := tracing.Start(ctx, "MerchantSettingsRepository.Create")
+	defer func() { tracing.End(span, err) }()
+
+	return m.db.WithContext(ctx).Table("merchant_settings").Create(settings).Error
+}
+
+func (m *merchantSettingsRepository) WithTransaction(trx *gorm.DB) MerchantSettingsRepository {
+	return &merchantSettingsRepository{db: trx}
+}
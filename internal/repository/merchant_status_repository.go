@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Idle listen connection is pinged at this interval, so a silently dropped connection is noticed
+const listenPingInterval = 30 * time.Second
+
+// Payload sent by merchant_status_notify trigger
+type MerchantStatusChange struct {
+	Code     string `json:"code"`
+	IsActive bool   `json:"is_active"`
+}
+
+type MerchantStatusRepository interface {
+	Listen(ctx context.Context, listening func(), handler func(change MerchantStatusChange)) error
+}
+
+type merchantStatusRepository struct {
+	pool    *pgxpool.Pool
+	channel string
+}
+
+func NewMerchantStatusRepository(pool *pgxpool.Pool) MerchantStatusRepository {
+	return &merchantStatusRepository{
+		pool:    pool,
+		channel: "merchant_status",
+	}
+}
+
+// Listen to merchant status notifications until context is cancelled, returns error when connection is lost.
+// Listening is called once LISTEN is in place, notifications sent while it runs are delivered after it returns.
+func (r *merchantStatusRepository) Listen(ctx context.Context, listening func(), handler func(change MerchantStatusChange)) error {
+	pooled, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire listen connection: %w", err)
+	}
+
+	// Connection keeps LISTEN state, take it out of the pool and close it when done
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+r.channel); err != nil {
+		return fmt.Errorf("failed to listen merchant status channel: %w", err)
+	}
+
+	if listening != nil {
+		listening()
+	}
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, listenPingInterval)
+		notification, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			if err := conn.Ping(ctx); err != nil {
+				return fmt.Errorf("merchant status connection lost: %w", err)
+			}
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("merchant status connection lost: %w", err)
+		}
+
+		var change MerchantStatusChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil || change.Code == "" {
+			continue
+		}
+		handler(change)
+	}
+}
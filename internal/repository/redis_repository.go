@@ -5,6 +5,8 @@ import (
 	"errors"
 	"time"
 
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -12,6 +14,7 @@ var ErrTokenNotFound = errors.New("token not found in redis")
 
 type RedisRepository interface {
 	SetToken(ctx context.Context, key, value string, ttl time.Duration) error
+	SetTokenIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
 	GetToken(ctx context.Context, key string) (string, error)
 	DeleteToken(ctx context.Context, key string) error
 	ExistToken(ctx context.Context, key string) (bool, error)
@@ -26,8 +29,11 @@ func NewRedisRepository(client *redis.Client) RedisRepository {
 	return &redisRepository{client: client}
 }
 
-func (r *redisRepository) SetToken(ctx context.Context, key, value string, ttl time.Duration) error {
-	err := r.client.Set(ctx, key, value, ttl).Err()
+func (r *redisRepository) SetToken(ctx context.Context, key, value string, ttl time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "RedisRepository.SetToken")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.client.Set(ctx, key, value, ttl).Err()
 
 	if err != nil {
 		return err
@@ -36,7 +42,18 @@ func (r *redisRepository) SetToken(ctx context.Context, key, value string, ttl t
 	return nil
 }
 
-func (r *redisRepository) GetToken(ctx context.Context, key string) (string, error) {
+// Set key only when it does not exist yet, return false when key already exists
+func (r *redisRepository) SetTokenIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "RedisRepository.SetTokenIfAbsent")
+	defer func() { tracing.End(span, err) }()
+
+	return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (r *redisRepository) GetToken(ctx context.Context, key string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "RedisRepository.GetToken")
+	defer func() { tracing.End(span, failure(err, ErrTokenNotFound)) }()
+
 	value, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return "", ErrTokenNotFound
@@ -49,11 +66,17 @@ func (r *redisRepository) GetToken(ctx context.Context, key string) (string, err
 	return value, nil
 }
 
-func (r *redisRepository) DeleteToken(ctx context.Context, key string) error {
+func (r *redisRepository) DeleteToken(ctx context.Context, key string) (err error) {
+	ctx, span := tracing.Start(ctx, "RedisRepository.DeleteToken")
+	defer func() { tracing.End(span, err) }()
+
 	return r.client.Del(ctx, key).Err()
 }
 
-func (r *redisRepository) ExistToken(ctx context.Context, key string) (bool, error) {
+func (r *redisRepository) ExistToken(ctx context.Context, key string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "RedisRepository.ExistToken")
+	defer func() { tracing.End(span, err) }()
+
 	result, err := r.client.Exists(ctx, key).Result()
 
 	if err != nil {
@@ -63,8 +86,11 @@ func (r *redisRepository) ExistToken(ctx context.Context, key string) (bool, err
 	return result > 0, nil
 }
 
-func (r *redisRepository) ExpireToken(ctx context.Context, key string, ttl time.Duration) error {
-	err := r.client.Expire(ctx, key, ttl).Err()
+func (r *redisRepository) ExpireToken(ctx context.Context, key string, ttl time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "RedisRepository.ExpireToken")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.client.Expire(ctx, key, ttl).Err()
 
 	if err != nil {
 		return err
@@ -0,0 +1,13 @@
+package repository
+
+import "errors"
+
+// Error worth marking span failed, expected outcome such as lookup without result is not a failed query
+func failure(err error, expected ...error) error {
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			return nil
+		}
+	}
+	return err
+}
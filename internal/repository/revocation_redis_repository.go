@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RevokedToken struct {
+	JTI       string `json:"jti"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type RevocationRepository interface {
+	Revoke(ctx context.Context, jti string, exp time.Time) error
+	ListRevoked(ctx context.Context) (map[string]time.Time, error)
+	Subscribe(ctx context.Context, handler func(token RevokedToken)) error
+}
+
+type revocationRepository struct {
+	client  *redis.Client
+	key     string
+	channel string
+}
+
+func NewRevocationRepository(client *redis.Client) RevocationRepository {
+	return &revocationRepository{
+		client:  client,
+		key:     "revoked_jti",
+		channel: "token_revocations",
+	}
+}
+
+// Store revoked jti scored by its expiry and notify every replica
+func (r *revocationRepository) Revoke(ctx context.Context, jti string, exp time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "RevocationRepository.Revoke")
+	defer func() { tracing.End(span, err) }()
+
+	payload, err := json.Marshal(RevokedToken{JTI: jti, ExpiresAt: exp.Unix()})
+	if err != nil {
+		return fmt.Errorf("failed to encode revoked token: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, r.key, redis.Z{Score: float64(exp.Unix()), Member: jti})
+	pipe.ZRemRangeByScore(ctx, r.key, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+	pipe.Publish(ctx, r.channel, payload)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish revoked token: %w", err)
+	}
+
+	return nil
+}
+
+// Get every revoked jti which has not expired yet
+func (r *revocationRepository) ListRevoked(ctx context.Context) (_ map[string]time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "RevocationRepository.ListRevoked")
+	defer func() { tracing.End(span, err) }()
+
+	members, err := r.client.ZRangeByScoreWithScores(ctx, r.key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revoked token from redis: %w", err)
+	}
+
+	revoked := make(map[string]time.Time, len(members))
+	for _, member := range members {
+		if jti, ok := member.Member.(string); ok {
+			revoked[jti] = time.Unix(int64(member.Score), 0)
+		}
+	}
+
+	return revoked, nil
+}
+
+// Listen to revocation channel until context is cancelled, reconnection is handled by go-redis
+func (r *revocationRepository) Subscribe(ctx context.Context, handler func(token RevokedToken)) error {
+	pubsub := r.client.Subscribe(ctx, r.channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe revocation channel: %w", err)
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return fmt.Errorf("revocation channel closed")
+			}
+
+			var token RevokedToken
+			if err := json.Unmarshal([]byte(message.Payload), &token); err != nil || token.JTI == "" {
+				continue
+			}
+			handler(token)
+		}
+	}
+}
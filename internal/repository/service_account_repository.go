@@ -0,0 +1,81 @@
+package repository
+
+import (
+	jwt "briefcash-jwt/internal/entity"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ServiceAccountRepository interface {
+	Create(ctx context.Context, account *jwt.ServiceAccount) error
+	GetByClientID(ctx context.Context, clientID string) (*jwt.ServiceAccount, error)
+	SetActive(ctx context.Context, clientID string, active bool) error
+	UpdateLastUsed(ctx context.Context, clientID string, usedAt time.Time) error
+}
+
+type serviceAccountRepository struct {
+	db *gorm.DB
+}
+
+func NewServiceAccountRepository(db *gorm.DB) ServiceAccountRepository {
+	return &serviceAccountRepository{db}
+}
+
+// Save new service account
+func (r *serviceAccountRepository) Create(ctx context.Context, account *jwt.ServiceAccount) (err error) {
+	ctx, span := tracing.Start(ctx, "ServiceAccountRepository.Create")
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Table("service_account").Create(account).Error
+}
+
+// Get service account by client id, nil when not registered
+func (r *serviceAccountRepository) GetByClientID(ctx context.Context, clientID string) (_ *jwt.ServiceAccount, err error) {
+	ctx, span := tracing.Start(ctx, "ServiceAccountRepository.GetByClientID")
+	defer func() { tracing.End(span, err) }()
+
+	var account jwt.ServiceAccount
+
+	if err := r.db.WithContext(ctx).Table("service_account").Where("client_id = ?", clientID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// Enable or disable service account
+func (r *serviceAccountRepository) SetActive(ctx context.Context, clientID string, active bool) (err error) {
+	ctx, span := tracing.Start(ctx, "ServiceAccountRepository.SetActive")
+	defer func() { tracing.End(span, err) }()
+
+	result := r.db.WithContext(ctx).Table("service_account").
+		Where("client_id = ?", clientID).
+		Update("is_active", active)
+
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Record last time service account requested a token
+func (r *serviceAccountRepository) UpdateLastUsed(ctx context.Context, clientID string, usedAt time.Time) (err error) {
+	ctx, span := tracing.Start(ctx, "ServiceAccountRepository.UpdateLastUsed")
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.WithContext(ctx).Table("service_account").
+		Where("client_id = ?", clientID).
+		Update("last_used_at", usedAt).Error
+}
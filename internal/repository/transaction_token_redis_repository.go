@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrTransactionTokenNotFound = errors.New("transaction token not found or expired")
+	ErrTransactionTokenConsumed = errors.New("transaction token already consumed")
+	ErrTransactionTokenMismatch = errors.New("transaction token does not match payload")
+	ErrTransactionTokenMerchant = errors.New("transaction token belongs to another merchant")
+)
+
+// Mark token consumed on first attempt of its own merchant, a mismatched payload also burns the token.
+// Another merchant leaves the token untouched, so it cannot burn tokens it does not own.
+// Returns 0 when missing, -1 when already consumed, -2 when hash differs, -3 when merchant differs,
+// otherwise 1 with stored fields.
+var consumeTransactionTokenScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {0}
+end
+if redis.call("HGET", KEYS[1], "merchant_id") ~= ARGV[2] then
+	return {-3}
+end
+if redis.call("HGET", KEYS[1], "consumed") == "1" then
+	return {-1}
+end
+redis.call("HSET", KEYS[1], "consumed", "1")
+if redis.call("HGET", KEYS[1], "payload_hash") ~= ARGV[1] then
+	return {-2}
+end
+return {1, redis.call("HGET", KEYS[1], "merchant_id"), redis.call("HGET", KEYS[1], "amount"), redis.call("HGET", KEYS[1], "issued_at")}`)
+
+// Single use token bound to one transfer payload
+type TransactionToken struct {
+	MerchantID  string
+	PayloadHash string
+	Amount      string
+	IssuedAt    time.Time
+}
+
+type TransactionTokenRepository interface {
+	Save(ctx context.Context, token string, record TransactionToken, ttl time.Duration) error
+	Consume(ctx context.Context, token, merchantID, payloadHash string) (*TransactionToken, error)
+}
+
+type transactionTokenRepository struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+func NewTransactionTokenRepository(client *redis.Client) TransactionTokenRepository {
+	return &transactionTokenRepository{
+		client:    client,
+		keyPrefix: "txn:",
+	}
+}
+
+func (r *transactionTokenRepository) Save(ctx context.Context, token string, record TransactionToken, ttl time.Duration) (err error) {
+	ctx, span := tracing.Start(ctx, "TransactionTokenRepository.Save")
+	defer func() { tracing.End(span, err) }()
+
+	key := r.keyPrefix + token
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"merchant_id", record.MerchantID,
+			"payload_hash", record.PayloadHash,
+			"amount", record.Amount,
+			"issued_at", record.IssuedAt.Unix(),
+			"consumed", "0",
+		)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save transaction token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *transactionTokenRepository) Consume(ctx context.Context, token, merchantID, payloadHash string) (_ *TransactionToken, err error) {
+	ctx, span := tracing.Start(ctx, "TransactionTokenRepository.Consume")
+	defer func() {
+		tracing.End(span, failure(err, ErrTransactionTokenNotFound, ErrTransactionTokenConsumed, ErrTransactionTokenMismatch, ErrTransactionTokenMerchant))
+	}()
+
+	result, err := consumeTransactionTokenScript.Run(ctx, r.client, []string{r.keyPrefix + token}, payloadHash, merchantID).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume transaction token: %w", err)
+	}
+
+	status, _ := result[0].(int64)
+	switch status {
+	case 0:
+		return nil, ErrTransactionTokenNotFound
+	case -1:
+		return nil, ErrTransactionTokenConsumed
+	case -2:
+		return nil, ErrTransactionTokenMismatch
+	case -3:
+		return nil, ErrTransactionTokenMerchant
+	}
+
+	if len(result) < 4 {
+		return nil, fmt.Errorf("failed to consume transaction token: unexpected script result")
+	}
+
+	amount, _ := result[2].(string)
+	issuedAtRaw, _ := result[3].(string)
+	issuedAt, _ := strconv.ParseInt(issuedAtRaw, 10, 64)
+
+	return &TransactionToken{
+		MerchantID:  merchantID,
+		PayloadHash: payloadHash,
+		Amount:      amount,
+		IssuedAt:    time.Unix(issuedAt, 0),
+	}, nil
+}
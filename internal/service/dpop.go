@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	logs "briefcash-jwt/internal/helper/loghelper"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	dpopProofType     = "dpop+jwt"
+	dpopReplayPrefix  = "dpop:"
+	defaultDPoPMaxAge = time.Minute
+)
+
+var ErrInvalidDPoPProof = errors.New("invalid dpop proof")
+
+// Asymmetric algorithms accepted for DPoP proof (RFC 9449 section 4.3)
+var dpopAlgorithms = []jose.SignatureAlgorithm{
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.EdDSA,
+}
+
+type dpopClaims struct {
+	JTI        string `json:"jti"`
+	Method     string `json:"htm"`
+	URL        string `json:"htu"`
+	IssuedAt   int64  `json:"iat"`
+	AccessHash string `json:"ath"`
+}
+
+// Verify DPoP proof JWT and return thumbprint of its public key.
+// accessToken is empty at issuance, otherwise proof must carry its hash in ath claim.
+func verifyDPoPProof(binding TokenBinding, accessToken string, maxAge time.Duration) (string, *dpopClaims, error) {
+	jws, err := jose.ParseSigned(binding.DPoPProof, dpopAlgorithms)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	if len(jws.Signatures) != 1 {
+		return "", nil, fmt.Errorf("%w: expected exactly one signature", ErrInvalidDPoPProof)
+	}
+
+	header := jws.Signatures[0].Header
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != dpopProofType {
+		return "", nil, fmt.Errorf("%w: typ must be %s", ErrInvalidDPoPProof, dpopProofType)
+	}
+
+	if header.JSONWebKey == nil || !header.JSONWebKey.IsPublic() {
+		return "", nil, fmt.Errorf("%w: header must contain public jwk", ErrInvalidDPoPProof)
+	}
+
+	payload, err := jws.Verify(header.JSONWebKey.Key)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: signature mismatch", ErrInvalidDPoPProof)
+	}
+
+	var claims dpopClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", nil, fmt.Errorf("%w: malformed claims", ErrInvalidDPoPProof)
+	}
+
+	if claims.JTI == "" {
+		return "", nil, fmt.Errorf("%w: jti is missing", ErrInvalidDPoPProof)
+	}
+
+	if !strings.EqualFold(claims.Method, binding.Method) {
+		return "", nil, fmt.Errorf("%w: htm does not match request method", ErrInvalidDPoPProof)
+	}
+
+	if !sameTargetURI(claims.URL, binding.URL) {
+		return "", nil, fmt.Errorf("%w: htu does not match request uri", ErrInvalidDPoPProof)
+	}
+
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	if age := time.Since(issuedAt); age > maxAge || age < -maxAge {
+		return "", nil, fmt.Errorf("%w: proof is not fresh", ErrInvalidDPoPProof)
+	}
+
+	if accessToken != "" && claims.AccessHash != accessTokenHash(accessToken) {
+		return "", nil, fmt.Errorf("%w: ath does not match access token", ErrInvalidDPoPProof)
+	}
+
+	thumbprint, err := header.JSONWebKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: failed to compute jwk thumbprint", ErrInvalidDPoPProof)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(thumbprint), &claims, nil
+}
+
+// Check and verify DPoP proof, then record its jti so the same proof can't be replayed
+func (ts *tokenService) checkDPoPProof(ctx context.Context, binding TokenBinding, accessToken string) (string, error) {
+	thumbprint, claims, err := verifyDPoPProof(binding, accessToken, ts.dpopMaxAge)
+	if err != nil {
+		return "", err
+	}
+
+	// Proof older than twice max age is rejected by iat check, so replay entry can expire after that
+	fresh, err := ts.redisRepo.SetTokenIfAbsent(ctx, dpopReplayPrefix+thumbprint+":"+claims.JTI, "used", 2*ts.dpopMaxAge)
+	if err != nil {
+		return "", fmt.Errorf("failed to check dpop proof replay: %w", err)
+	}
+
+	if !fresh {
+		return "", fmt.Errorf("%w: proof already used", ErrInvalidDPoPProof)
+	}
+
+	return thumbprint, nil
+}
+
+// Require fresh proof signed with the key the token is bound to
+func (ts *tokenService) verifyDPoPBinding(ctx context.Context, binding TokenBinding, jkt, stringToken string) error {
+	log := logs.Logger.WithField("jkt", jkt)
+
+	if binding.DPoPProof == "" {
+		log.Warn("DPoP bound token presented without proof")
+		return fmt.Errorf("%w: proof is required for this token", ErrInvalidDPoPProof)
+	}
+
+	thumbprint, err := ts.checkDPoPProof(ctx, binding, stringToken)
+	if err != nil {
+		log.WithError(err).Warn("DPoP proof rejected")
+		return err
+	}
+
+	if thumbprint != jkt {
+		log.Warn("DPoP proof signed with different key")
+		return fmt.Errorf("%w: proof key does not match token", ErrInvalidDPoPProof)
+	}
+
+	return nil
+}
+
+// Base64url encoded SHA-256 of access token, as used by ath claim
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Compare htu with request uri, ignoring query and fragment
+func sameTargetURI(proofURI, requestURI string) bool {
+	left, err := url.Parse(proofURI)
+	if err != nil {
+		return false
+	}
+	right, err := url.Parse(requestURI)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(left.Scheme, right.Scheme) &&
+		strings.EqualFold(left.Host, right.Host) &&
+		left.Path == right.Path
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const testTokenURL = "https://auth.briefcash.test/api/v1/token/generate"
+const testValidateURL = "https://auth.briefcash.test/api/v1/token/validate"
+
+func newDPoPProof(t *testing.T, key *ecdsa.PrivateKey, method, uri, accessToken string) string {
+	t.Helper()
+
+	opts := (&jose.SignerOptions{EmbedJWK: true}).WithType(dpopProofType)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, opts)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+
+	claims := map[string]any{
+		"jti": time.Now().Format(time.RFC3339Nano),
+		"htm": method,
+		"htu": uri,
+		"iat": time.Now().Unix(),
+	}
+	if accessToken != "" {
+		claims["ath"] = accessTokenHash(accessToken)
+	}
+
+	payload, _ := json.Marshal(claims)
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		t.Fatalf("Failed to sign proof: %v", err)
+	}
+
+	proof, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize proof: %v", err)
+	}
+	return proof
+}
+
+func TestValidateToken_DPoPBound(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(&MockJWTRepository{}, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	issueCtx := WithTokenBinding(context.Background(), TokenBinding{
+		DPoPProof: newDPoPProof(t, key, "POST", testTokenURL, ""),
+		Method:    "POST",
+		URL:       testTokenURL,
+	})
+	resp, err := svc.GenerateToken(issueCtx, dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.TokenType != TokenTypeDPoP {
+		t.Fatalf("Expected DPoP token type, got %s", resp.TokenType)
+	}
+
+	if _, err := svc.ValidateToken(context.Background(), resp.AccessToken); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Fatalf("Expected dpop error without proof, got %v", err)
+	}
+
+	binding := TokenBinding{
+		DPoPProof: newDPoPProof(t, key, "POST", testValidateURL, resp.AccessToken),
+		Method:    "POST",
+		URL:       testValidateURL,
+	}
+	if _, err := svc.ValidateToken(WithTokenBinding(context.Background(), binding), resp.AccessToken); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if _, err := svc.ValidateToken(WithTokenBinding(context.Background(), binding), resp.AccessToken); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Fatalf("Expected replayed proof to be rejected, got %v", err)
+	}
+}
+
+func TestValidateToken_DPoPDifferentKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(&MockJWTRepository{}, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	issueCtx := WithTokenBinding(context.Background(), TokenBinding{
+		DPoPProof: newDPoPProof(t, key, "POST", testTokenURL, ""),
+		Method:    "POST",
+		URL:       testTokenURL,
+	})
+	resp, err := svc.GenerateToken(issueCtx, dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	binding := TokenBinding{
+		DPoPProof: newDPoPProof(t, otherKey, "POST", testValidateURL, resp.AccessToken),
+		Method:    "POST",
+		URL:       testValidateURL,
+	}
+	if _, err := svc.ValidateToken(WithTokenBinding(context.Background(), binding), resp.AccessToken); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Fatalf("Expected dpop error, got %v", err)
+	}
+}
+
+func TestVerifyDPoPProof_MethodMismatch(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	binding := TokenBinding{
+		DPoPProof: newDPoPProof(t, key, "GET", testTokenURL, ""),
+		Method:    "POST",
+		URL:       testTokenURL + "?ignored=true",
+	}
+	if _, _, err := verifyDPoPProof(binding, "", time.Minute); !errors.Is(err, ErrInvalidDPoPProof) {
+		t.Fatalf("Expected dpop error, got %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+var (
+	jweKeyAlgorithms     = []jose.KeyAlgorithm{jose.RSA_OAEP_256, jose.ECDH_ES_A256KW}
+	jweContentEncryption = []jose.ContentEncryption{jose.A256GCM}
+)
+
+type EncryptionKey struct {
+	KeyID      string
+	PrivateKey crypto.Signer
+}
+
+// Wrap signed JWT into JWE (RSA-OAEP-256 or ECDH-ES+A256KW, A256GCM).
+// The first key encrypts new token, every key can decrypt so old key stay usable during rotation.
+type TokenEncrypter struct {
+	active    EncryptionKey
+	algorithm jose.KeyAlgorithm
+	keys      map[string]EncryptionKey
+}
+
+func NewTokenEncrypter(keys []EncryptionKey) (*TokenEncrypter, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+
+	encrypter := &TokenEncrypter{keys: make(map[string]EncryptionKey, len(keys))}
+	for index, key := range keys {
+		if _, err := keyAlgorithm(key.PrivateKey); err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", key.KeyID, err)
+		}
+		if _, exists := encrypter.keys[key.KeyID]; exists {
+			return nil, fmt.Errorf("duplicate encryption key id %s", key.KeyID)
+		}
+		encrypter.keys[key.KeyID] = key
+
+		if index == 0 {
+			encrypter.active = key
+			encrypter.algorithm, _ = keyAlgorithm(key.PrivateKey)
+		}
+	}
+
+	return encrypter, nil
+}
+
+func (e *TokenEncrypter) Encrypt(signedToken string) (string, error) {
+	options := (&jose.EncrypterOptions{}).WithContentType("JWT")
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{
+		Algorithm: e.algorithm,
+		Key:       e.active.PrivateKey.Public(),
+		KeyID:     e.active.KeyID,
+	}, options)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token encrypter: %w", err)
+	}
+
+	object, err := encrypter.Encrypt([]byte(signedToken))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt token: %w", err)
+	}
+
+	return object.CompactSerialize()
+}
+
+// Decrypt JWE and return the nested signed JWT
+func (e *TokenEncrypter) Decrypt(encryptedToken string) (string, error) {
+	object, err := jose.ParseEncryptedCompact(encryptedToken, jweKeyAlgorithms, jweContentEncryption)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse encrypted token: %w", err)
+	}
+
+	key, ok := e.keys[object.Header.KeyID]
+	if !ok {
+		return "", fmt.Errorf("unknown encryption key id %q", object.Header.KeyID)
+	}
+
+	plaintext, err := object.Decrypt(key.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt token: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// Public part of every encryption key, in JWKS format
+func (e *TokenEncrypter) KeySet() jose.JSONWebKeySet {
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(e.keys))}
+
+	// Active key first so clients pick it by default
+	set.Keys = append(set.Keys, e.publicKey(e.active))
+	for keyID, key := range e.keys {
+		if keyID != e.active.KeyID {
+			set.Keys = append(set.Keys, e.publicKey(key))
+		}
+	}
+
+	return set
+}
+
+func (e *TokenEncrypter) publicKey(key EncryptionKey) jose.JSONWebKey {
+	algorithm, _ := keyAlgorithm(key.PrivateKey)
+	return jose.JSONWebKey{
+		Key:       key.PrivateKey.Public(),
+		KeyID:     key.KeyID,
+		Algorithm: string(algorithm),
+		Use:       "enc",
+	}
+}
+
+func keyAlgorithm(key crypto.Signer) (jose.KeyAlgorithm, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return jose.RSA_OAEP_256, nil
+	case *ecdsa.PrivateKey:
+		return jose.ECDH_ES_A256KW, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T, use RSA or EC key", key)
+	}
+}
+
+// Compact JWE has five segments, signed JWT only three
+func isEncryptedToken(token string) bool {
+	return strings.Count(token, ".") == 4
+}
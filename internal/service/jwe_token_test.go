@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestEncrypter(t *testing.T) *TokenEncrypter {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed generating rsa key: %v", err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed generating ec key: %v", err)
+	}
+
+	encrypter, err := NewTokenEncrypter([]EncryptionKey{
+		{KeyID: "rsa-2026", PrivateKey: rsaKey},
+		{KeyID: "ec-2026", PrivateKey: ecKey},
+	})
+	if err != nil {
+		t.Fatalf("Failed creating encrypter: %v", err)
+	}
+
+	return encrypter
+}
+
+func TestTokenEncrypter_EncryptDecrypt(t *testing.T) {
+	encrypter := newTestEncrypter(t)
+
+	encrypted, err := encrypter.Encrypt("header.payload.signature")
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !isEncryptedToken(encrypted) {
+		t.Fatalf("Expected compact JWE, got %s", encrypted)
+	}
+
+	plain, err := encrypter.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if plain != "header.payload.signature" {
+		t.Fatalf("Unexpected decrypted value: %s", plain)
+	}
+
+	if keys := encrypter.KeySet().Keys; len(keys) != 2 || keys[0].KeyID != "rsa-2026" {
+		t.Fatalf("Unexpected key set: %+v", keys)
+	}
+}
+
+func TestNewTokenEncrypter_UnsupportedKey(t *testing.T) {
+	if _, err := NewTokenEncrypter([]EncryptionKey{{KeyID: "bad", PrivateKey: crypto.Signer(nil)}}); err == nil {
+		t.Fatal("Expected error but got nil")
+	}
+}
+
+func TestGenerateToken_EncryptedRoundTrip(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sr := &MockMerchantSettingsRepository{Settings: map[string]*model.MerchantSettings{
+		"STARK-1225": {MerchantCode: "STARK-1225", TokenFormat: TokenFormatJWE},
+	}}
+	svc := NewTokenService(jr, rr, sr, nil, nil, TokenConfig{Secret: "imamfahruzi", Encrypter: newTestEncrypter(t)})
+
+	resp, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !isEncryptedToken(resp.AccessToken) {
+		t.Fatalf("Expected encrypted access token, got %s", resp.AccessToken)
+	}
+
+	token, err := svc.ValidateToken(context.Background(), resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if claims := token.Claims.(jwt.MapClaims); claims["user_id"] != "STARK-1225" {
+		t.Fatalf("Unexpected claims: %v", claims)
+	}
+}
@@ -5,79 +5,310 @@ import (
 	"errors"
 	"fmt"
 	"time"
+	"unicode/utf8"
 
 	dto "briefcash-jwt/internal/dto"
 	model "briefcash-jwt/internal/entity"
 	logs "briefcash-jwt/internal/helper/loghelper"
+	metrics "briefcash-jwt/internal/helper/metricshelper"
 	mask "briefcash-jwt/internal/helper/securityhelper"
 	clock "briefcash-jwt/internal/helper/timehelper"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
 	repo "briefcash-jwt/internal/repository"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
+const (
+	ValidationStateful  = "stateful"
+	ValidationStateless = "stateless"
+
+	historyDateLayout   = "2006-01-02"
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+
+	// Size of jwt_token.user_agent column
+	maxUserAgentLength = 255
+)
+
+var (
+	ErrInvalidRequest  = errors.New("invalid request")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
 type TokenService interface {
 	GenerateToken(ctx context.Context, dto dto.JwtRequest) (*dto.JwtResponse, error)
 	ValidateToken(ctx context.Context, stringToken string) (*jwt.Token, error)
 	BlacklistToken(ctx context.Context, stringToken string) error
 	RefreshToken(ctx context.Context, refreshToken string) (*dto.JwtResponse, error)
+	ListActiveSessions(ctx context.Context, merchantID string) ([]dto.SessionResponse, error)
+	TerminateSession(ctx context.Context, sessionID int64) error
+	ListSessionHistory(ctx context.Context, req dto.SessionHistoryRequest) ([]dto.SessionHistoryResponse, error)
+	ExchangeToken(ctx context.Context, req dto.TokenExchangeRequest) (*dto.TokenExchangeResponse, error)
+	GenerateServiceToken(ctx context.Context, req dto.ServiceTokenRequest) (*dto.ServiceTokenResponse, error)
+	IsServiceAccountActive(ctx context.Context, clientID string) bool
+	RevokeTokensByAPIKey(ctx context.Context, apiKeyID int64) (int, error)
+	RevokeTokensByMerchant(ctx context.Context, merchantCode string) (int, error)
+	RevokeTokensByServiceAccount(ctx context.Context, clientID string) (int, error)
+	CountActiveSessions(ctx context.Context) (int64, error)
+	EncryptionKeys() jose.JSONWebKeySet
+	CheckSigningKeys() error
 }
 
-type tokenService struct {
-	jwtRepo   repo.JwtRepository
-	redisRepo repo.RedisRepository
-	db        *gorm.DB
-	jwtSecret string
+type TokenConfig struct {
+	Secret string
+
+	// ValidationStateless verifies signature and claims first, then only consults the revocation list
+	ValidationMode string
+
+	// Format used when merchant settings has no token format
+	DefaultFormat string
+
+	// Optional formats, enabled only when their key is configured
+	Encrypter    *TokenEncrypter
+	PasetoPublic TokenFormat
+	PasetoLocal  TokenFormat
+
+	// Accepted clock difference for DPoP proof iat, default one minute
+	DPoPProofMaxAge time.Duration
+
+	// Scopes each audience may receive through token exchange, and lifetime of exchanged token
+	ExchangeAudiences map[string][]string
+	ExchangeTTL       time.Duration
+
+	// Registry of service accounts, client credentials grant is disabled when nil
+	ServiceAccounts repo.ServiceAccountRepository
+
+	// Merchant api keys, merchant holding a key must present it to get a token. Not checked when nil
+	MerchantKeys repo.MerchantAPIKeyRepository
+
+	// Every merchant must present api key, set once key rollout finished
+	RequireMerchantKey bool
 }
 
-func NewTokenService(jr repo.JwtRepository, rr repo.RedisRepository, db *gorm.DB, secret string) TokenService {
-	return &tokenService{jr, rr, db, secret}
+type tokenService struct {
+	jwtRepo       repo.JwtRepository
+	redisRepo     repo.RedisRepository
+	settingsRepo  repo.MerchantSettingsRepository
+	db            *gorm.DB
+	revocations   *RevocationList
+	encrypter     *TokenEncrypter
+	formats       []TokenFormat
+	defaultFormat string
+	stateless     bool
+	dpopMaxAge    time.Duration
+	exchange      exchangePolicy
+
+	serviceAccounts    repo.ServiceAccountRepository
+	merchantKeys       repo.MerchantAPIKeyRepository
+	requireMerchantKey bool
 }
 
-func NewMockTokenService(jr repo.JwtRepository, rr repo.RedisRepository, secret string) TokenService {
+func NewTokenService(jr repo.JwtRepository, rr repo.RedisRepository, sr repo.MerchantSettingsRepository, db *gorm.DB, revocations *RevocationList, cfg TokenConfig) TokenService {
+	signer := &jwtFormat{secret: []byte(cfg.Secret)}
+	formats := []TokenFormat{signer}
+	if cfg.Encrypter != nil {
+		formats = append(formats, &jweFormat{signer: signer, encrypter: cfg.Encrypter})
+	}
+	if cfg.PasetoPublic != nil {
+		formats = append(formats, cfg.PasetoPublic)
+	}
+	if cfg.PasetoLocal != nil {
+		formats = append(formats, cfg.PasetoLocal)
+	}
+
+	defaultFormat := cfg.DefaultFormat
+	if defaultFormat == "" {
+		defaultFormat = TokenFormatJWT
+	}
+
+	dpopMaxAge := cfg.DPoPProofMaxAge
+	if dpopMaxAge <= 0 {
+		dpopMaxAge = defaultDPoPMaxAge
+	}
+
 	return &tokenService{
-		jwtRepo:   jr,
-		redisRepo: rr,
-		db:        nil,
-		jwtSecret: secret,
+		jwtRepo:       jr,
+		redisRepo:     rr,
+		settingsRepo:  sr,
+		db:            db,
+		revocations:   revocations,
+		encrypter:     cfg.Encrypter,
+		formats:       formats,
+		defaultFormat: defaultFormat,
+		stateless:     cfg.ValidationMode == ValidationStateless && revocations != nil,
+		dpopMaxAge:    dpopMaxAge,
+		exchange:      newExchangePolicy(cfg.ExchangeAudiences, cfg.ExchangeTTL),
+
+		serviceAccounts:    cfg.ServiceAccounts,
+		merchantKeys:       cfg.MerchantKeys,
+		requireMerchantKey: cfg.RequireMerchantKey,
 	}
 }
 
+func NewMockTokenService(jr repo.JwtRepository, rr repo.RedisRepository, secret string) TokenService {
+	return NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: secret})
+}
+
 func (ts *tokenService) GenerateToken(ctx context.Context, req dto.JwtRequest) (*dto.JwtResponse, error) {
+	response, err := ts.generateToken(ctx, req)
+	metrics.RecordToken(metrics.TokenIssued, err)
+	return response, err
+}
+
+func (ts *tokenService) generateToken(ctx context.Context, req dto.JwtRequest) (*dto.JwtResponse, error) {
+	apiKeyID, err := ts.authenticateMerchant(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation, err := ts.requestConfirmation(ctx)
+	if err != nil {
+		logs.Logger.WithError(err).WithField("user_id", req.UserID).Warn("DPoP proof rejected")
+		return nil, err
+	}
+
+	return ts.issueToken(ctx, req, issueOptions{confirmation: confirmation, apiKeyID: apiKeyID})
+}
+
+// Issuance options on top of default merchant access and refresh token pair
+type issueOptions struct {
+	// Access token is bound to these keys when present
+	confirmation tokenConfirmation
+
+	// Extra access token claims, e.g. aud, scope and act of exchanged token
+	claims jwt.MapClaims
+
+	// Access token lifetime, default to access token ttl
+	ttl time.Duration
+
+	// Skip refresh token, used by short lived delegated token
+	accessOnly bool
+
+	// Subject is a service account identified by client id, not a merchant
+	serviceAccount bool
+
+	// Merchant api key the token is issued with, tokens are revoked together with the key
+	apiKeyID *int64
+}
+
+// Issue access and refresh token, then store it to database and redis
+func (ts *tokenService) issueToken(ctx context.Context, req dto.JwtRequest, opts issueOptions) (*dto.JwtResponse, error) {
 	log := logs.Logger.WithField("user_id", req.UserID)
 	log.Infof("Generating %s token", req.Type)
 
+	if req.UserID == "" {
+		log.Error("User id is empty")
+		return nil, fmt.Errorf("user id not found")
+	}
+
 	var expAccessToken, expRefreshToken time.Time
 	var signedAccessToken, signedRefreshToken string
 	var err error
 
-	expAccessToken = time.Now().Add(ts.tokenTTL("access"))
+	accessTTL := ts.tokenTTL("access")
+	if opts.ttl > 0 {
+		accessTTL = opts.ttl
+	}
+
+	expAccessToken = time.Now().Add(accessTTL)
 	expRefreshToken = time.Now().Add(ts.tokenTTL("refresh"))
 
-	accessClaims := ts.buildClaims(req.UserID, expAccessToken)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	signedAccessToken, err = token.SignedString([]byte(ts.jwtSecret))
+	jti, err := mask.RandomID(16)
 	if err != nil {
-		log.WithError(err).Error("Failed signing access token")
+		log.WithError(err).Error("Failed generating token id")
 		return nil, fmt.Errorf("failed to generate access token")
 	}
 
+	format := ts.defaultFormat
+	if !opts.serviceAccount {
+		format, err = ts.resolveTokenFormat(ctx, req.UserID)
+		if err != nil {
+			log.WithError(err).Error("Failed resolving merchant token format")
+			return nil, fmt.Errorf("failed to generate access token")
+		}
+	}
+
+	accessClaims := ts.buildClaims(req.UserID, jti, expAccessToken)
 	refreshClaims := ts.buildRefreshClaims(req.UserID, expRefreshToken)
-	rtoken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	signedRefreshToken, err = rtoken.SignedString([]byte(ts.jwtSecret))
-	if err != nil {
-		log.WithError(err).Error("Failed signing refresh token")
-		return nil, fmt.Errorf("failed to generate refresh token")
+	if !opts.confirmation.isEmpty() {
+		accessClaims[confirmationClaim] = opts.confirmation.claims()
+	}
+	for name, value := range opts.claims {
+		accessClaims[name] = value
+	}
+	if opts.serviceAccount {
+		delete(accessClaims, "user_id")
+		accessClaims["sub"] = req.UserID
+		accessClaims["type"] = tokenTypeService
 	}
 
+	var serverClaims *string
+	if format == TokenFormatOpaque {
+		signedAccessToken, signedRefreshToken, serverClaims, err = issueOpaqueTokens(accessClaims)
+		if err != nil {
+			log.WithError(err).Error("Failed generating opaque token")
+			return nil, fmt.Errorf("failed to generate access token")
+		}
+
+		if opts.accessOnly {
+			signedRefreshToken = ""
+		}
+	} else {
+		tokenFormat, err := ts.format(format)
+		if err != nil {
+			log.WithError(err).Error("Merchant token format is not available")
+			return nil, fmt.Errorf("failed to generate access token")
+		}
+
+		signedAccessToken, err = tokenFormat.Sign(accessClaims)
+		if err != nil {
+			log.WithError(err).Error("Failed signing access token")
+			return nil, fmt.Errorf("failed to generate access token")
+		}
+
+		if !opts.accessOnly {
+			signedRefreshToken, err = tokenFormat.Sign(refreshClaims)
+			if err != nil {
+				log.WithError(err).Error("Failed signing refresh token")
+				return nil, fmt.Errorf("failed to generate refresh token")
+			}
+		}
+	}
+
+	now := time.Now()
 	tokenEntity := &model.JwtToken{
-		MerchantID:   req.UserID,
-		AccessToken:  signedAccessToken,
-		RefreshToken: signedRefreshToken,
-		CreatedAt:    time.Now(),
-		ExpiresAt:    expAccessToken,
-		IsRevoke:     false,
+		MerchantID:     req.UserID,
+		AccessToken:    signedAccessToken,
+		RefreshToken:   signedRefreshToken,
+		CreatedAt:      now,
+		ExpiresAt:      expAccessToken,
+		ClientIP:       req.ClientIP,
+		UserAgent:      truncateUserAgent(req.UserAgent),
+		TokenFormat:    format,
+		Claims:         serverClaims,
+		DPoPJKT:        opts.confirmation.JKT,
+		CertThumbprint: opts.confirmation.CertThumbprint,
+		APIKeyID:       opts.apiKeyID,
+		IsRevoke:       false,
+	}
+
+	// Row is kept until refresh token expires, delegated token without refresh token only lives as long as access token
+	tokenEntity.RefreshExpiresAt = &expAccessToken
+	if signedRefreshToken != "" {
+		tokenEntity.RefreshExpiresAt = &expRefreshToken
+	}
+
+	if req.Type == "refresh" {
+		tokenEntity.RefreshedAt = &now
+	}
+
+	if opts.serviceAccount {
+		tokenEntity.MerchantID = ""
+		tokenEntity.ServiceAccountID = &req.UserID
 	}
 
 	log.Infof("Saving %s token to database", req.Type)
@@ -87,7 +318,7 @@ func (ts *tokenService) GenerateToken(ctx context.Context, req dto.JwtRequest) (
 	}
 
 	log.Infof("Saving %s token to redis", req.Type)
-	if err := ts.redisRepo.SetToken(ctx, signedAccessToken, "valid", time.Until(expAccessToken)); err != nil {
+	if err := ts.cacheToken(ctx, tokenEntity); err != nil {
 		log.WithError(err).Warnf("Failed to cache %s token to redis", req.Type)
 	}
 
@@ -97,15 +328,73 @@ func (ts *tokenService) GenerateToken(ctx context.Context, req dto.JwtRequest) (
 }
 
 func (ts *tokenService) ValidateToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
+	token, err := ts.checkToken(ctx, stringToken)
+	metrics.RecordToken(metrics.TokenValidated, err)
+	return token, err
+}
+
+// Validate token state and signature, then its binding to the presented proof
+func (ts *tokenService) checkToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
+	token, err := ts.validateToken(ctx, stringToken)
+	if err != nil {
+		return nil, err
+	}
 
+	if err := ts.verifyTokenBinding(ctx, token, stringToken); err != nil {
+		return nil, err
+	}
+
+	if err := verifyTokenAudience(ctx, token); err != nil {
+		return nil, err
+	}
+
+	if claimString(token, "type") == tokenTypeService {
+		logs.Logger.WithField("service_account", claimString(token, "sub")).Info("Service account token validated")
+	}
+
+	return token, nil
+}
+
+func (ts *tokenService) validateToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
 	masked := mask.MaskToken(stringToken)
 	log := logs.Logger.WithField("token", masked)
 
+	if isOpaqueToken(stringToken) {
+		return ts.validateOpaqueToken(ctx, stringToken)
+	}
+
+	tokenFormat := ts.detectFormat(stringToken)
+	if tokenFormat == nil {
+		log.Warn("Unknown token format")
+		return nil, fmt.Errorf("token invalid or blacklisted")
+	}
+
+	if ts.stateless {
+		token, err := ts.verify(ctx, tokenFormat, stringToken)
+		if err != nil {
+			return nil, err
+		}
+
+		if jti := claimString(token, "jti"); jti != "" {
+			if ts.revocations.IsRevoked(jti) {
+				log.Warn("Token found in revocation list")
+				return nil, fmt.Errorf("token invalid or blacklisted")
+			}
+
+			log.Info("Token is valid")
+			return token, nil
+		}
+
+		// Token issued before jti was introduced cannot be checked against revocation list
+		log.Warn("Token has no jti, falling back to stateful validation")
+	}
+
 	log.Infof("Check token in redis")
 
 	val, err := ts.redisRepo.GetToken(ctx, stringToken)
 
 	if errors.Is(err, repo.ErrTokenNotFound) {
+		metrics.RecordCacheLookup(metrics.CacheMiss)
 		log.Warn("Token not found in redis, checking in database")
 
 		tokenData, dbErr := ts.jwtRepo.FindByAccessToken(ctx, stringToken)
@@ -114,6 +403,11 @@ func (ts *tokenService) ValidateToken(ctx context.Context, stringToken string) (
 			return nil, fmt.Errorf("token invalid or blacklisted")
 		}
 
+		if tokenData.IsRevoke {
+			log.Warn("Token revoked in database")
+			return nil, fmt.Errorf("token invalid or blacklisted")
+		}
+
 		go func() {
 			if err := ts.redisRepo.SetToken(context.Background(), tokenData.AccessToken, "valid", time.Until(tokenData.ExpiresAt)); err != nil {
 				log.WithError(err).Warn("Failed to cache token into redis")
@@ -122,8 +416,11 @@ func (ts *tokenService) ValidateToken(ctx context.Context, stringToken string) (
 
 		val = "valid"
 	} else if err != nil {
+		metrics.RecordCacheLookup(metrics.CacheError)
 		log.WithError(err).Error("Redis error while checking token")
 		return nil, fmt.Errorf("temporary cache issue, please retry")
+	} else {
+		metrics.RecordCacheLookup(metrics.CacheHit)
 	}
 
 	if val != "valid" {
@@ -131,21 +428,8 @@ func (ts *tokenService) ValidateToken(ctx context.Context, stringToken string) (
 		return nil, fmt.Errorf("token invalid or blacklisted")
 	}
 
-	log.Infof("Parsing JWT for token %s", masked)
-	token, err := jwt.Parse(stringToken, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(ts.jwtSecret), nil
-	})
-
+	token, err := ts.verify(ctx, tokenFormat, stringToken)
 	if err != nil {
-		if errors.Is(err, jwt.ErrTokenExpired) {
-			log.Warn("Token has expired")
-			return nil, fmt.Errorf("token expired")
-		}
-
-		log.WithError(err).Error("Unexpected error while parsing token")
 		return nil, err
 	}
 
@@ -154,7 +438,38 @@ func (ts *tokenService) ValidateToken(ctx context.Context, stringToken string) (
 	return token, nil
 }
 
+// Verify signature in its own span, so parsing cost is told apart from redis and db lookups
+func (ts *tokenService) verify(ctx context.Context, tokenFormat TokenFormat, stringToken string) (*jwt.Token, error) {
+	_, span := tracing.Start(ctx, "TokenFormat.Verify", attribute.String("token.format", tokenFormat.Name()))
+	token, err := tokenFormat.Verify(stringToken)
+	tracing.End(span, err)
+	return token, err
+}
+
+func (ts *tokenService) EncryptionKeys() jose.JSONWebKeySet {
+	if ts.encrypter == nil {
+		return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	}
+	return ts.encrypter.KeySet()
+}
+
+// Read string claim from parsed token, empty when missing
+func claimString(token *jwt.Token, name string) string {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	value, _ := claims[name].(string)
+	return value
+}
+
 func (ts *tokenService) BlacklistToken(ctx context.Context, stringToken string) error {
+	err := ts.blacklistToken(ctx, stringToken)
+	metrics.RecordToken(metrics.TokenRevoked, err)
+	return err
+}
+
+func (ts *tokenService) blacklistToken(ctx context.Context, stringToken string) error {
 	masked := mask.MaskToken(stringToken)
 	log := logs.Logger.WithField("token", masked)
 
@@ -164,13 +479,20 @@ func (ts *tokenService) BlacklistToken(ctx context.Context, stringToken string)
 		log.WithError(err).Warn("Failed to delete token from Redis (possibly already removed)")
 	}
 
+	if isOpaqueToken(stringToken) {
+		if err := ts.redisRepo.DeleteToken(ctx, opaqueKey(stringToken)); err != nil {
+			log.WithError(err).Warn("Failed to delete opaque token claims from Redis")
+		}
+	}
+
+	// Row is kept with is_revoke set, so purge job archives the revocation into history
 	tokenData, err := ts.jwtRepo.FindByAccessToken(ctx, stringToken)
 	if err != nil {
-		log.WithError(err).Warn("Token not found in database, skipping database deletion")
+		log.WithError(err).Warn("Token not found in database, skipping database revocation")
 		return err
 	} else {
-		if err := ts.deleteToken(ctx, stringToken); err != nil {
-			log.WithError(err).Error("Failed to delete token from database")
+		if err := ts.revokeToken(ctx, stringToken); err != nil {
+			log.WithError(err).Error("Failed to revoke token in database")
 			return err
 		}
 	}
@@ -183,6 +505,8 @@ func (ts *tokenService) BlacklistToken(ctx context.Context, stringToken string)
 		}
 	}
 
+	ts.revokeJTI(ctx, stringToken, time.Now().Add(ttl))
+
 	if err := ts.redisRepo.SetToken(ctx, "blacklist:"+stringToken, "true", ttl); err != nil {
 		log.WithError(err).Error("Failed to store blacklist token in redis")
 		return err
@@ -194,27 +518,209 @@ func (ts *tokenService) BlacklistToken(ctx context.Context, stringToken string)
 }
 
 func (ts *tokenService) RefreshToken(ctx context.Context, refreshToken string) (*dto.JwtResponse, error) {
+	response, err := ts.refreshToken(ctx, refreshToken)
+	metrics.RecordToken(metrics.TokenRefreshed, err)
+	return response, err
+}
+
+func (ts *tokenService) refreshToken(ctx context.Context, refreshToken string) (*dto.JwtResponse, error) {
 	masked := mask.MaskToken(refreshToken)
 	log := logs.Logger.WithField("token", masked)
 
+	// Delegated token is stored without refresh token, empty value must never match it
+	if refreshToken == "" {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
 	log.Info("Check refresh token in database")
 
+	// Refresh token of revoked row was already rotated or its session terminated
 	oldToken, err := ts.jwtRepo.FindByRefreshToken(ctx, refreshToken)
-	if err != nil {
+	if err != nil || oldToken.IsRevoke {
 		return nil, fmt.Errorf("invalid refresh token")
 	}
 
+	// Refresh of bound token must be proven with the same keys (RFC 9449 section 5, RFC 8705 section 4)
+	confirmation, err := ts.requestConfirmation(ctx)
+	if err != nil {
+		log.WithError(err).Warn("DPoP proof rejected")
+		return nil, err
+	}
+
+	if err := confirmation.matches(&tokenConfirmation{JKT: oldToken.DPoPJKT, CertThumbprint: oldToken.CertThumbprint}); err != nil {
+		log.WithError(err).Warn("Refresh of bound token without matching proof")
+		return nil, err
+	}
+
 	log.Info("Blacklist old token")
 	if err := ts.BlacklistToken(ctx, oldToken.AccessToken); err != nil {
 		logs.Logger.WithError(err).Warnf("Failed to blacklist old token")
 	}
 
 	refToken := dto.JwtRequest{
-		UserID: oldToken.MerchantID,
-		Type:   "refresh",
+		UserID:    oldToken.MerchantID,
+		Type:      "refresh",
+		ClientIP:  oldToken.ClientIP,
+		UserAgent: oldToken.UserAgent,
+	}
+
+	return ts.issueToken(ctx, refToken, issueOptions{confirmation: confirmation, apiKeyID: oldToken.APIKeyID})
+}
+
+func (ts *tokenService) ListActiveSessions(ctx context.Context, merchantID string) ([]dto.SessionResponse, error) {
+	log := logs.Logger.WithField("merchant_settings_id", merchantID)
+
+	log.Info("Retrieve active sessions from database")
+	tokens, err := ts.jwtRepo.FindActiveByMerchantID(ctx, merchantID)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve active sessions")
+		return nil, fmt.Errorf("failed to retrieve active sessions")
+	}
+
+	sessions := make([]dto.SessionResponse, 0, len(tokens))
+	for index := range tokens {
+		sessions = append(sessions, sessionResponse(&tokens[index]))
+	}
+
+	log.Infof("Total active sessions: %d", len(sessions))
+
+	return sessions, nil
+}
+
+// Count active sessions of every merchant and service account
+func (ts *tokenService) CountActiveSessions(ctx context.Context) (int64, error) {
+	count, err := ts.jwtRepo.CountActive(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active sessions: %w", err)
+	}
+	return count, nil
+}
+
+func (ts *tokenService) TerminateSession(ctx context.Context, sessionID int64) error {
+	log := logs.Logger.WithField("session_id", sessionID)
+
+	log.Info("Check session in database")
+	tokenData, err := ts.jwtRepo.FindByID(ctx, sessionID)
+	if errors.Is(err, repo.ErrSessionNotFound) {
+		log.Warn("Session not found in database")
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve session")
+		return err
+	}
+
+	log.WithField("merchant_settings_id", tokenData.MerchantID).Info("Terminating session")
+	if err := ts.BlacklistToken(ctx, tokenData.AccessToken); err != nil {
+		log.WithError(err).Error("Failed to terminate session")
+		return err
+	}
+
+	log.Info("Session successfully terminated")
+
+	return nil
+}
+
+func (ts *tokenService) ListSessionHistory(ctx context.Context, req dto.SessionHistoryRequest) ([]dto.SessionHistoryResponse, error) {
+	log := logs.Logger.WithField("merchant_settings_id", req.MerchantSettingsID)
+
+	from, err := time.Parse(historyDateLayout, req.From)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from date must use format %s", ErrInvalidRequest, historyDateLayout)
+	}
+
+	to, err := time.Parse(historyDateLayout, req.To)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to date must use format %s", ErrInvalidRequest, historyDateLayout)
+	}
+
+	// Include the whole last day of the range
+	to = to.AddDate(0, 0, 1)
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to date must not be before from date", ErrInvalidRequest)
+	}
+
+	limit := req.Limit
+	if limit <= 0 || limit > maxHistoryLimit {
+		limit = defaultHistoryLimit
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	log.Infof("Retrieve session history from %s to %s", req.From, req.To)
+	histories, err := ts.jwtRepo.FindHistory(ctx, req.MerchantSettingsID, from, to, limit, offset)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve session history")
+		return nil, fmt.Errorf("failed to retrieve session history")
+	}
+
+	result := make([]dto.SessionHistoryResponse, 0, len(histories))
+	for index := range histories {
+		result = append(result, sessionHistoryResponse(&histories[index]))
 	}
 
-	return ts.GenerateToken(ctx, refToken)
+	return result, nil
+}
+
+func sessionHistoryResponse(history *model.JwtTokenHistory) dto.SessionHistoryResponse {
+	lastRefreshed := ""
+	if history.RefreshedAt != nil {
+		lastRefreshed = clock.FormatTimeToISO7(*history.RefreshedAt)
+	}
+
+	return dto.SessionHistoryResponse{
+		SessionID:          history.ID,
+		MerchantSettingsID: history.MerchantID,
+		AccessTokenHash:    history.AccessTokenHash,
+		CreatedAt:          clock.FormatTimeToISO7(history.CreatedAt),
+		ExpiresAt:          clock.FormatTimeToISO7(history.ExpiresAt),
+		LastRefreshedAt:    lastRefreshed,
+		ArchivedAt:         clock.FormatTimeToISO7(history.ArchivedAt),
+		ClientIP:           history.ClientIP,
+		UserAgent:          history.UserAgent,
+		IsRevoke:           history.IsRevoke,
+	}
+}
+
+func sessionResponse(token *model.JwtToken) dto.SessionResponse {
+	lastRefreshed := ""
+	if token.RefreshedAt != nil {
+		lastRefreshed = clock.FormatTimeToISO7(*token.RefreshedAt)
+	}
+
+	return dto.SessionResponse{
+		SessionID:          token.ID,
+		MerchantSettingsID: token.MerchantID,
+		CreatedAt:          clock.FormatTimeToISO7(token.CreatedAt),
+		ExpiresAt:          clock.FormatTimeToISO7(token.ExpiresAt),
+		LastRefreshedAt:    lastRefreshed,
+		ClientIP:           token.ClientIP,
+		UserAgent:          token.UserAgent,
+	}
+}
+
+// Add token jti to revocation list, so stateless replicas reject it without store lookup
+func (ts *tokenService) revokeJTI(ctx context.Context, stringToken string, exp time.Time) {
+	if ts.revocations == nil {
+		return
+	}
+
+	token, err := ts.verifyToken(stringToken)
+	if err != nil {
+		return
+	}
+
+	jti := claimString(token, "jti")
+	if jti == "" {
+		return
+	}
+
+	if err := ts.revocations.Revoke(ctx, jti, exp); err != nil {
+		logs.Logger.WithError(err).WithField("jti", jti).Warn("Failed to publish token revocation")
+	}
 }
 
 func tokenResponse(token *model.JwtToken) *dto.JwtResponse {
@@ -222,11 +728,21 @@ func tokenResponse(token *model.JwtToken) *dto.JwtResponse {
 		UserID:       token.MerchantID,
 		RefreshToken: token.RefreshToken,
 		AccessToken:  token.AccessToken,
+		TokenFormat:  token.TokenFormat,
+		TokenType:    tokenType(token),
 		CreatedAt:    clock.FormatTimeToISO7(token.CreatedAt),
 		ExpiresAt:    clock.FormatTimeToISO7(token.ExpiresAt),
 	}
 }
 
+// Token type for Authorization header scheme, DPoP bound token must be sent with DPoP scheme
+func tokenType(token *model.JwtToken) string {
+	if token.DPoPJKT != "" {
+		return TokenTypeDPoP
+	}
+	return TokenTypeBearer
+}
+
 func (ts *tokenService) saveToken(ctx context.Context, token *model.JwtToken) error {
 
 	if ts.db == nil {
@@ -239,21 +755,23 @@ func (ts *tokenService) saveToken(ctx context.Context, token *model.JwtToken) er
 	})
 }
 
-func (ts *tokenService) deleteToken(ctx context.Context, stringToken string) error {
+func (ts *tokenService) revokeToken(ctx context.Context, stringToken string) error {
 	if ts.db == nil {
-		return ts.jwtRepo.DeleteByAccessToken(ctx, stringToken)
+		return ts.jwtRepo.RevokeByAccessToken(ctx, stringToken)
 	}
 
 	return ts.db.Transaction(func(tx *gorm.DB) error {
 		repoTx := ts.jwtRepo.WithTransaction(tx)
-		return repoTx.DeleteByAccessToken(ctx, stringToken)
+		return repoTx.RevokeByAccessToken(ctx, stringToken)
 	})
 }
 
-func (ts *tokenService) buildClaims(userID string, exp time.Time) jwt.MapClaims {
+func (ts *tokenService) buildClaims(userID, jti string, exp time.Time) jwt.MapClaims {
 	return jwt.MapClaims{
+		"jti":     jti,
 		"user_id": userID,
 		"type":    "access",
+		"iat":     time.Now().Unix(),
 		"exp":     exp.Unix(),
 	}
 }
@@ -274,3 +792,11 @@ func (ts *tokenService) tokenTTL(tokenType string) time.Duration {
 		return 15 * time.Minute
 	}
 }
+
+// Header is client controlled, longer value is cut to column size instead of failing issuance
+func truncateUserAgent(userAgent string) string {
+	if utf8.RuneCountInString(userAgent) <= maxUserAgentLength {
+		return userAgent
+	}
+	return string([]rune(userAgent)[:maxUserAgentLength])
+}
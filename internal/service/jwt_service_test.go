@@ -3,13 +3,16 @@ package service
 import (
 	dto "briefcash-jwt/internal/dto"
 	model "briefcash-jwt/internal/entity"
+	metrics "briefcash-jwt/internal/helper/metricshelper"
 	repo "briefcash-jwt/internal/repository"
 	"fmt"
 
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
@@ -21,11 +24,22 @@ type MockJWTRepository struct {
 	FindByAccessTokenErr     error
 	FindByRefreshTokenResult *model.JwtToken
 	FindByRefreshTokenErr    error
-	DeleteByAccessTokenErr   error
+	RevokeByAccessTokenErr   error
+	FindByIDResult           *model.JwtToken
+	FindByIDErr              error
+	FindActiveResult         []model.JwtToken
+	FindActiveErr            error
+	DeleteExpiredResults     []int64
+	DeleteExpiredErr         error
+	ArchivedPartitions       []time.Time
+	FindHistoryResult        []model.JwtTokenHistory
+	FindHistoryErr           error
 	WithTransactionCancelled bool
+	Saved                    *model.JwtToken
 }
 
-func (m *MockJWTRepository) Create(ctx context.Context, token *model.JwtToken) error {
+func (m *MockJWTRepository) Save(ctx context.Context, token *model.JwtToken) error {
+	m.Saved = token
 	return m.CreateErr
 }
 
@@ -42,8 +56,58 @@ func (m *MockJWTRepository) FindByRefreshToken(ctx context.Context, token string
 	return m.FindByRefreshTokenResult, m.FindByRefreshTokenErr
 }
 
-func (m *MockJWTRepository) DeleteAccessByToken(ctx context.Context, token string) error {
-	return m.DeleteByAccessTokenErr
+func (m *MockJWTRepository) RevokeByAccessToken(ctx context.Context, token string) error {
+	return m.RevokeByAccessTokenErr
+}
+
+func (m *MockJWTRepository) FindByID(ctx context.Context, id int64) (*model.JwtToken, error) {
+	return m.FindByIDResult, m.FindByIDErr
+}
+
+func (m *MockJWTRepository) FindActiveByMerchantID(ctx context.Context, merchantID string) ([]model.JwtToken, error) {
+	return m.FindActiveResult, m.FindActiveErr
+}
+
+func (m *MockJWTRepository) FindActiveByAPIKeyID(ctx context.Context, apiKeyID int64) ([]model.JwtToken, error) {
+	return m.FindActiveResult, m.FindActiveErr
+}
+
+func (m *MockJWTRepository) FindActiveByServiceAccountID(ctx context.Context, clientID string) ([]model.JwtToken, error) {
+	return m.FindActiveResult, m.FindActiveErr
+}
+
+func (m *MockJWTRepository) CountActive(ctx context.Context) (int64, error) {
+	return int64(len(m.FindActiveResult)), m.FindActiveErr
+}
+
+func (m *MockJWTRepository) DeleteExpiredBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if m.DeleteExpiredErr != nil || len(m.DeleteExpiredResults) == 0 {
+		return 0, m.DeleteExpiredErr
+	}
+	deleted := m.DeleteExpiredResults[0]
+	m.DeleteExpiredResults = m.DeleteExpiredResults[1:]
+	return deleted, nil
+}
+
+func (m *MockJWTRepository) ArchiveExpiredBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return m.DeleteExpiredBatch(ctx, before, limit)
+}
+
+func (m *MockJWTRepository) FindExpiredRange(ctx context.Context, before time.Time) (*time.Time, *time.Time, error) {
+	return nil, nil, nil
+}
+
+func (m *MockJWTRepository) EnsureHistoryPartitions(ctx context.Context, from, to time.Time) error {
+	m.ArchivedPartitions = append(m.ArchivedPartitions, from, to)
+	return nil
+}
+
+func (m *MockJWTRepository) DropHistoryPartitionsBefore(ctx context.Context, before time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockJWTRepository) FindHistory(ctx context.Context, merchantID string, from, to time.Time, limit, offset int) ([]model.JwtTokenHistory, error) {
+	return m.FindHistoryResult, m.FindHistoryErr
 }
 
 type MockRedisRepository struct {
@@ -62,6 +126,20 @@ func (r *MockRedisRepository) SetToken(ctx context.Context, key, value string, t
 	return nil
 }
 
+func (r *MockRedisRepository) SetTokenIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if r.Store == nil {
+		r.Store = make(map[string]string)
+	}
+	if r.Err != nil {
+		return false, r.Err
+	}
+	if _, ok := r.Store[key]; ok {
+		return false, nil
+	}
+	r.Store[key] = value
+	return true, nil
+}
+
 func (r *MockRedisRepository) GetToken(ctx context.Context, key string) (string, error) {
 	if r.Err != nil {
 		return "", r.Err
@@ -121,6 +199,40 @@ func TestGenerateToken(t *testing.T) {
 	}
 }
 
+func TestGenerateToken_KeepsRowUntilRefreshExpiry(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewMockTokenService(jr, rr, "imamfahruzi")
+	request := dto.JwtRequest{UserID: "STARK-1225", Type: "access"}
+
+	if _, err := svc.GenerateToken(context.Background(), request); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if jr.Saved == nil || jr.Saved.RefreshExpiresAt == nil {
+		t.Fatal("expected refresh expiry stored")
+	}
+
+	if !jr.Saved.RefreshExpiresAt.After(jr.Saved.ExpiresAt.Add(24 * time.Hour)) {
+		t.Fatalf("expected refresh expiry past purge retention, got %v", jr.Saved.RefreshExpiresAt)
+	}
+}
+
+func TestGenerateToken_TruncatesUserAgent(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewMockTokenService(jr, rr, "imamfahruzi")
+	request := dto.JwtRequest{UserID: "STARK-1225", Type: "access", UserAgent: strings.Repeat("é", 300)}
+
+	if _, err := svc.GenerateToken(context.Background(), request); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if got := utf8.RuneCountInString(jr.Saved.UserAgent); got != 255 {
+		t.Fatalf("expected user agent cut to 255 characters, got %d", got)
+	}
+}
+
 func TestGenerateToken_FailedUserId(t *testing.T) {
 	jr := &MockJWTRepository{}
 	rr := &MockRedisRepository{Store: make(map[string]string)}
@@ -191,6 +303,71 @@ func TestValidateToken_Failed(t *testing.T) {
 
 }
 
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatalf("Expected metrics to be gathered: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if labels[pair.GetName()] != pair.GetValue() {
+					continue metrics
+				}
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+
+	return 0
+}
+
+func TestValidateToken_RecordsCacheLookups(t *testing.T) {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "STARK-1225",
+		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+	}).SignedString([]byte("imamfahruzi"))
+
+	hit := map[string]string{"result": metrics.CacheHit}
+	miss := map[string]string{"result": metrics.CacheMiss}
+	validated := map[string]string{"operation": metrics.TokenValidated, "outcome": metrics.OutcomeSuccess}
+
+	hits := metricValue(t, "briefcash_jwt_token_cache_lookups_total", hit)
+	misses := metricValue(t, "briefcash_jwt_token_cache_lookups_total", miss)
+	validations := metricValue(t, "briefcash_jwt_token_operations_total", validated)
+
+	rr := &MockRedisRepository{Store: map[string]string{token: "valid"}}
+	if _, err := NewMockTokenService(&MockJWTRepository{}, rr, "imamfahruzi").ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	jr := &MockJWTRepository{FindByAccessTokenResult: &model.JwtToken{AccessToken: token, ExpiresAt: time.Now().Add(15 * time.Minute)}}
+	rr = &MockRedisRepository{Store: make(map[string]string), Err: repo.ErrTokenNotFound}
+	if _, err := NewMockTokenService(jr, rr, "imamfahruzi").ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("Expected database fallback to succeed: %v", err)
+	}
+
+	if got := metricValue(t, "briefcash_jwt_token_cache_lookups_total", hit) - hits; got != 1 {
+		t.Fatalf("Expected one cache hit, got %v", got)
+	}
+
+	if got := metricValue(t, "briefcash_jwt_token_cache_lookups_total", miss) - misses; got != 1 {
+		t.Fatalf("Expected one cache miss, got %v", got)
+	}
+
+	if got := metricValue(t, "briefcash_jwt_token_operations_total", validated) - validations; got != 2 {
+		t.Fatalf("Expected two successful validations, got %v", got)
+	}
+}
+
 func TestBlacklistToken_Success(t *testing.T) {
 
 	exp := time.Now().Add(15 * time.Minute)
@@ -270,3 +447,94 @@ func TestRefreshToken_Success(t *testing.T) {
 	}
 
 }
+
+func TestRefreshToken_RevokedRejected(t *testing.T) {
+	exp := time.Now().Add(15 * time.Minute)
+	refreshToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "STARK-1225",
+		"exp":     exp.Unix(),
+	}).SignedString([]byte("imamfahruzi"))
+
+	// Revoked row stays in table until purge archives it, its refresh token must not be reused
+	jr := &MockJWTRepository{
+		FindByRefreshTokenResult: &model.JwtToken{
+			AccessToken:  "access-1",
+			RefreshToken: refreshToken,
+			MerchantID:   "STARK-1225",
+			ExpiresAt:    exp,
+			IsRevoke:     true,
+		},
+	}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewMockTokenService(jr, rr, "imamfahruzi")
+
+	if _, err := svc.RefreshToken(context.Background(), refreshToken); err == nil {
+		t.Fatal("Expected refresh of revoked token to fail")
+	}
+
+	if jr.Saved != nil {
+		t.Fatal("Expected no token issued")
+	}
+}
+
+func TestListActiveSessions_Success(t *testing.T) {
+
+	refreshed := time.Now().Add(-5 * time.Minute)
+	jr := &MockJWTRepository{
+		FindActiveResult: []model.JwtToken{
+			{
+				ID:          1,
+				MerchantID:  "STARK-1225",
+				CreatedAt:   time.Now().Add(-10 * time.Minute),
+				ExpiresAt:   time.Now().Add(5 * time.Minute),
+				RefreshedAt: &refreshed,
+				ClientIP:    "10.10.1.2",
+				UserAgent:   "okhttp/4.12.0",
+			},
+		},
+	}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewMockTokenService(jr, rr, "imamfahruzi")
+
+	sessions, err := svc.ListActiveSessions(context.Background(), "STARK-1225")
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if len(sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d", len(sessions))
+	}
+
+	if sessions[0].ClientIP != "10.10.1.2" || sessions[0].LastRefreshedAt == "" {
+		t.Fatalf("Unexpected session value: %+v", sessions[0])
+	}
+}
+
+func TestTerminateSession_NotFound(t *testing.T) {
+
+	jr := &MockJWTRepository{
+		FindByIDErr: repo.ErrSessionNotFound,
+	}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewMockTokenService(jr, rr, "imamfahruzi")
+
+	if err := svc.TerminateSession(context.Background(), 99); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("Expected session not found, got %v", err)
+	}
+}
+
+func TestListSessionHistory_InvalidRange(t *testing.T) {
+
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewMockTokenService(jr, rr, "imamfahruzi")
+
+	_, err := svc.ListSessionHistory(context.Background(), dto.SessionHistoryRequest{
+		MerchantSettingsID: "STARK-1225",
+		From:               "2026-10-10",
+		To:                 "2026-10-01",
+	})
+	if err == nil {
+		t.Fatal("Expected error but got nil")
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "briefcash-jwt/internal/helper/loghelper"
+	repo "briefcash-jwt/internal/repository"
+)
+
+const defaultMerchantCacheSync = time.Minute
+
+// In-process copy of active merchant set, kept in sync with redis through pub/sub.
+// Until first load, and while subscription is down, lookups fall back to redis.
+type merchantCache struct {
+	mu         sync.RWMutex
+	codes      map[string]struct{}
+	ready      bool
+	subscribed bool
+	generation uint64
+}
+
+func newMerchantCache() *merchantCache {
+	return &merchantCache{codes: make(map[string]struct{})}
+}
+
+// Returns whether code is active, ok is false when cache can't answer
+func (c *merchantCache) lookup(mCode string) (active bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.ready {
+		return false, false
+	}
+
+	_, active = c.codes[mCode]
+	return active, true
+}
+
+func (c *merchantCache) loaded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ready
+}
+
+func (c *merchantCache) size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.codes)
+}
+
+func (c *merchantCache) snapshotGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// Install full set read from redis, skipped when a change was applied since the read started.
+// Set only answers lookups while subscribed, otherwise a change could be missed right after the read.
+func (c *merchantCache) replace(codes []string, generation uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+
+	c.codes = make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		c.codes[code] = struct{}{}
+	}
+	c.ready = c.subscribed
+	c.generation++
+
+	return true
+}
+
+// Mark change channel subscribed, next full load makes cache ready
+func (c *merchantCache) follow() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribed = true
+}
+
+func (c *merchantCache) set(mCode string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if active {
+		c.codes[mCode] = struct{}{}
+	} else {
+		delete(c.codes, mCode)
+	}
+	c.generation++
+}
+
+// Stop answering from memory, used when changes may have been missed
+func (c *merchantCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ready = false
+	c.generation++
+}
+
+// Stop answering from memory until subscribed again, used when change channel is lost
+func (c *merchantCache) unfollow() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ready = false
+	c.subscribed = false
+	c.generation++
+}
+
+// Follow the change channel, load merchant set from redis into memory once subscribed, and reload periodically
+// until context is cancelled. Returns after first subscribe attempt, loaded or not.
+func (s *merchantService) StartCache(ctx context.Context) {
+	rec := log.Logger.WithField("component", "merchant_cache")
+
+	first := make(chan struct{})
+	var once sync.Once
+	started := func() { once.Do(func() { close(first) }) }
+
+	go func() {
+		defer started()
+
+		for {
+			// Loaded only after subscription is confirmed, so no change published during the load is missed
+			err := s.redisRepo.Subscribe(ctx, func() {
+				s.cache.follow()
+				if err := s.reloadCache(ctx); err != nil {
+					rec.WithError(err).Warn("Failed to load merchant cache, validating against redis")
+				}
+				started()
+			}, s.applyChange)
+			if err != nil {
+				rec.WithError(err).Warn("Merchant change subscription interrupted, reconnecting")
+			}
+			started()
+
+			// Changes published while disconnected are lost, answer from redis until subscribed and reloaded
+			s.cache.unfollow()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	<-first
+
+	go func() {
+		ticker := time.NewTicker(s.cacheSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.reloadCache(ctx); err != nil {
+					rec.WithError(err).Warn("Failed to reload merchant cache")
+				}
+			}
+		}
+	}()
+}
+
+// Reports whether validation is answered from memory, false while loading or after a missed change
+func (s *merchantService) CacheReady() bool {
+	return s.cache.loaded()
+}
+
+// Number of active merchant codes held in memory, zero until first load
+func (s *merchantService) ActiveMerchants() int {
+	return s.cache.size()
+}
+
+func (s *merchantService) applyChange(change repo.MerchantChange) {
+	switch change.Action {
+	case repo.MerchantChangeAdd:
+		s.cache.set(change.Code, true)
+	case repo.MerchantChangeRemove:
+		s.cache.set(change.Code, false)
+	case repo.MerchantChangeSync:
+		if err := s.reloadCache(context.Background()); err != nil {
+			log.Logger.WithError(err).Warn("Failed to reload merchant cache after sync")
+			s.cache.invalidate()
+		}
+	}
+}
+
+// Read full set from redis, retried when a change lands while reading so an older snapshot never wins
+func (s *merchantService) reloadCache(ctx context.Context) error {
+	for attempt := 0; attempt < cacheWriteAttempts; attempt++ {
+		generation := s.cache.snapshotGeneration()
+
+		codes, err := s.redisRepo.GetAllMerchantCode(ctx)
+		if err != nil {
+			return err
+		}
+
+		if s.cache.replace(codes, generation) {
+			log.Logger.WithField("component", "merchant_cache").Debugf("Merchant cache loaded with %d codes", len(codes))
+			return nil
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	logs "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Verify merchant api key and secret, returns id of the key used.
+// While keys are being rolled out, merchant that never had a key is not asked for credentials.
+// Once a key was issued it's required for good, revoking or expiring it never reopens key-less issuance.
+func (ts *tokenService) authenticateMerchant(ctx context.Context, req dto.JwtRequest) (*int64, error) {
+	if ts.merchantKeys == nil {
+		return nil, nil
+	}
+
+	log := logs.Logger.WithField("user_id", req.UserID)
+
+	if req.APIKey == "" {
+		if ts.requireMerchantKey {
+			log.Warn("Merchant api key is missing")
+			return nil, fmt.Errorf("%w: api_key and api_secret are required", ErrInvalidClient)
+		}
+
+		issued, err := ts.merchantKeys.CountByMerchant(ctx, req.UserID)
+		if err != nil {
+			log.WithError(err).Error("Failed to retrieve merchant api keys")
+			return nil, fmt.Errorf("failed to generate access token")
+		}
+
+		if issued > 0 {
+			log.Warn("Merchant api key is missing")
+			return nil, fmt.Errorf("%w: api_key and api_secret are required", ErrInvalidClient)
+		}
+
+		return nil, nil
+	}
+
+	key, err := ts.merchantKeys.GetByAPIKey(ctx, req.APIKey)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve merchant api key")
+		return nil, fmt.Errorf("failed to generate access token")
+	}
+
+	if key == nil || key.MerchantCode != req.UserID || !apiKeyUsable(key, time.Now()) || !mask.VerifySecret(key.SecretHash, req.APISecret) {
+		log.WithField("api_key", mask.MaskToken(req.APIKey)).Warn("Merchant api key authentication failed")
+		return nil, ErrInvalidClient
+	}
+
+	return &key.ID, nil
+}
+
+// Blacklist every active token issued with the api key, returns number of tokens revoked
+func (ts *tokenService) RevokeTokensByAPIKey(ctx context.Context, apiKeyID int64) (int, error) {
+	log := logs.Logger.WithField("api_key_id", apiKeyID)
+
+	tokens, err := ts.jwtRepo.FindActiveByAPIKeyID(ctx, apiKeyID)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve tokens issued with api key")
+		return 0, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	revoked, err := ts.revokeTokens(ctx, tokens, log)
+	if err != nil {
+		return revoked, err
+	}
+
+	log.Infof("Revoked %d tokens issued with api key", revoked)
+
+	return revoked, nil
+}
+
+// Blacklist every active token of merchant, used when merchant is deactivated
+func (ts *tokenService) RevokeTokensByMerchant(ctx context.Context, merchantCode string) (int, error) {
+	log := logs.Logger.WithField("merchant_code", merchantCode)
+
+	tokens, err := ts.jwtRepo.FindActiveByMerchantID(ctx, merchantCode)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve active tokens of merchant")
+		return 0, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	revoked, err := ts.revokeTokens(ctx, tokens, log)
+	if err != nil {
+		return revoked, err
+	}
+
+	log.Infof("Revoked %d tokens of merchant", revoked)
+
+	return revoked, nil
+}
+
+// Blacklist every given token, continuing past failures so one token does not leave the rest valid.
+// Token another replica revoked meanwhile for the same event is skipped, not reported as failure.
+func (ts *tokenService) revokeTokens(ctx context.Context, tokens []model.JwtToken, log *logrus.Entry) (int, error) {
+	revoked := 0
+	var errs []error
+	for index := range tokens {
+		err := ts.BlacklistToken(ctx, tokens[index].AccessToken)
+		if errors.Is(err, repo.ErrAccessTokenNotFound) {
+			continue
+		}
+		if err != nil {
+			log.WithError(err).WithField("session_id", tokens[index].ID).Error("Failed to revoke token")
+			errs = append(errs, err)
+			continue
+		}
+		revoked++
+	}
+
+	if len(errs) > 0 {
+		return revoked, fmt.Errorf("failed to revoke %d of %d tokens: %w", len(errs), len(tokens), errors.Join(errs...))
+	}
+
+	return revoked, nil
+}
+
+// Key can still authenticate, not revoked and still within its rotation grace window
+func apiKeyUsable(key *model.MerchantAPIKey, now time.Time) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || now.Before(*key.ExpiresAt))
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	log "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	clock "briefcash-jwt/internal/helper/timehelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	merchantKeyPrefix       = "bk_"
+	defaultKeyRotationGrace = 24 * time.Hour
+
+	MerchantKeyStatusActive  = "active"
+	MerchantKeyStatusGrace   = "grace"
+	MerchantKeyStatusExpired = "expired"
+	MerchantKeyStatusRevoked = "revoked"
+)
+
+var (
+	ErrMerchantKeyExists   = errors.New("merchant already has an active api key")
+	ErrMerchantKeyNotFound = errors.New("merchant api key not found")
+)
+
+type MerchantKeyService interface {
+	GenerateKey(ctx context.Context, merchantCode string) (*dto.MerchantKeyResponse, error)
+	RotateKey(ctx context.Context, merchantCode string) (*dto.MerchantKeyResponse, error)
+	RevokeKey(ctx context.Context, req dto.MerchantKeyRevokeRequest) (*dto.MerchantKeyRevokeResponse, error)
+	ListKeys(ctx context.Context, merchantCode string) ([]dto.MerchantKeyResponse, error)
+}
+
+type merchantKeyService struct {
+	keys      repo.MerchantAPIKeyRepository
+	merchants repo.MerchantRepository
+	tokens    TokenService
+	db        *gorm.DB
+	grace     time.Duration
+}
+
+func NewMerchantKeyService(keys repo.MerchantAPIKeyRepository, merchants repo.MerchantRepository, tokens TokenService, db *gorm.DB, grace time.Duration) MerchantKeyService {
+	if grace <= 0 {
+		grace = defaultKeyRotationGrace
+	}
+	return &merchantKeyService{keys: keys, merchants: merchants, tokens: tokens, db: db, grace: grace}
+}
+
+// Issue first api key of merchant, secret is returned once and only its hash is stored
+func (s *merchantKeyService) GenerateKey(ctx context.Context, merchantCode string) (*dto.MerchantKeyResponse, error) {
+	rec := log.Logger.WithField("merchant_code", merchantCode)
+
+	if merchantCode == "" {
+		return nil, fmt.Errorf("%w: merchant_code is required", ErrInvalidRequest)
+	}
+
+	// Merchant row is locked, so concurrent requests can't both find no usable key
+	var response *dto.MerchantKeyResponse
+	err := s.inTransaction(func(keys repo.MerchantAPIKeyRepository, merchants repo.MerchantRepository) error {
+		if err := lockMerchant(ctx, merchants, merchantCode); err != nil {
+			return err
+		}
+
+		usable, err := keys.ListUsableByMerchant(ctx, merchantCode, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if len(usable) > 0 {
+			return ErrMerchantKeyExists
+		}
+
+		response, err = s.createKey(ctx, keys, merchantCode)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrMerchantKeyExists) || errors.Is(err, ErrMerchantNotFound) {
+			return nil, err
+		}
+		rec.WithError(err).Error("Failed to generate merchant api key")
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	rec.WithField("api_key", response.APIKey).Info("Merchant api key generated")
+
+	return response, nil
+}
+
+// Issue new api key, current key keeps working until grace window ends so clients can switch over.
+// Only two keys are usable at a time, a key still in grace from an earlier rotation expires immediately.
+func (s *merchantKeyService) RotateKey(ctx context.Context, merchantCode string) (*dto.MerchantKeyResponse, error) {
+	rec := log.Logger.WithField("merchant_code", merchantCode)
+
+	if merchantCode == "" {
+		return nil, fmt.Errorf("%w: merchant_code is required", ErrInvalidRequest)
+	}
+
+	// Merchant row is locked, so concurrent rotations can't leave more than two usable keys
+	var response *dto.MerchantKeyResponse
+	err := s.inTransaction(func(keys repo.MerchantAPIKeyRepository, merchants repo.MerchantRepository) error {
+		if err := lockMerchant(ctx, merchants, merchantCode); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		usable, err := keys.ListUsableByMerchant(ctx, merchantCode, now)
+		if err != nil {
+			return err
+		}
+
+		if len(usable) == 0 {
+			return ErrMerchantKeyNotFound
+		}
+
+		graceEnd := now.Add(s.grace)
+		for index := range usable {
+			expiresAt := graceEnd
+			if usable[index].ExpiresAt != nil {
+				expiresAt = now
+			}
+
+			if err := keys.SetExpiresAt(ctx, usable[index].ID, expiresAt); err != nil {
+				return err
+			}
+		}
+
+		response, err = s.createKey(ctx, keys, merchantCode)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrMerchantKeyNotFound) || errors.Is(err, ErrMerchantNotFound) {
+			return nil, err
+		}
+		rec.WithError(err).Error("Failed to rotate merchant api key")
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+
+	rec.WithFields(map[string]any{
+		"api_key": response.APIKey,
+		"grace":   s.grace.String(),
+	}).Info("Merchant api key rotated")
+
+	return response, nil
+}
+
+// Revoke api key and every token issued with it. Revoking an already revoked key
+// only retries token revocation, so a partial failure can be completed.
+func (s *merchantKeyService) RevokeKey(ctx context.Context, req dto.MerchantKeyRevokeRequest) (*dto.MerchantKeyRevokeResponse, error) {
+	rec := log.Logger.WithFields(map[string]any{
+		"merchant_code": req.MerchantCode,
+		"api_key":       req.APIKey,
+	})
+
+	if req.MerchantCode == "" || req.APIKey == "" {
+		return nil, fmt.Errorf("%w: merchant_code and api_key are required", ErrInvalidRequest)
+	}
+
+	key, err := s.keys.GetByAPIKey(ctx, req.APIKey)
+	if err != nil {
+		rec.WithError(err).Error("Failed to retrieve merchant api key")
+		return nil, fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	if key == nil || key.MerchantCode != req.MerchantCode {
+		return nil, ErrMerchantKeyNotFound
+	}
+
+	revokedAt := time.Now()
+	if key.RevokedAt == nil {
+		if err := s.keys.Revoke(ctx, key.ID, revokedAt, req.RevokedBy); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			rec.WithError(err).Error("Failed to revoke merchant api key")
+			return nil, fmt.Errorf("failed to revoke api key: %w", err)
+		}
+	} else {
+		revokedAt = *key.RevokedAt
+	}
+
+	revokedTokens, err := s.tokens.RevokeTokensByAPIKey(ctx, key.ID)
+	if err != nil {
+		rec.WithError(err).WithField("revoked_tokens", revokedTokens).Error("Api key revoked but not all of its tokens")
+		return nil, err
+	}
+
+	rec.WithFields(map[string]any{
+		"revoked_by":     req.RevokedBy,
+		"revoked_tokens": revokedTokens,
+	}).Info("Merchant api key revoked")
+
+	return &dto.MerchantKeyRevokeResponse{
+		MerchantCode:  key.MerchantCode,
+		APIKey:        key.APIKey,
+		RevokedAt:     clock.FormatTimeToISO7(revokedAt),
+		RevokedTokens: revokedTokens,
+	}, nil
+}
+
+func (s *merchantKeyService) ListKeys(ctx context.Context, merchantCode string) ([]dto.MerchantKeyResponse, error) {
+	if merchantCode == "" {
+		return nil, fmt.Errorf("%w: merchant_code is required", ErrInvalidRequest)
+	}
+
+	keys, err := s.keys.ListByMerchant(ctx, merchantCode)
+	if err != nil {
+		log.Logger.WithError(err).WithField("merchant_code", merchantCode).Error("Failed to list merchant api keys")
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	now := time.Now()
+	result := make([]dto.MerchantKeyResponse, 0, len(keys))
+	for index := range keys {
+		result = append(result, merchantKeyResponse(&keys[index], now))
+	}
+
+	return result, nil
+}
+
+func (s *merchantKeyService) createKey(ctx context.Context, keys repo.MerchantAPIKeyRepository, merchantCode string) (*dto.MerchantKeyResponse, error) {
+	id, err := mask.RandomID(16)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := mask.RandomID(32)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := mask.HashSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &model.MerchantAPIKey{
+		MerchantCode: merchantCode,
+		APIKey:       merchantKeyPrefix + id,
+		SecretHash:   hash,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := keys.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	response := merchantKeyResponse(key, key.CreatedAt)
+	response.APISecret = secret
+
+	return &response, nil
+}
+
+// Lock merchant row until transaction ends, key changes of one merchant run one at a time
+func lockMerchant(ctx context.Context, merchants repo.MerchantRepository, merchantCode string) error {
+	merchant, err := merchants.GetByCodeForUpdate(ctx, merchantCode)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve merchant: %w", err)
+	}
+
+	if merchant == nil {
+		return ErrMerchantNotFound
+	}
+
+	return nil
+}
+
+func (s *merchantKeyService) inTransaction(fn func(keys repo.MerchantAPIKeyRepository, merchants repo.MerchantRepository) error) error {
+	if s.db == nil {
+		return fn(s.keys, s.merchants)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(s.keys.WithTransaction(tx), s.merchants.WithTransaction(tx))
+	})
+}
+
+func merchantKeyResponse(key *model.MerchantAPIKey, now time.Time) dto.MerchantKeyResponse {
+	response := dto.MerchantKeyResponse{
+		MerchantCode: key.MerchantCode,
+		APIKey:       key.APIKey,
+		Status:       MerchantKeyStatusActive,
+		CreatedAt:    clock.FormatTimeToISO7(key.CreatedAt),
+	}
+
+	if key.ExpiresAt != nil {
+		response.ExpiresAt = clock.FormatTimeToISO7(*key.ExpiresAt)
+		response.Status = MerchantKeyStatusGrace
+		if !now.Before(*key.ExpiresAt) {
+			response.Status = MerchantKeyStatusExpired
+		}
+	}
+
+	if key.RevokedAt != nil {
+		response.RevokedAt = clock.FormatTimeToISO7(*key.RevokedAt)
+		response.Status = MerchantKeyStatusRevoked
+	}
+
+	return response
+}
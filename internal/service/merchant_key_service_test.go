@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type MockMerchantAPIKeyRepository struct {
+	Keys []*model.MerchantAPIKey
+	Err  error
+}
+
+func (m *MockMerchantAPIKeyRepository) Create(ctx context.Context, key *model.MerchantAPIKey) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	key.ID = int64(len(m.Keys) + 1)
+	m.Keys = append(m.Keys, key)
+	return nil
+}
+
+func (m *MockMerchantAPIKeyRepository) GetByAPIKey(ctx context.Context, apiKey string) (*model.MerchantAPIKey, error) {
+	for _, key := range m.Keys {
+		if key.APIKey == apiKey {
+			return key, m.Err
+		}
+	}
+	return nil, m.Err
+}
+
+func (m *MockMerchantAPIKeyRepository) ListByMerchant(ctx context.Context, merchantCode string) ([]model.MerchantAPIKey, error) {
+	var keys []model.MerchantAPIKey
+	for _, key := range m.Keys {
+		if key.MerchantCode == merchantCode {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, m.Err
+}
+
+func (m *MockMerchantAPIKeyRepository) ListUsableByMerchant(ctx context.Context, merchantCode string, now time.Time) ([]model.MerchantAPIKey, error) {
+	var keys []model.MerchantAPIKey
+	for _, key := range m.Keys {
+		if key.MerchantCode == merchantCode && apiKeyUsable(key, now) {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, m.Err
+}
+
+func (m *MockMerchantAPIKeyRepository) CountByMerchant(ctx context.Context, merchantCode string) (int64, error) {
+	keys, err := m.ListByMerchant(ctx, merchantCode)
+	return int64(len(keys)), err
+}
+
+func (m *MockMerchantAPIKeyRepository) SetExpiresAt(ctx context.Context, id int64, expiresAt time.Time) error {
+	for _, key := range m.Keys {
+		if key.ID == id {
+			key.ExpiresAt = &expiresAt
+		}
+	}
+	return m.Err
+}
+
+func (m *MockMerchantAPIKeyRepository) Revoke(ctx context.Context, id int64, revokedAt time.Time, revokedBy string) error {
+	for _, key := range m.Keys {
+		if key.ID == id && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+			key.RevokedBy = &revokedBy
+			return m.Err
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (m *MockMerchantAPIKeyRepository) WithTransaction(tx *gorm.DB) repo.MerchantAPIKeyRepository {
+	return m
+}
+
+func (m *MockMerchantAPIKeyRepository) usable(merchantCode string) int {
+	keys, _ := m.ListUsableByMerchant(context.Background(), merchantCode, time.Now())
+	return len(keys)
+}
+
+func newMerchantKeyService(keys *MockMerchantAPIKeyRepository, tokens TokenService) MerchantKeyService {
+	merchants := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"STARK-1225": {Code: "STARK-1225"}}}
+	return NewMerchantKeyService(keys, merchants, tokens, nil, time.Hour)
+}
+
+func TestGenerateKey_StoresOnlyHash(t *testing.T) {
+	keys := &MockMerchantAPIKeyRepository{}
+	svc := newMerchantKeyService(keys, nil)
+
+	resp, err := svc.GenerateKey(context.Background(), "STARK-1225")
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.APISecret == "" || resp.Status != MerchantKeyStatusActive {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if keys.Keys[0].SecretHash == resp.APISecret || !mask.VerifySecret(keys.Keys[0].SecretHash, resp.APISecret) {
+		t.Fatal("Expected secret stored as hash")
+	}
+
+	listed, err := svc.ListKeys(context.Background(), "STARK-1225")
+	if err != nil || len(listed) != 1 || listed[0].APISecret != "" {
+		t.Fatalf("Expected listed key without secret, got %+v %v", listed, err)
+	}
+
+	if _, err := svc.GenerateKey(context.Background(), "STARK-1225"); !errors.Is(err, ErrMerchantKeyExists) {
+		t.Fatalf("Expected ErrMerchantKeyExists, got %v", err)
+	}
+
+	if _, err := svc.GenerateKey(context.Background(), "WAYNE-0001"); !errors.Is(err, ErrMerchantNotFound) {
+		t.Fatalf("Expected ErrMerchantNotFound, got %v", err)
+	}
+}
+
+func TestRotateKey_KeepsTwoUsableKeys(t *testing.T) {
+	keys := &MockMerchantAPIKeyRepository{}
+	svc := newMerchantKeyService(keys, nil)
+
+	if _, err := svc.RotateKey(context.Background(), "STARK-1225"); !errors.Is(err, ErrMerchantKeyNotFound) {
+		t.Fatalf("Expected ErrMerchantKeyNotFound, got %v", err)
+	}
+
+	first, _ := svc.GenerateKey(context.Background(), "STARK-1225")
+	second, err := svc.RotateKey(context.Background(), "STARK-1225")
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if keys.usable("STARK-1225") != 2 {
+		t.Fatalf("Expected old and new key usable during grace, got %d", keys.usable("STARK-1225"))
+	}
+
+	old, _ := keys.GetByAPIKey(context.Background(), first.APIKey)
+	if old.ExpiresAt == nil || time.Until(*old.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("Expected grace window on old key, got %v", old.ExpiresAt)
+	}
+
+	if _, err := svc.RotateKey(context.Background(), "STARK-1225"); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if keys.usable("STARK-1225") != 2 {
+		t.Fatalf("Expected only two usable keys after second rotation, got %d", keys.usable("STARK-1225"))
+	}
+
+	if previous, _ := keys.GetByAPIKey(context.Background(), second.APIKey); !apiKeyUsable(previous, time.Now()) {
+		t.Fatal("Expected previous key usable within grace")
+	}
+	if apiKeyUsable(old, time.Now().Add(time.Millisecond)) {
+		t.Fatal("Expected first key expired after second rotation")
+	}
+}
+
+func TestRevokeKey_RevokesIssuedTokens(t *testing.T) {
+	keys := &MockMerchantAPIKeyRepository{}
+	jr := &MockJWTRepository{FindActiveResult: []model.JwtToken{
+		{ID: 1, AccessToken: "access-1", ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: 2, AccessToken: "access-2", ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	tokens := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+	svc := newMerchantKeyService(keys, tokens)
+
+	key, _ := svc.GenerateKey(context.Background(), "STARK-1225")
+
+	resp, err := svc.RevokeKey(context.Background(), dto.MerchantKeyRevokeRequest{
+		MerchantCode: "STARK-1225",
+		APIKey:       key.APIKey,
+		RevokedBy:    "api_key:ops",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.RevokedTokens != 2 {
+		t.Fatalf("Expected 2 revoked tokens, got %d", resp.RevokedTokens)
+	}
+	if rr.Store["blacklist:access-1"] != "true" || rr.Store["blacklist:access-2"] != "true" {
+		t.Fatal("Expected issued tokens blacklisted")
+	}
+	if keys.usable("STARK-1225") != 0 || *keys.Keys[0].RevokedBy != "api_key:ops" {
+		t.Fatal("Expected key revoked with actor")
+	}
+
+	if _, err := svc.RevokeKey(context.Background(), dto.MerchantKeyRevokeRequest{MerchantCode: "WAYNE-0001", APIKey: key.APIKey}); !errors.Is(err, ErrMerchantKeyNotFound) {
+		t.Fatalf("Expected ErrMerchantKeyNotFound, got %v", err)
+	}
+}
+
+func TestGenerateToken_RequiresMerchantKey(t *testing.T) {
+	keys := &MockMerchantAPIKeyRepository{}
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", MerchantKeys: keys})
+
+	// Merchant without key keeps working until a key is generated
+	if _, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"}); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	key, _ := newMerchantKeyService(keys, svc).GenerateKey(context.Background(), "STARK-1225")
+
+	if _, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"}); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("Expected ErrInvalidClient, got %v", err)
+	}
+
+	if _, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access", APIKey: key.APIKey, APISecret: "wrong"}); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("Expected ErrInvalidClient, got %v", err)
+	}
+
+	if _, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access", APIKey: key.APIKey, APISecret: key.APISecret}); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if jr.Saved.APIKeyID == nil || *jr.Saved.APIKeyID != keys.Keys[0].ID {
+		t.Fatalf("Expected token linked to api key, got %v", jr.Saved.APIKeyID)
+	}
+}
+
+func TestGenerateToken_RevokedKeyStillRequiresKey(t *testing.T) {
+	keys := &MockMerchantAPIKeyRepository{}
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", MerchantKeys: keys})
+	keyService := newMerchantKeyService(keys, svc)
+
+	key, _ := keyService.GenerateKey(context.Background(), "STARK-1225")
+	if _, err := keyService.RevokeKey(context.Background(), dto.MerchantKeyRevokeRequest{MerchantCode: "STARK-1225", APIKey: key.APIKey}); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	// Revoking the only key must not turn merchant into key-less issuer
+	if _, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"}); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("Expected ErrInvalidClient, got %v", err)
+	}
+
+	required := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", MerchantKeys: keys, RequireMerchantKey: true})
+	if _, err := required.GenerateToken(context.Background(), dto.JwtRequest{UserID: "WAYNE-0001", Type: "access"}); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("Expected ErrInvalidClient for merchant without key once keys are required, got %v", err)
+	}
+}
@@ -0,0 +1,443 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	log "briefcash-jwt/internal/helper/loghelper"
+	clock "briefcash-jwt/internal/helper/timehelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MerchantStatusActive    = "active"
+	MerchantStatusInactive  = "inactive"
+	MerchantStatusSuspended = "suspended"
+	MerchantStatusUnknown   = "unknown"
+
+	defaultMerchantListLimit = 50
+	maxMerchantListLimit     = 500
+)
+
+var (
+	ErrMerchantExists = errors.New("merchant already exists")
+
+	merchantCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+	phonePattern        = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+	countryPattern      = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	merchantTokenFormats = map[string]bool{
+		TokenFormatJWT:          true,
+		TokenFormatOpaque:       true,
+		TokenFormatJWE:          true,
+		TokenFormatPasetoPublic: true,
+		TokenFormatPasetoLocal:  true,
+	}
+)
+
+// Onboard merchant inactive, merchant_details and merchant_settings are created in the same transaction.
+// Merchant is activated afterwards through AddMerchantCode so redis follows the db.
+func (s *merchantService) CreateMerchant(ctx context.Context, req dto.MerchantCreateRequest) (*dto.MerchantResponse, error) {
+	rec := log.Logger.WithField("merchant_code", req.Code)
+
+	if !merchantCodePattern.MatchString(req.Code) {
+		return nil, fmt.Errorf("%w: merchant_code must be 3-20 letters, digits, - or _", ErrInvalidRequest)
+	}
+
+	if strings.TrimSpace(req.CompanyName) == "" {
+		return nil, fmt.Errorf("%w: company_name is required", ErrInvalidRequest)
+	}
+
+	if err := validateMerchantProfile(req.CompanyName, req.Address, req.Email, req.Phone, req.Website); err != nil {
+		return nil, err
+	}
+
+	if err := validateMerchantDetails(req.Details.Country); err != nil {
+		return nil, err
+	}
+
+	tokenFormat := req.Settings.TokenFormat
+	if tokenFormat == "" {
+		tokenFormat = TokenFormatJWT
+	}
+	if !merchantTokenFormats[tokenFormat] {
+		return nil, fmt.Errorf("%w: unsupported token_format %s", ErrInvalidRequest, tokenFormat)
+	}
+
+	joined := time.Now()
+	merchant := &model.Merchant{
+		Code:        req.Code,
+		CompanyName: req.CompanyName,
+		Address:     req.Address,
+		Email:       req.Email,
+		Phone:       req.Phone,
+		Website:     req.Website,
+		IsActive:    false,
+		DateJoined:  &joined,
+	}
+
+	details := &model.MerchantDetails{
+		MerchantCode:     req.Code,
+		DirectorName:     req.Details.DirectorName,
+		DirectorIDType:   req.Details.DirectorIDType,
+		DirectorIDNumber: req.Details.DirectorIDNumber,
+		Country:          req.Details.Country,
+		TaxNumber:        req.Details.TaxNumber,
+		BusinessType:     req.Details.BusinessType,
+		LegalID:          req.Details.LegalID,
+	}
+
+	settings := &model.MerchantSettings{
+		MerchantCode: req.Code,
+		ChannelID:    req.Settings.ChannelID,
+		PartnerID:    req.Settings.PartnerID,
+		TokenFormat:  tokenFormat,
+	}
+
+	err := s.inTransaction(func(merchants repo.MerchantRepository, settingsRepo repo.MerchantSettingsRepository) error {
+		existing, err := merchants.GetByCode(ctx, req.Code)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			return ErrMerchantExists
+		}
+
+		if err := merchants.Create(ctx, merchant); err != nil {
+			return err
+		}
+
+		if err := merchants.CreateDetails(ctx, details); err != nil {
+			return err
+		}
+
+		return settingsRepo.Create(ctx, settings)
+	})
+	if err != nil {
+		if errors.Is(err, ErrMerchantExists) {
+			return nil, err
+		}
+		rec.WithError(err).Error("Failed to create merchant")
+		return nil, fmt.Errorf("failed to create merchant: %w", err)
+	}
+
+	rec.Info("Merchant onboarded")
+
+	return merchantResponse(merchant, details, settings), nil
+}
+
+// Update merchant profile and details, active status is managed by AddMerchantCode and RemoveMerchantCode
+func (s *merchantService) UpdateMerchant(ctx context.Context, req dto.MerchantUpdateRequest) (*dto.MerchantResponse, error) {
+	rec := log.Logger.WithField("merchant_code", req.Code)
+
+	if req.Code == "" {
+		return nil, fmt.Errorf("%w: merchant_code is required", ErrInvalidRequest)
+	}
+
+	fields := map[string]any{}
+	setField := func(column string, value *string) {
+		if value != nil {
+			fields[column] = *value
+		}
+	}
+	setField("company_name", req.CompanyName)
+	setField("address", req.Address)
+	setField("email", req.Email)
+	setField("phone", req.Phone)
+	setField("website", req.Website)
+
+	if req.CompanyName != nil && strings.TrimSpace(*req.CompanyName) == "" {
+		return nil, fmt.Errorf("%w: company_name must not be empty", ErrInvalidRequest)
+	}
+
+	if err := validateMerchantProfile(valueOf(req.CompanyName), valueOf(req.Address), valueOf(req.Email), valueOf(req.Phone), valueOf(req.Website)); err != nil {
+		return nil, err
+	}
+
+	detailFields := map[string]any{}
+	if req.Details != nil {
+		setDetail := func(column string, value *string) {
+			if value != nil {
+				detailFields[column] = *value
+			}
+		}
+		setDetail("director_name", req.Details.DirectorName)
+		setDetail("director_id_type", req.Details.DirectorIDType)
+		setDetail("director_id_number", req.Details.DirectorIDNumber)
+		setDetail("merchant_country", req.Details.Country)
+		setDetail("merchant_tax_number", req.Details.TaxNumber)
+		setDetail("business_type", req.Details.BusinessType)
+		setDetail("merchant_legal_id", req.Details.LegalID)
+
+		if err := validateMerchantDetails(valueOf(req.Details.Country)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(fields) == 0 && len(detailFields) == 0 {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalidRequest)
+	}
+
+	err := s.inTransaction(func(merchants repo.MerchantRepository, _ repo.MerchantSettingsRepository) error {
+		merchant, err := merchants.GetByCodeForUpdate(ctx, req.Code)
+		if err != nil {
+			return err
+		}
+
+		if merchant == nil {
+			return ErrMerchantNotFound
+		}
+
+		if len(fields) > 0 {
+			if err := merchants.Update(ctx, req.Code, fields); err != nil {
+				return err
+			}
+		}
+
+		if len(detailFields) == 0 {
+			return nil
+		}
+
+		details, err := merchants.GetDetailsByCode(ctx, req.Code)
+		if err != nil {
+			return err
+		}
+
+		// Merchants onboarded by hand may have no details row yet
+		if details == nil {
+			details = &model.MerchantDetails{MerchantCode: req.Code}
+			applyDetailsUpdate(details, req.Details)
+			return merchants.CreateDetails(ctx, details)
+		}
+
+		return merchants.UpdateDetails(ctx, req.Code, detailFields)
+	})
+	if err != nil {
+		if errors.Is(err, ErrMerchantNotFound) {
+			return nil, err
+		}
+		rec.WithError(err).Error("Failed to update merchant")
+		return nil, fmt.Errorf("failed to update merchant: %w", err)
+	}
+
+	rec.WithField("fields", len(fields)+len(detailFields)).Info("Merchant updated")
+
+	return s.GetMerchant(ctx, req.Code)
+}
+
+func (s *merchantService) GetMerchant(ctx context.Context, mCode string) (*dto.MerchantResponse, error) {
+	rec := log.Logger.WithField("merchant_code", mCode)
+
+	merchant, err := s.dbRepo.GetByCode(ctx, mCode)
+	if err != nil {
+		rec.WithError(err).Error("Failed to retrieve merchant")
+		return nil, fmt.Errorf("failed to retrieve merchant: %w", err)
+	}
+
+	if merchant == nil {
+		return nil, ErrMerchantNotFound
+	}
+
+	details, err := s.dbRepo.GetDetailsByCode(ctx, mCode)
+	if err != nil {
+		rec.WithError(err).Error("Failed to retrieve merchant details")
+		return nil, fmt.Errorf("failed to retrieve merchant: %w", err)
+	}
+
+	settings, err := s.settingsRepo.GetByMerchantCode(ctx, mCode)
+	if err != nil {
+		rec.WithError(err).Error("Failed to retrieve merchant settings")
+		return nil, fmt.Errorf("failed to retrieve merchant: %w", err)
+	}
+
+	return merchantResponse(merchant, details, settings), nil
+}
+
+func (s *merchantService) ListMerchants(ctx context.Context, req dto.MerchantListRequest) (*dto.MerchantListResponse, error) {
+	filter := repo.MerchantFilter{Limit: req.Limit, Offset: req.Offset}
+
+	switch req.Status {
+	case "":
+	case MerchantStatusActive, MerchantStatusInactive:
+		active := req.Status == MerchantStatusActive
+		filter.IsActive = &active
+	default:
+		return nil, fmt.Errorf("%w: status must be %s or %s", ErrInvalidRequest, MerchantStatusActive, MerchantStatusInactive)
+	}
+
+	if req.JoinedFrom != "" {
+		from, err := time.Parse(historyDateLayout, req.JoinedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("%w: joined_from must use format %s", ErrInvalidRequest, historyDateLayout)
+		}
+		filter.JoinedFrom = &from
+	}
+
+	if req.JoinedTo != "" {
+		to, err := time.Parse(historyDateLayout, req.JoinedTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: joined_to must use format %s", ErrInvalidRequest, historyDateLayout)
+		}
+		// Include the whole last day of the range
+		to = to.AddDate(0, 0, 1)
+		filter.JoinedTo = &to
+	}
+
+	if filter.JoinedFrom != nil && filter.JoinedTo != nil && !filter.JoinedTo.After(*filter.JoinedFrom) {
+		return nil, fmt.Errorf("%w: joined_to must not be before joined_from", ErrInvalidRequest)
+	}
+
+	if filter.Limit <= 0 || filter.Limit > maxMerchantListLimit {
+		filter.Limit = defaultMerchantListLimit
+	}
+
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	merchants, total, err := s.dbRepo.List(ctx, filter)
+	if err != nil {
+		log.Logger.WithError(err).Error("Failed to list merchants")
+		return nil, fmt.Errorf("failed to list merchants: %w", err)
+	}
+
+	result := make([]dto.MerchantResponse, 0, len(merchants))
+	for index := range merchants {
+		result = append(result, *merchantResponse(&merchants[index], nil, nil))
+	}
+
+	return &dto.MerchantListResponse{
+		Merchants: result,
+		Total:     total,
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+	}, nil
+}
+
+// Run fn against repositories bound to one db transaction, falls back to plain repositories without db
+func (s *merchantService) inTransaction(fn func(merchants repo.MerchantRepository, settings repo.MerchantSettingsRepository) error) error {
+	if s.db == nil {
+		return fn(s.dbRepo, s.settingsRepo)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(s.dbRepo.WithTransaction(tx), s.settingsRepo.WithTransaction(tx))
+	})
+}
+
+// Empty values are skipped, column sizes follow the merchant table
+func validateMerchantProfile(companyName, address, email, phone, website string) error {
+	if len(companyName) > 50 {
+		return fmt.Errorf("%w: company_name must be at most 50 characters", ErrInvalidRequest)
+	}
+
+	if len(address) > 200 {
+		return fmt.Errorf("%w: address must be at most 200 characters", ErrInvalidRequest)
+	}
+
+	if email != "" {
+		parsed, err := mail.ParseAddress(email)
+		if err != nil || parsed.Address != email || len(email) > 50 {
+			return fmt.Errorf("%w: email is not a valid address", ErrInvalidRequest)
+		}
+	}
+
+	if phone != "" && !phonePattern.MatchString(phone) {
+		return fmt.Errorf("%w: phone must be 7-15 digits with optional leading +", ErrInvalidRequest)
+	}
+
+	if website != "" {
+		parsed, err := url.ParseRequestURI(website)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(website) > 50 {
+			return fmt.Errorf("%w: website must be an http or https URL", ErrInvalidRequest)
+		}
+	}
+
+	return nil
+}
+
+func validateMerchantDetails(country string) error {
+	if country != "" && !countryPattern.MatchString(country) {
+		return fmt.Errorf("%w: country must be an ISO 3166 alpha-3 code", ErrInvalidRequest)
+	}
+	return nil
+}
+
+func applyDetailsUpdate(details *model.MerchantDetails, req *dto.MerchantDetailsUpdateRequest) {
+	details.DirectorName = valueOf(req.DirectorName)
+	details.DirectorIDType = valueOf(req.DirectorIDType)
+	details.DirectorIDNumber = valueOf(req.DirectorIDNumber)
+	details.Country = valueOf(req.Country)
+	details.TaxNumber = valueOf(req.TaxNumber)
+	details.BusinessType = valueOf(req.BusinessType)
+	details.LegalID = valueOf(req.LegalID)
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func merchantResponse(merchant *model.Merchant, details *model.MerchantDetails, settings *model.MerchantSettings) *dto.MerchantResponse {
+	dateJoined := ""
+	if merchant.DateJoined != nil {
+		dateJoined = clock.FormatTimeToISO7(*merchant.DateJoined)
+	}
+
+	response := &dto.MerchantResponse{
+		Code:        merchant.Code,
+		CompanyName: merchant.CompanyName,
+		Address:     merchant.Address,
+		Email:       merchant.Email,
+		Phone:       merchant.Phone,
+		Website:     merchant.Website,
+		IsActive:    merchant.IsActive,
+		DateJoined:  dateJoined,
+	}
+
+	if details != nil {
+		response.Details = &dto.MerchantDetailsResponse{
+			DirectorName:     details.DirectorName,
+			DirectorIDType:   details.DirectorIDType,
+			DirectorIDNumber: details.DirectorIDNumber,
+			Country:          details.Country,
+			TaxNumber:        details.TaxNumber,
+			BusinessType:     details.BusinessType,
+			LegalID:          details.LegalID,
+		}
+	}
+
+	if settings != nil {
+		response.Settings = &dto.MerchantSettingsResponse{
+			ChannelID:   settings.ChannelID,
+			PartnerID:   settings.PartnerID,
+			TokenFormat: settings.TokenFormat,
+		}
+	}
+
+	if merchant.SuspendedAt != nil && !merchant.IsActive {
+		response.Suspension = &dto.MerchantSuspensionResponse{
+			Reason:      valueOf(merchant.SuspendedReason),
+			SuspendedBy: valueOf(merchant.SuspendedBy),
+			SuspendedAt: clock.FormatTimeToISO7(*merchant.SuspendedAt),
+		}
+		if merchant.SuspendedUntil != nil {
+			response.Suspension.SuspendedUntil = clock.FormatTimeToISO7(*merchant.SuspendedUntil)
+		}
+	}
+
+	return response
+}
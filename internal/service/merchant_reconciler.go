@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	log "briefcash-jwt/internal/helper/loghelper"
+	metrics "briefcash-jwt/internal/helper/metricshelper"
+	repo "briefcash-jwt/internal/repository"
+)
+
+const (
+	merchantReconcileLease           = "merchant_reconcile"
+	defaultMerchantReconcileInterval = 5 * time.Minute
+)
+
+// Counters of db to redis reconciliation, exposed for monitoring
+type MerchantDriftStats struct {
+	Runs        int64     `json:"runs"`
+	Skipped     int64     `json:"skipped"`
+	Failures    int64     `json:"failures"`
+	DriftRuns   int64     `json:"drift_runs"`
+	Added       int64     `json:"added"`
+	Removed     int64     `json:"removed"`
+	LastAdded   int       `json:"last_added"`
+	LastRemoved int       `json:"last_removed"`
+	Reactivated int64     `json:"reactivated"`
+	LastRunAt   time.Time `json:"last_run_at"`
+}
+
+// Periodically repair drift between merchant.is_active and redis set, revoke tokens of deactivated merchants
+// and reactivate merchants whose suspension ended, only the lease holder runs it
+func (s *merchantService) StartReconciler(ctx context.Context, leases repo.LeaseRepository, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultMerchantReconcileInterval
+	}
+
+	s.leaseRepo = leases
+	s.leaseTTL = interval
+	rec := log.Logger.WithField("job", merchantReconcileLease)
+	rec.Infof("Merchant reconcile job started (interval: %s)", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				rec.Info("Merchant reconcile job stopped")
+				return
+			case <-ticker.C:
+				s.reconcileOnce(ctx, interval)
+			}
+		}
+	}()
+}
+
+func (s *merchantService) reconcileOnce(ctx context.Context, interval time.Duration) {
+	rec := log.Logger.WithFields(map[string]any{
+		"job":   merchantReconcileLease,
+		"owner": s.owner,
+	})
+
+	acquired, err := s.leaseRepo.Acquire(ctx, merchantReconcileLease, s.owner, interval)
+	if err != nil {
+		rec.WithError(err).Error("Failed to acquire merchant reconcile lease")
+		s.recordDrift(func(st *MerchantDriftStats) { st.Failures++ })
+		return
+	}
+
+	if !acquired {
+		rec.Debug("Merchant reconcile lease held by another instance, skipping")
+		s.recordDrift(func(st *MerchantDriftStats) { st.Skipped++ })
+		return
+	}
+
+	defer func() {
+		if err := s.leaseRepo.Release(context.Background(), merchantReconcileLease, s.owner); err != nil {
+			rec.WithError(err).Warn("Failed to release merchant reconcile lease")
+		}
+	}()
+
+	// Also revokes merchants queued while the lease was held by this run
+	s.reconcileAndRevoke(ctx)
+
+	reactivated, err := s.ReactivateSuspended(ctx)
+	if err != nil {
+		rec.WithError(err).Error("Failed to reactivate suspended merchants")
+	}
+	if len(reactivated) > 0 {
+		s.recordDrift(func(st *MerchantDriftStats) { st.Reactivated += int64(len(reactivated)) })
+	}
+}
+
+// Compare active merchants in db with redis set and repair every code that differs.
+// Each drifted code is re-read from db before repair, so a change made meanwhile is never undone.
+func (s *merchantService) ReconcileDrift(ctx context.Context) (*dto.MerchantSyncResponse, error) {
+	start := time.Now()
+	rec := log.Logger.WithField("job", merchantReconcileLease)
+
+	dbCodes, err := s.dbRepo.GetAllActiveCode(ctx)
+	if err != nil {
+		s.finishReconcile(start, nil, true)
+		return nil, fmt.Errorf("failed to load merchant code from db: %w", err)
+	}
+
+	redisCodes, err := s.redisRepo.GetAllMerchantCode(ctx)
+	if err != nil {
+		s.finishReconcile(start, nil, true)
+		return nil, fmt.Errorf("failed to load merchant code from redis: %w", err)
+	}
+
+	result := &dto.MerchantSyncResponse{Total: len(dbCodes), Added: []string{}, Removed: []string{}}
+	failed := false
+	for _, code := range symmetricDiff(dbCodes, redisCodes) {
+		merchant, err := s.dbRepo.GetByCode(ctx, code)
+		if err != nil {
+			rec.WithError(err).WithField("merchant_code", code).Warn("Failed to read merchant code from db while reconciling")
+			failed = true
+			continue
+		}
+
+		active, err := s.restoreCache(ctx, code, merchant)
+		if err != nil {
+			rec.WithError(err).WithField("merchant_code", code).Warn("Failed to repair merchant code in redis")
+			failed = true
+			continue
+		}
+
+		if active {
+			result.Added = append(result.Added, code)
+		} else {
+			result.Removed = append(result.Removed, code)
+		}
+	}
+
+	slices.Sort(result.Added)
+	slices.Sort(result.Removed)
+	s.finishReconcile(start, result, failed)
+
+	if len(result.Added) > 0 || len(result.Removed) > 0 {
+		rec.WithFields(map[string]any{
+			"added":   result.Added,
+			"removed": result.Removed,
+		}).Warn("Merchant drift between db and redis repaired")
+	}
+
+	if failed {
+		return result, fmt.Errorf("failed to repair every drifted merchant code")
+	}
+
+	return result, nil
+}
+
+func (s *merchantService) DriftStats() MerchantDriftStats {
+	s.driftMu.Lock()
+	defer s.driftMu.Unlock()
+	return s.driftStats
+}
+
+// Export reconcile counters, read from DriftStats on every scrape
+func RegisterDriftMetrics(merchants MerchantService) {
+	runs := "Merchant reconcile runs by result, skipped when another replica holds the lease."
+	metrics.RegisterCounterFunc("merchant_reconcile_runs_total", runs, map[string]string{"result": "run"}, func() float64 {
+		return float64(merchants.DriftStats().Runs)
+	})
+	metrics.RegisterCounterFunc("merchant_reconcile_runs_total", runs, map[string]string{"result": "skipped"}, func() float64 {
+		return float64(merchants.DriftStats().Skipped)
+	})
+	metrics.RegisterCounterFunc("merchant_reconcile_runs_total", runs, map[string]string{"result": "failed"}, func() float64 {
+		return float64(merchants.DriftStats().Failures)
+	})
+	metrics.RegisterCounterFunc("merchant_reconcile_drift_runs_total", "Merchant reconcile runs that found drift between db and redis.", nil, func() float64 {
+		return float64(merchants.DriftStats().DriftRuns)
+	})
+	drift := "Merchant codes repaired in redis by reconcile job."
+	metrics.RegisterCounterFunc("merchant_drift_codes_total", drift, map[string]string{"action": "added"}, func() float64 {
+		return float64(merchants.DriftStats().Added)
+	})
+	metrics.RegisterCounterFunc("merchant_drift_codes_total", drift, map[string]string{"action": "removed"}, func() float64 {
+		return float64(merchants.DriftStats().Removed)
+	})
+	metrics.RegisterCounterFunc("merchant_reactivated_total", "Suspended merchants reactivated once suspension ended.", nil, func() float64 {
+		return float64(merchants.DriftStats().Reactivated)
+	})
+	metrics.RegisterGaugeFunc("merchant_reconcile_last_run_timestamp_seconds", "Start of last merchant reconcile run on this replica.", func() float64 {
+		lastRun := merchants.DriftStats().LastRunAt
+		if lastRun.IsZero() {
+			return 0
+		}
+		return float64(lastRun.Unix())
+	})
+}
+
+func (s *merchantService) finishReconcile(start time.Time, result *dto.MerchantSyncResponse, failed bool) {
+	s.recordDrift(func(st *MerchantDriftStats) {
+		st.Runs++
+		st.LastRunAt = start
+		st.LastAdded, st.LastRemoved = 0, 0
+		if result != nil {
+			st.LastAdded, st.LastRemoved = len(result.Added), len(result.Removed)
+			st.Added += int64(st.LastAdded)
+			st.Removed += int64(st.LastRemoved)
+			if st.LastAdded > 0 || st.LastRemoved > 0 {
+				st.DriftRuns++
+			}
+		}
+		if failed {
+			st.Failures++
+		}
+	})
+}
+
+func (s *merchantService) recordDrift(update func(st *MerchantDriftStats)) {
+	s.driftMu.Lock()
+	defer s.driftMu.Unlock()
+	update(&s.driftStats)
+}
+
+// Codes present in only one of both lists
+func symmetricDiff(left, right []string) []string {
+	seen := make(map[string]int, len(left)+len(right))
+	for _, code := range left {
+		seen[code] |= 1
+	}
+	for _, code := range right {
+		seen[code] |= 2
+	}
+
+	var diff []string
+	for code, sides := range seen {
+		if sides != 3 {
+			diff = append(diff, code)
+		}
+	}
+	return diff
+}
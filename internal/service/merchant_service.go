@@ -2,33 +2,98 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
 	log "briefcash-jwt/internal/helper/loghelper"
 	repo "briefcash-jwt/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	cacheWriteAttempts       = 3
+	defaultCacheRetryBackoff = 100 * time.Millisecond
+	defaultReconcileInterval = 5 * time.Second
+	reconcileTimeout         = 10 * time.Minute
+)
+
+var (
+	ErrMerchantNotFound      = errors.New("merchant not found")
+	ErrMerchantAlreadyActive = errors.New("merchant code already active")
+	ErrMerchantNotActive     = errors.New("merchant code is not active")
+	ErrMerchantCacheStale    = errors.New("merchant updated in db, redis sync pending")
 )
 
 type MerchantService interface {
-	CachingCode(ctx context.Context) error
-	ValidateMerchantCode(ctx context.Context, mCode string) (bool, error)
+	CachingCode(ctx context.Context) (*dto.MerchantSyncResponse, error)
+	ValidateMerchantCode(ctx context.Context, mCode string) (*MerchantStatus, error)
 	AddMerchantCode(ctx context.Context, mCode string) error
 	RemoveMerchantCode(ctx context.Context, mCode string) error
+	CreateMerchant(ctx context.Context, req dto.MerchantCreateRequest) (*dto.MerchantResponse, error)
+	UpdateMerchant(ctx context.Context, req dto.MerchantUpdateRequest) (*dto.MerchantResponse, error)
+	GetMerchant(ctx context.Context, mCode string) (*dto.MerchantResponse, error)
+	ListMerchants(ctx context.Context, req dto.MerchantListRequest) (*dto.MerchantListResponse, error)
+	StartCache(ctx context.Context)
+	CacheReady() bool
+	ActiveMerchants() int
+	StartReconciler(ctx context.Context, leases repo.LeaseRepository, interval time.Duration)
+	ReconcileDrift(ctx context.Context) (*dto.MerchantSyncResponse, error)
+	DriftStats() MerchantDriftStats
+	StartStatusListener(ctx context.Context, listener repo.MerchantStatusRepository, tokens TokenService)
+	SuspendMerchant(ctx context.Context, req dto.MerchantSuspendRequest) (*dto.MerchantResponse, error)
+	ReactivateSuspended(ctx context.Context) ([]string, error)
 }
 
 type merchantService struct {
-	dbRepo    repo.MerchantRepository
-	redisRepo repo.MerchantRedisRepository
+	dbRepo       repo.MerchantRepository
+	redisRepo    repo.MerchantRedisRepository
+	settingsRepo repo.MerchantSettingsRepository
+	db           *gorm.DB
+
+	retryBackoff      time.Duration
+	reconcileInterval time.Duration
+	reconciling       sync.Map
+
+	cache             *merchantCache
+	cacheSyncInterval time.Duration
+
+	leaseRepo  repo.LeaseRepository
+	leaseTTL   time.Duration
+	owner      string
+	driftMu    sync.Mutex
+	driftStats MerchantDriftStats
+
+	tokens        TokenService
+	listenBackoff time.Duration
 }
 
-func NewMerchantService(dbRepo repo.MerchantRepository, redisRepo repo.MerchantRedisRepository) MerchantService {
+func NewMerchantService(dbRepo repo.MerchantRepository, redisRepo repo.MerchantRedisRepository, settingsRepo repo.MerchantSettingsRepository, db *gorm.DB) MerchantService {
 	return &merchantService{
-		dbRepo:    dbRepo,
-		redisRepo: redisRepo,
+		dbRepo:       dbRepo,
+		redisRepo:    redisRepo,
+		settingsRepo: settingsRepo,
+		db:           db,
+
+		retryBackoff:      defaultCacheRetryBackoff,
+		reconcileInterval: defaultReconcileInterval,
+
+		cache:             newMerchantCache(),
+		cacheSyncInterval: defaultMerchantCacheSync,
+
+		leaseTTL: defaultMerchantReconcileInterval,
+		owner:    instanceID(),
+
+		listenBackoff: defaultListenBackoff,
 	}
 }
 
-func (s *merchantService) CachingCode(ctx context.Context) error {
+// Replace redis merchant set with active merchants in db, returns which codes were added and removed
+func (s *merchantService) CachingCode(ctx context.Context) (*dto.MerchantSyncResponse, error) {
 	start := time.Now()
 
 	defer func() {
@@ -39,67 +104,91 @@ func (s *merchantService) CachingCode(ctx context.Context) error {
 	listCodes, err := s.dbRepo.GetAllActiveCode(ctx)
 	if err != nil {
 		log.Logger.WithError(err).Error("Failed to retrieve list of merchant code from db")
-		return fmt.Errorf("failed to load merchant code: %w", err)
+		return nil, fmt.Errorf("failed to load merchant code: %w", err)
 	}
 
 	log.Logger.Infof("Total list of merchant codes: %d", len(listCodes))
 	if len(listCodes) == 0 {
-		log.Logger.Warn("No active merchant codes found in db")
-		return fmt.Errorf("no active merchant code found in db")
+		log.Logger.Warn("No active merchant codes found in db, every merchant code will be rejected")
 	}
 
 	log.Logger.Info("Load list of merchant codes to redis")
-	if err := s.redisRepo.SetActiveMerchantCode(ctx, listCodes); err != nil {
+	diff, err := s.redisRepo.SetActiveMerchantCode(ctx, listCodes)
+	if err != nil {
 		log.Logger.WithError(err).Error("Failed load merchant code list to redis")
-		return fmt.Errorf("failed to load merchant code: %w", err)
+		return nil, fmt.Errorf("failed to load merchant code: %w", err)
 	}
 
-	log.Logger.Info("List of merchant codes successfully loaded to redis")
+	log.Logger.WithFields(map[string]any{
+		"added":   diff.Added,
+		"removed": diff.Removed,
+	}).Info("List of merchant codes successfully loaded to redis")
 
-	return nil
+	return &dto.MerchantSyncResponse{
+		Total:   len(listCodes),
+		Added:   diff.Added,
+		Removed: diff.Removed,
+	}, nil
 }
 
-func (s *merchantService) ValidateMerchantCode(ctx context.Context, mCode string) (bool, error) {
+// Active state is answered from in-process cache, redis is only asked while cache is not loaded.
+// Merchant which is not active is reported as suspended when a suspension is recorded, otherwise unknown.
+func (s *merchantService) ValidateMerchantCode(ctx context.Context, mCode string) (*MerchantStatus, error) {
 	rec := log.Logger.WithField("merchant_code", mCode)
-	rec.Info("Checking active merchant code in redis")
-	isActive, err := s.redisRepo.IsMerchantCodeActive(ctx, mCode)
+
+	isActive, ok := s.cache.lookup(mCode)
+	if !ok {
+		rec.Info("Checking active merchant code in redis")
+		active, err := s.redisRepo.IsMerchantCodeActive(ctx, mCode)
+		if err != nil {
+			rec.WithError(err).Error("Failed validate merchant code in redis")
+			return nil, fmt.Errorf("failed to validate merchant code: %w", err)
+		}
+		isActive = active
+	}
+
+	if isActive {
+		return &MerchantStatus{Status: MerchantStatusActive}, nil
+	}
+
+	suspension, err := s.redisRepo.GetSuspension(ctx, mCode)
 	if err != nil {
-		rec.WithError(err).Error("Failed validate merchant code in redis")
-		return false, fmt.Errorf("failed to validate merchant code: %w", err)
+		rec.WithError(err).Error("Failed to check merchant suspension in redis")
+		return nil, fmt.Errorf("failed to validate merchant code: %w", err)
 	}
 
-	status := func() string {
-		if isActive {
-			return "active"
-		}
-		return "inactive"
-	}()
+	if suspension == nil {
+		rec.Info("Merchant code is not active")
+		return &MerchantStatus{Status: MerchantStatusUnknown}, nil
+	}
 
-	rec.Infof("Merchant code is %s in redis", status)
+	rec.WithField("reason", suspension.Reason).Info("Merchant code is suspended")
 
-	return isActive, nil
+	return &MerchantStatus{
+		Status:         MerchantStatusSuspended,
+		Reason:         suspension.Reason,
+		SuspendedUntil: suspension.Until,
+	}, nil
 }
 
 func (s *merchantService) AddMerchantCode(ctx context.Context, mCode string) error {
 	rec := log.Logger.WithField("merchant_code", mCode)
 
-	rec.Info("Check existing merchant code in redis")
-	exist, err := s.redisRepo.IsMerchantCodeActive(ctx, mCode)
-
-	if err != nil {
-		rec.WithError(err).Error("Failed to check merchant code in redis")
-		return fmt.Errorf("failed to check merchant code")
-	}
-
-	if exist {
-		rec.Warn("Merchant code already exists in redis")
-		return fmt.Errorf("merchant code already exists")
+	rec.Info("Activate merchant code in db")
+	if err := s.persistActive(ctx, mCode, true); err != nil {
+		if errors.Is(err, ErrMerchantNotFound) || errors.Is(err, ErrMerchantAlreadyActive) {
+			rec.WithError(err).Warn("Merchant code cannot be activated")
+			return err
+		}
+		rec.WithError(err).Error("Failed to activate merchant code in db")
+		return fmt.Errorf("failed to add merchant code: %w", err)
 	}
 
-	rec.Info("Add new merchant code to redis")
-	if err := s.redisRepo.AddMerchantCode(ctx, mCode); err != nil {
-		rec.WithError(err).Error("Failed to add merchant code to redis")
-		return fmt.Errorf("failed to add merchant code: %w", err)
+	rec.Info("Add merchant code to redis")
+	if err := s.writeCache(ctx, mCode, true); err != nil {
+		rec.WithError(err).Error("Failed to add merchant code to redis, reconciling from db")
+		s.reconcileLater(mCode)
+		return fmt.Errorf("%w: %v", ErrMerchantCacheStale, err)
 	}
 
 	rec.Info("merchant code successfully added to redis")
@@ -110,26 +199,152 @@ func (s *merchantService) AddMerchantCode(ctx context.Context, mCode string) err
 func (s *merchantService) RemoveMerchantCode(ctx context.Context, mCode string) error {
 	rec := log.Logger.WithField("merchant_code", mCode)
 
-	rec.Info("Check existing merchant code in redis")
-	exist, err := s.redisRepo.IsMerchantCodeActive(ctx, mCode)
+	rec.Info("Deactivate merchant code in db")
+	if err := s.persistActive(ctx, mCode, false); err != nil {
+		if errors.Is(err, ErrMerchantNotFound) || errors.Is(err, ErrMerchantNotActive) {
+			rec.WithError(err).Warn("Merchant code cannot be deactivated")
+			return err
+		}
+		rec.WithError(err).Error("Failed to deactivate merchant code in db")
+		return fmt.Errorf("failed to remove merchant code: %w", err)
+	}
 
-	if err != nil {
-		rec.WithError(err).Error("Failed to check merchant code in redis")
-		return fmt.Errorf("failed to check merchant code")
+	rec.Info("Remove merchant code from redis")
+	if err := s.writeCache(ctx, mCode, false); err != nil {
+		rec.WithError(err).Error("Failed to remove merchant code from redis, reconciling from db")
+		s.reconcileLater(mCode)
+		return fmt.Errorf("%w: %v", ErrMerchantCacheStale, err)
 	}
 
-	if !exist {
-		rec.Warn("Merchant code does not exist in redis")
-		return fmt.Errorf("merchant code does not exist")
+	rec.Info("Merchant code successfully removed from redis")
+
+	return nil
+}
+
+// Update merchant.is_active under row lock, db is the source of truth for active merchant codes.
+// When the row already has the requested state, redis is still refreshed so drift heals.
+func (s *merchantService) persistActive(ctx context.Context, mCode string, active bool) error {
+	update := func(r repo.MerchantRepository) error {
+		merchant, err := r.GetByCodeForUpdate(ctx, mCode)
+		if err != nil {
+			return err
+		}
+
+		if merchant == nil {
+			return ErrMerchantNotFound
+		}
+
+		if merchant.IsActive == active {
+			if active {
+				return ErrMerchantAlreadyActive
+			}
+			return ErrMerchantNotActive
+		}
+
+		return r.SetActive(ctx, mCode, active)
 	}
 
-	rec.Info("Remove existing merchant code from redis")
-	if err := s.redisRepo.RemoveMerchantCode(ctx, mCode); err != nil {
-		rec.WithError(err).Error("Failed to remove merchant code from redis")
-		return fmt.Errorf("failed to remove merchant code: %w", err)
+	err := s.inTransaction(func(merchants repo.MerchantRepository, _ repo.MerchantSettingsRepository) error {
+		return update(merchants)
+	})
+
+	if errors.Is(err, ErrMerchantAlreadyActive) || errors.Is(err, ErrMerchantNotActive) {
+		if cacheErr := s.writeCache(ctx, mCode, active); cacheErr != nil {
+			log.Logger.WithField("merchant_code", mCode).WithError(cacheErr).Warn("Failed to refresh merchant code in redis")
+		}
 	}
 
-	rec.Info("Merchant code successfully removed from redis")
+	return err
+}
 
+// Apply active state to redis, retrying transient failures with linear backoff
+func (s *merchantService) writeCache(ctx context.Context, mCode string, active bool) error {
+	err := s.retryCacheWrite(ctx, func() error {
+		if active {
+			return s.redisRepo.AddMerchantCode(ctx, mCode)
+		}
+		return s.redisRepo.RemoveMerchantCode(ctx, mCode)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Apply locally right away, other replicas follow through the published change
+	s.cache.set(mCode, active)
 	return nil
 }
+
+func (s *merchantService) retryCacheWrite(ctx context.Context, write func() error) error {
+	var err error
+
+	for attempt := 1; attempt <= cacheWriteAttempts; attempt++ {
+		if err = write(); err == nil {
+			return nil
+		}
+
+		if attempt == cacheWriteAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * s.retryBackoff):
+		}
+	}
+
+	return err
+}
+
+// Apply db state of merchant to redis, suspended merchant keeps its reason
+func (s *merchantService) restoreCache(ctx context.Context, mCode string, merchant *model.Merchant) (bool, error) {
+	if merchant != nil && !merchant.IsActive && merchant.SuspendedAt != nil {
+		return false, s.writeSuspension(ctx, mCode, merchant)
+	}
+
+	active := merchant != nil && merchant.IsActive
+	return active, s.writeCache(ctx, mCode, active)
+}
+
+// Keep re-applying db state of merchant code to redis in background until it succeeds,
+// state is re-read on every attempt so a later add or remove is never overwritten
+func (s *merchantService) reconcileLater(mCode string) {
+	if _, running := s.reconciling.LoadOrStore(mCode, struct{}{}); running {
+		return
+	}
+
+	go func() {
+		defer s.reconciling.Delete(mCode)
+
+		rec := log.Logger.WithField("merchant_code", mCode)
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+		defer cancel()
+
+		ticker := time.NewTicker(s.reconcileInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				rec.Error("Gave up reconciling merchant code, run merchant sync to restore redis")
+				return
+			case <-ticker.C:
+			}
+
+			merchant, err := s.dbRepo.GetByCode(ctx, mCode)
+			if err != nil {
+				rec.WithError(err).Warn("Failed to read merchant code from db while reconciling")
+				continue
+			}
+
+			active, err := s.restoreCache(ctx, mCode, merchant)
+			if err != nil {
+				rec.WithError(err).Warn("Failed to reconcile merchant code in redis")
+				continue
+			}
+
+			rec.WithField("active", active).Info("Merchant code reconciled from db to redis")
+			return
+		}
+	}()
+}
@@ -0,0 +1,1006 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	repo "briefcash-jwt/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+type MockMerchantRepository struct {
+	mu        sync.Mutex
+	Merchants map[string]*model.Merchant
+	Details   map[string]*model.MerchantDetails
+	Err       error
+}
+
+func (m *MockMerchantRepository) GetAllActiveCode(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var codes []string
+	for code, merchant := range m.Merchants {
+		if merchant.IsActive {
+			codes = append(codes, code)
+		}
+	}
+	return codes, m.Err
+}
+
+func (m *MockMerchantRepository) GetByCode(ctx context.Context, code string) (*model.Merchant, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if merchant, ok := m.Merchants[code]; ok {
+		copied := *merchant
+		return &copied, m.Err
+	}
+	return nil, m.Err
+}
+
+func (m *MockMerchantRepository) GetByCodeForUpdate(ctx context.Context, code string) (*model.Merchant, error) {
+	return m.GetByCode(ctx, code)
+}
+
+func (m *MockMerchantRepository) SetActive(ctx context.Context, code string, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	merchant, ok := m.Merchants[code]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	merchant.IsActive = active
+	if active {
+		merchant.SuspendedReason, merchant.SuspendedBy = nil, nil
+		merchant.SuspendedAt, merchant.SuspendedUntil = nil, nil
+	}
+	return nil
+}
+
+func (m *MockMerchantRepository) GetSuspensionEndedCodes(ctx context.Context, now time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var codes []string
+	for code, merchant := range m.Merchants {
+		if !merchant.IsActive && merchant.SuspendedUntil != nil && !merchant.SuspendedUntil.After(now) {
+			codes = append(codes, code)
+		}
+	}
+	return codes, m.Err
+}
+
+func (m *MockMerchantRepository) Create(ctx context.Context, merchant *model.Merchant) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Merchants == nil {
+		m.Merchants = make(map[string]*model.Merchant)
+	}
+	m.Merchants[merchant.Code] = merchant
+	return nil
+}
+
+func (m *MockMerchantRepository) Update(ctx context.Context, code string, fields map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	merchant, ok := m.Merchants[code]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for column, value := range fields {
+		switch column {
+		case "company_name":
+			merchant.CompanyName = value.(string)
+		case "email":
+			merchant.Email = value.(string)
+		case "phone":
+			merchant.Phone = value.(string)
+		case "website":
+			merchant.Website = value.(string)
+		case "is_active":
+			merchant.IsActive = value.(bool)
+		case "suspended_reason":
+			reason := value.(string)
+			merchant.SuspendedReason = &reason
+		case "suspended_by":
+			by := value.(string)
+			merchant.SuspendedBy = &by
+		case "suspended_at":
+			at := value.(time.Time)
+			merchant.SuspendedAt = &at
+		case "suspended_until":
+			merchant.SuspendedUntil = value.(*time.Time)
+		}
+	}
+	return m.Err
+}
+
+func (m *MockMerchantRepository) List(ctx context.Context, filter repo.MerchantFilter) ([]model.Merchant, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var merchants []model.Merchant
+	for _, merchant := range m.Merchants {
+		if filter.IsActive != nil && merchant.IsActive != *filter.IsActive {
+			continue
+		}
+		merchants = append(merchants, *merchant)
+	}
+	return merchants, int64(len(merchants)), m.Err
+}
+
+func (m *MockMerchantRepository) CreateDetails(ctx context.Context, details *model.MerchantDetails) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Details == nil {
+		m.Details = make(map[string]*model.MerchantDetails)
+	}
+	m.Details[details.MerchantCode] = details
+	return m.Err
+}
+
+func (m *MockMerchantRepository) UpdateDetails(ctx context.Context, code string, fields map[string]any) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if details, ok := m.Details[code]; ok {
+		if name, ok := fields["director_name"]; ok {
+			details.DirectorName = name.(string)
+		}
+	}
+	return m.Err
+}
+
+func (m *MockMerchantRepository) GetDetailsByCode(ctx context.Context, code string) (*model.MerchantDetails, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Details[code], m.Err
+}
+
+func (m *MockMerchantRepository) WithTransaction(tx *gorm.DB) repo.MerchantRepository {
+	return m
+}
+
+type MockMerchantRedisRepository struct {
+	mu          sync.Mutex
+	Codes       map[string]bool
+	Suspended   map[string]repo.MerchantSuspension
+	Revocations map[string]bool
+	FailWrites  int
+	Lookups     int
+	Changes     chan repo.MerchantChange
+}
+
+func (m *MockMerchantRedisRepository) SetActiveMerchantCode(ctx context.Context, mCodes []string) (*repo.MerchantCodeDiff, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	diff := &repo.MerchantCodeDiff{Added: []string{}, Removed: []string{}}
+	next := make(map[string]bool)
+	for _, code := range mCodes {
+		next[code] = true
+		if !m.Codes[code] {
+			diff.Added = append(diff.Added, code)
+		}
+	}
+	for code := range m.Codes {
+		if !next[code] {
+			diff.Removed = append(diff.Removed, code)
+		}
+	}
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+
+	m.Codes = next
+	return diff, nil
+}
+
+func (m *MockMerchantRedisRepository) IsMerchantCodeActive(ctx context.Context, mCode string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Lookups++
+	return m.Codes[mCode], nil
+}
+
+func (m *MockMerchantRedisRepository) AddMerchantCode(ctx context.Context, mCode string) error {
+	return m.write(mCode, true)
+}
+
+func (m *MockMerchantRedisRepository) RemoveMerchantCode(ctx context.Context, mCode string) error {
+	return m.write(mCode, false)
+}
+
+func (m *MockMerchantRedisRepository) GetAllMerchantCode(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var codes []string
+	for code := range m.Codes {
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (m *MockMerchantRedisRepository) SuspendMerchantCode(ctx context.Context, mCode string, suspension repo.MerchantSuspension) error {
+	if err := m.write(mCode, false); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Suspended == nil {
+		m.Suspended = make(map[string]repo.MerchantSuspension)
+	}
+	m.Suspended[mCode] = suspension
+	return nil
+}
+
+func (m *MockMerchantRedisRepository) GetSuspension(ctx context.Context, mCode string) (*repo.MerchantSuspension, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if suspension, ok := m.Suspended[mCode]; ok {
+		return &suspension, nil
+	}
+	return nil, nil
+}
+
+func (m *MockMerchantRedisRepository) Subscribe(ctx context.Context, subscribed func(), handler func(change repo.MerchantChange)) error {
+	subscribed()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change := <-m.Changes:
+			handler(change)
+		}
+	}
+}
+
+func (m *MockMerchantRedisRepository) QueueTokenRevocation(ctx context.Context, mCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Revocations == nil {
+		m.Revocations = make(map[string]bool)
+	}
+	m.Revocations[mCode] = true
+	return nil
+}
+
+func (m *MockMerchantRedisRepository) PopTokenRevocations(ctx context.Context, count int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var codes []string
+	for code := range m.Revocations {
+		if len(codes) == count {
+			break
+		}
+		codes = append(codes, code)
+		delete(m.Revocations, code)
+	}
+	return codes, nil
+}
+
+func (m *MockMerchantRedisRepository) lookups() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Lookups
+}
+
+func (m *MockMerchantRedisRepository) write(mCode string, active bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.FailWrites > 0 {
+		m.FailWrites--
+		return errors.New("redis unavailable")
+	}
+	if m.Codes == nil {
+		m.Codes = make(map[string]bool)
+	}
+	if active {
+		m.Codes[mCode] = true
+		delete(m.Suspended, mCode)
+	} else {
+		delete(m.Codes, mCode)
+	}
+	return nil
+}
+
+func (m *MockMerchantRedisRepository) has(mCode string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Codes[mCode]
+}
+
+func newTestMerchantService(db *MockMerchantRepository, cache *MockMerchantRedisRepository) *merchantService {
+	svc := NewMerchantService(db, cache, &MockMerchantSettingsRepository{}, nil).(*merchantService)
+	svc.retryBackoff = time.Millisecond
+	svc.reconcileInterval = 5 * time.Millisecond
+	return svc
+}
+
+func TestAddMerchantCode_PersistsToDB(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001"}}}
+	cache := &MockMerchantRedisRepository{}
+	svc := newTestMerchantService(db, cache)
+
+	if err := svc.AddMerchantCode(context.Background(), "M001"); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !db.Merchants["M001"].IsActive {
+		t.Fatal("Expected merchant to be active in db")
+	}
+	if !cache.has("M001") {
+		t.Fatal("Expected merchant code in redis")
+	}
+
+	// Resync from db must keep the merchant active
+	if _, err := svc.CachingCode(context.Background()); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+	if !cache.has("M001") {
+		t.Fatal("Expected merchant code to survive resync")
+	}
+}
+
+func TestAddMerchantCode_UnknownMerchant(t *testing.T) {
+	svc := newTestMerchantService(&MockMerchantRepository{}, &MockMerchantRedisRepository{})
+
+	err := svc.AddMerchantCode(context.Background(), "M404")
+	if !errors.Is(err, ErrMerchantNotFound) {
+		t.Fatalf("Expected ErrMerchantNotFound, got %v", err)
+	}
+}
+
+func TestAddMerchantCode_AlreadyActiveHealsRedis(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001", IsActive: true}}}
+	cache := &MockMerchantRedisRepository{}
+	svc := newTestMerchantService(db, cache)
+
+	err := svc.AddMerchantCode(context.Background(), "M001")
+	if !errors.Is(err, ErrMerchantAlreadyActive) {
+		t.Fatalf("Expected ErrMerchantAlreadyActive, got %v", err)
+	}
+	if !cache.has("M001") {
+		t.Fatal("Expected missing redis entry to be restored")
+	}
+}
+
+func TestRemoveMerchantCode_PersistsToDB(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001", IsActive: true},
+		"M002": {Code: "M002", IsActive: true},
+	}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true, "M002": true}}
+	svc := newTestMerchantService(db, cache)
+
+	if err := svc.RemoveMerchantCode(context.Background(), "M001"); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if db.Merchants["M001"].IsActive {
+		t.Fatal("Expected merchant to be inactive in db")
+	}
+
+	if _, err := svc.CachingCode(context.Background()); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+	if cache.has("M001") {
+		t.Fatal("Expected removed merchant code to stay removed after resync")
+	}
+}
+
+func TestRemoveMerchantCode_RetriesRedisWrite(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001", IsActive: true}}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}, FailWrites: cacheWriteAttempts - 1}
+	svc := newTestMerchantService(db, cache)
+
+	if err := svc.RemoveMerchantCode(context.Background(), "M001"); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+	if cache.has("M001") {
+		t.Fatal("Expected merchant code removed from redis")
+	}
+}
+
+func TestAddMerchantCode_ReconcilesAfterRedisFailure(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001"}}}
+	cache := &MockMerchantRedisRepository{FailWrites: cacheWriteAttempts + 1}
+	svc := newTestMerchantService(db, cache)
+
+	err := svc.AddMerchantCode(context.Background(), "M001")
+	if !errors.Is(err, ErrMerchantCacheStale) {
+		t.Fatalf("Expected ErrMerchantCacheStale, got %v", err)
+	}
+	if !db.Merchants["M001"].IsActive {
+		t.Fatal("Expected db change to be kept")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !cache.has("M001") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected merchant code reconciled to redis")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newCreateMerchantRequest() dto.MerchantCreateRequest {
+	return dto.MerchantCreateRequest{
+		Code:        "STARK-1225",
+		CompanyName: "Stark Industries",
+		Email:       "finance@stark.co.id",
+		Phone:       "+6281234567890",
+		Website:     "https://stark.co.id",
+		Details:     dto.MerchantDetailsRequest{DirectorName: "Tony", Country: "IDN"},
+		Settings:    dto.MerchantSettingsRequest{ChannelID: "95221", PartnerID: "STARK"},
+	}
+}
+
+func TestCreateMerchant_CreatesAllRows(t *testing.T) {
+	db := &MockMerchantRepository{}
+	settings := &MockMerchantSettingsRepository{}
+	svc := NewMerchantService(db, &MockMerchantRedisRepository{}, settings, nil)
+
+	resp, err := svc.CreateMerchant(context.Background(), newCreateMerchantRequest())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.IsActive || resp.DateJoined == "" || resp.Settings == nil || resp.Settings.TokenFormat != TokenFormatJWT {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if db.Merchants["STARK-1225"] == nil || db.Details["STARK-1225"] == nil || settings.Settings["STARK-1225"] == nil {
+		t.Fatal("Expected merchant, details and settings rows")
+	}
+
+	if _, err := svc.CreateMerchant(context.Background(), newCreateMerchantRequest()); !errors.Is(err, ErrMerchantExists) {
+		t.Fatalf("Expected ErrMerchantExists, got %v", err)
+	}
+}
+
+func TestCreateMerchant_RejectsInvalidContact(t *testing.T) {
+	cases := map[string]func(req *dto.MerchantCreateRequest){
+		"email":   func(req *dto.MerchantCreateRequest) { req.Email = "finance.stark.co.id" },
+		"phone":   func(req *dto.MerchantCreateRequest) { req.Phone = "0812-ABC" },
+		"website": func(req *dto.MerchantCreateRequest) { req.Website = "ftp://stark.co.id" },
+		"country": func(req *dto.MerchantCreateRequest) { req.Details.Country = "Indonesia" },
+		"format":  func(req *dto.MerchantCreateRequest) { req.Settings.TokenFormat = "saml" },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			db := &MockMerchantRepository{}
+			svc := NewMerchantService(db, &MockMerchantRedisRepository{}, &MockMerchantSettingsRepository{}, nil)
+
+			req := newCreateMerchantRequest()
+			mutate(&req)
+
+			if _, err := svc.CreateMerchant(context.Background(), req); !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("Expected ErrInvalidRequest, got %v", err)
+			}
+			if len(db.Merchants) != 0 {
+				t.Fatal("Expected no merchant created")
+			}
+		})
+	}
+}
+
+func TestUpdateMerchant_PartialUpdate(t *testing.T) {
+	db := &MockMerchantRepository{}
+	svc := NewMerchantService(db, &MockMerchantRedisRepository{}, &MockMerchantSettingsRepository{}, nil)
+
+	if _, err := svc.CreateMerchant(context.Background(), newCreateMerchantRequest()); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	email := "treasury@stark.co.id"
+	director := "Pepper"
+	resp, err := svc.UpdateMerchant(context.Background(), dto.MerchantUpdateRequest{
+		Code:    "STARK-1225",
+		Email:   &email,
+		Details: &dto.MerchantDetailsUpdateRequest{DirectorName: &director},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.Email != email || resp.Phone != "+6281234567890" || resp.Details.DirectorName != director {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+
+	invalid := "not-a-url"
+	if _, err := svc.UpdateMerchant(context.Background(), dto.MerchantUpdateRequest{Code: "STARK-1225", Website: &invalid}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("Expected ErrInvalidRequest, got %v", err)
+	}
+
+	if _, err := svc.UpdateMerchant(context.Background(), dto.MerchantUpdateRequest{Code: "WAYNE-0001", Email: &email}); !errors.Is(err, ErrMerchantNotFound) {
+		t.Fatalf("Expected ErrMerchantNotFound, got %v", err)
+	}
+}
+
+func TestListMerchants_Filters(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001", IsActive: true},
+		"M002": {Code: "M002"},
+	}}
+	svc := NewMerchantService(db, &MockMerchantRedisRepository{}, &MockMerchantSettingsRepository{}, nil)
+
+	resp, err := svc.ListMerchants(context.Background(), dto.MerchantListRequest{Status: MerchantStatusActive, Limit: 1000})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.Total != 1 || resp.Merchants[0].Code != "M001" || resp.Limit != defaultMerchantListLimit {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+
+	if _, err := svc.ListMerchants(context.Background(), dto.MerchantListRequest{Status: "closed"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("Expected ErrInvalidRequest, got %v", err)
+	}
+
+	if _, err := svc.ListMerchants(context.Background(), dto.MerchantListRequest{JoinedFrom: "2025-02-01", JoinedTo: "2025-01-01"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("Expected ErrInvalidRequest, got %v", err)
+	}
+}
+
+func TestValidateMerchantCode_ServedFromCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}, Changes: make(chan repo.MerchantChange)}
+	svc := newTestMerchantService(&MockMerchantRepository{}, cache)
+
+	// Before cache is loaded redis is asked
+	if status, err := svc.ValidateMerchantCode(ctx, "M001"); err != nil || status.Status != MerchantStatusActive {
+		t.Fatalf("Expected active merchant, got %+v %v", status, err)
+	}
+	if cache.lookups() != 1 {
+		t.Fatalf("Expected redis lookup before cache load, got %d", cache.lookups())
+	}
+
+	svc.StartCache(ctx)
+
+	for range 3 {
+		if status, _ := svc.ValidateMerchantCode(ctx, "M001"); status.Status != MerchantStatusActive {
+			t.Fatal("Expected active merchant from cache")
+		}
+	}
+	if status, _ := svc.ValidateMerchantCode(ctx, "M002"); status.Status == MerchantStatusActive {
+		t.Fatal("Expected unknown merchant inactive")
+	}
+	if cache.lookups() != 1 {
+		t.Fatalf("Expected no redis lookup once cached, got %d", cache.lookups())
+	}
+}
+
+func TestValidateMerchantCode_FollowsPublishedChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}, Changes: make(chan repo.MerchantChange)}
+	svc := newTestMerchantService(&MockMerchantRepository{}, cache)
+	svc.StartCache(ctx)
+
+	// Change made by another replica
+	cache.Changes <- repo.MerchantChange{Action: repo.MerchantChangeRemove, Code: "M001"}
+	cache.Changes <- repo.MerchantChange{Action: repo.MerchantChangeAdd, Code: "M002"}
+
+	// Handler runs before the next send is accepted, so one more message flushes the previous ones
+	cache.Changes <- repo.MerchantChange{Action: "noop"}
+
+	if status, _ := svc.ValidateMerchantCode(ctx, "M001"); status.Status == MerchantStatusActive {
+		t.Fatal("Expected removed merchant inactive")
+	}
+	if status, _ := svc.ValidateMerchantCode(ctx, "M002"); status.Status != MerchantStatusActive {
+		t.Fatal("Expected added merchant active")
+	}
+
+	cache.SetActiveMerchantCode(ctx, []string{"M003"})
+	cache.Changes <- repo.MerchantChange{Action: repo.MerchantChangeSync}
+	cache.Changes <- repo.MerchantChange{Action: "noop"}
+
+	if status, _ := svc.ValidateMerchantCode(ctx, "M002"); status.Status == MerchantStatusActive {
+		t.Fatal("Expected merchant dropped after sync")
+	}
+	if status, _ := svc.ValidateMerchantCode(ctx, "M003"); status.Status != MerchantStatusActive {
+		t.Fatal("Expected synced merchant active")
+	}
+	if cache.lookups() != 0 {
+		t.Fatalf("Expected no redis lookup, got %d", cache.lookups())
+	}
+}
+
+// Subscription never confirmed, e.g. pub/sub rejected while plain commands still work
+type unsubscribedRedisRepository struct {
+	*MockMerchantRedisRepository
+}
+
+func (m *unsubscribedRedisRepository) Subscribe(ctx context.Context, subscribed func(), handler func(change repo.MerchantChange)) error {
+	return errors.New("subscribe rejected")
+}
+
+func TestStartCache_NotReadyWithoutSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	svc := NewMerchantService(&MockMerchantRepository{}, &unsubscribedRedisRepository{cache}, &MockMerchantSettingsRepository{}, nil).(*merchantService)
+	svc.cacheSyncInterval = time.Millisecond
+	svc.StartCache(ctx)
+
+	// Periodic reload alone must not serve lookups, changes published meanwhile would be missed
+	time.Sleep(20 * time.Millisecond)
+	if svc.CacheReady() {
+		t.Fatal("Expected cache not ready while change channel is not subscribed")
+	}
+
+	if status, _ := svc.ValidateMerchantCode(ctx, "M001"); status.Status != MerchantStatusActive {
+		t.Fatal("Expected merchant validated against redis")
+	}
+	if cache.lookups() != 1 {
+		t.Fatalf("Expected redis lookup, got %d", cache.lookups())
+	}
+}
+
+func TestCachingCode_ReturnsDiff(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001", IsActive: true},
+		"M003": {Code: "M003", IsActive: true},
+	}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true, "M002": true}}
+	svc := newTestMerchantService(db, cache)
+
+	result, err := svc.CachingCode(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if result.Total != 2 || !slices.Equal(result.Added, []string{"M003"}) || !slices.Equal(result.Removed, []string{"M002"}) {
+		t.Fatalf("Unexpected sync result: %+v", result)
+	}
+}
+
+func TestReconcileDrift_RepairsRedis(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001", IsActive: true},
+		"M002": {Code: "M002"},
+	}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M002": true, "M003": true}}
+	svc := newTestMerchantService(db, cache)
+
+	result, err := svc.ReconcileDrift(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !slices.Equal(result.Added, []string{"M001"}) || !slices.Equal(result.Removed, []string{"M002", "M003"}) {
+		t.Fatalf("Unexpected reconcile result: %+v", result)
+	}
+	if !cache.has("M001") || cache.has("M002") || cache.has("M003") {
+		t.Fatalf("Expected redis to match db, got %v", cache.Codes)
+	}
+
+	if _, err := svc.ReconcileDrift(context.Background()); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	stats := svc.DriftStats()
+	if stats.Runs != 2 || stats.DriftRuns != 1 || stats.Added != 1 || stats.Removed != 2 || stats.LastAdded != 0 {
+		t.Fatalf("Unexpected drift stats: %+v", stats)
+	}
+}
+
+func TestReconcileOnce_SkipWhenLeaseHeld(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001", IsActive: true}}}
+	cache := &MockMerchantRedisRepository{}
+	svc := newTestMerchantService(db, cache)
+	svc.leaseRepo = &MockLeaseRepository{Owners: map[string]string{merchantReconcileLease: "other-replica"}}
+
+	svc.reconcileOnce(context.Background(), time.Minute)
+
+	if cache.has("M001") {
+		t.Fatal("Expected reconcile skipped while another replica holds the lease")
+	}
+	if stats := svc.DriftStats(); stats.Skipped != 1 || stats.Runs != 0 {
+		t.Fatalf("Unexpected drift stats: %+v", stats)
+	}
+}
+
+type MockMerchantStatusRepository struct {
+	Failures int
+	Changes  chan repo.MerchantStatusChange
+}
+
+func (m *MockMerchantStatusRepository) Listen(ctx context.Context, listening func(), handler func(change repo.MerchantStatusChange)) error {
+	if m.Failures > 0 {
+		m.Failures--
+		return errors.New("connection reset")
+	}
+	listening()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case change := <-m.Changes:
+			handler(change)
+		}
+	}
+}
+
+func TestStatusListener_DeactivationRevokesTokens(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001"}}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	jr := &MockJWTRepository{FindActiveResult: []model.JwtToken{
+		{ID: 1, AccessToken: "access-1", ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := newTestMerchantService(db, cache)
+	svc.tokens = NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+	svc.leaseRepo = &MockLeaseRepository{}
+
+	svc.applyStatusChange(context.Background(), repo.MerchantStatusChange{Code: "M001", IsActive: false})
+
+	if cache.has("M001") {
+		t.Fatal("Expected merchant code removed from redis")
+	}
+	if status, _ := svc.ValidateMerchantCode(context.Background(), "M001"); status.Status == MerchantStatusActive {
+		t.Fatal("Expected merchant code rejected after deactivation")
+	}
+	if rr.Store["blacklist:access-1"] != "true" {
+		t.Fatal("Expected tokens of deactivated merchant blacklisted")
+	}
+}
+
+func TestStatusListener_RevocationWaitsForLease(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001"}}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	jr := &MockJWTRepository{FindActiveResult: []model.JwtToken{
+		{ID: 1, AccessToken: "access-1", ExpiresAt: time.Now().Add(time.Hour)},
+	}}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	leases := &MockLeaseRepository{Owners: map[string]string{merchantReconcileLease: "other-replica"}}
+	svc := newTestMerchantService(db, cache)
+	svc.tokens = NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+	svc.leaseRepo = leases
+
+	// Cache is written on every replica, revocation is left to the lease holder
+	svc.applyStatusChange(context.Background(), repo.MerchantStatusChange{Code: "M001", IsActive: false})
+
+	if cache.has("M001") {
+		t.Fatal("Expected merchant code removed from redis")
+	}
+	if rr.Store["blacklist:access-1"] == "true" || !cache.Revocations["M001"] {
+		t.Fatal("Expected revocation queued while another replica holds the lease")
+	}
+
+	leases.Release(context.Background(), merchantReconcileLease, "other-replica")
+	svc.reconcileOnce(context.Background(), time.Minute)
+
+	if rr.Store["blacklist:access-1"] != "true" || len(cache.Revocations) != 0 {
+		t.Fatal("Expected queued revocation handled by next lease holder")
+	}
+}
+
+func TestStatusListener_ReconnectsAndCatchesUp(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001"},
+		"M002": {Code: "M002"},
+	}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	svc := newTestMerchantService(db, cache)
+	svc.listenBackoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc.leaseRepo = &MockLeaseRepository{}
+	listener := &MockMerchantStatusRepository{Failures: 2, Changes: make(chan repo.MerchantStatusChange)}
+	svc.StartStatusListener(ctx, listener, nil)
+
+	// Deactivation missed while disconnected is repaired from db
+	deadline := time.Now().Add(time.Second)
+	for cache.has("M001") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected missed deactivation repaired after reconnect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	listener.Changes <- repo.MerchantStatusChange{Code: "M002", IsActive: true}
+
+	for !cache.has("M002") {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected notified activation applied to redis")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSuspendMerchant_ReportsReason(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001", IsActive: true},
+		"M002": {Code: "M002"},
+	}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	svc := newTestMerchantService(db, cache)
+
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	resp, err := svc.SuspendMerchant(context.Background(), dto.MerchantSuspendRequest{
+		MerchantCode:   "M001",
+		Reason:         "Compliance review",
+		SuspendedUntil: until.Format(time.RFC3339),
+		SuspendedBy:    "ops",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if resp.IsActive || resp.Suspension == nil || resp.Suspension.Reason != "Compliance review" || resp.Suspension.SuspendedBy != "ops" {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+
+	status, err := svc.ValidateMerchantCode(context.Background(), "M001")
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+	if status.Status != MerchantStatusSuspended || status.Reason != "Compliance review" || !status.SuspendedUntil.Equal(until) {
+		t.Fatalf("Unexpected merchant status: %+v", status)
+	}
+
+	if status, _ := svc.ValidateMerchantCode(context.Background(), "M404"); status.Status != MerchantStatusUnknown {
+		t.Fatalf("Expected unknown merchant, got %+v", status)
+	}
+
+	// Merchant switched off for good can't be suspended
+	if _, err := svc.SuspendMerchant(context.Background(), dto.MerchantSuspendRequest{MerchantCode: "M002", Reason: "Unpaid fees"}); !errors.Is(err, ErrMerchantNotActive) {
+		t.Fatalf("Expected ErrMerchantNotActive, got %v", err)
+	}
+
+	if _, err := svc.SuspendMerchant(context.Background(), dto.MerchantSuspendRequest{MerchantCode: "M001"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("Expected ErrInvalidRequest, got %v", err)
+	}
+}
+
+func TestReactivateSuspended_AfterSuspensionEnds(t *testing.T) {
+	ended := time.Now().Add(-time.Minute)
+	later := time.Now().Add(time.Hour)
+	reason := "Unpaid fees"
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{
+		"M001": {Code: "M001", SuspendedReason: &reason, SuspendedAt: &ended, SuspendedUntil: &ended},
+		"M002": {Code: "M002", SuspendedReason: &reason, SuspendedAt: &ended, SuspendedUntil: &later},
+	}}
+	cache := &MockMerchantRedisRepository{Suspended: map[string]repo.MerchantSuspension{
+		"M001": {Reason: reason, Until: &ended},
+		"M002": {Reason: reason, Until: &later},
+	}}
+	svc := newTestMerchantService(db, cache)
+
+	reactivated, err := svc.ReactivateSuspended(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !slices.Equal(reactivated, []string{"M001"}) {
+		t.Fatalf("Expected only ended suspension reactivated, got %v", reactivated)
+	}
+	if !db.Merchants["M001"].IsActive || db.Merchants["M001"].SuspendedAt != nil {
+		t.Fatal("Expected merchant active with suspension cleared in db")
+	}
+	if status, _ := svc.ValidateMerchantCode(context.Background(), "M001"); status.Status != MerchantStatusActive {
+		t.Fatalf("Expected reactivated merchant active, got %+v", status)
+	}
+	if status, _ := svc.ValidateMerchantCode(context.Background(), "M002"); status.Status != MerchantStatusSuspended {
+		t.Fatalf("Expected merchant still suspended, got %+v", status)
+	}
+}
+
+func TestCachingCode_EmptyMerchantTable(t *testing.T) {
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	svc := newTestMerchantService(&MockMerchantRepository{}, cache)
+
+	result, err := svc.CachingCode(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if result.Total != 0 || !slices.Equal(result.Removed, []string{"M001"}) || cache.has("M001") {
+		t.Fatalf("Expected redis emptied, got %+v", result)
+	}
+}
+
+// Fails lookup of one token, every other token is found
+type failingTokenRepository struct {
+	*MockJWTRepository
+	failing string
+}
+
+func (m *failingTokenRepository) FindByAccessToken(ctx context.Context, token string) (*model.JwtToken, error) {
+	if token == m.failing {
+		return nil, errors.New("connection reset")
+	}
+	return &model.JwtToken{AccessToken: token, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func TestRevokeTokensByMerchant_ContinuesPastFailure(t *testing.T) {
+	jr := &failingTokenRepository{failing: "access-1", MockJWTRepository: &MockJWTRepository{FindActiveResult: []model.JwtToken{
+		{ID: 1, AccessToken: "access-1", ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: 2, AccessToken: "access-2", ExpiresAt: time.Now().Add(time.Hour)},
+	}}}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	tokens := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	revoked, err := tokens.RevokeTokensByMerchant(context.Background(), "M001")
+	if err == nil || revoked != 1 {
+		t.Fatalf("Expected one token revoked and failure reported, got %d and %v", revoked, err)
+	}
+	if rr.Store["blacklist:access-2"] != "true" {
+		t.Fatal("Expected token after failed one still blacklisted")
+	}
+
+	// Tokens already revoked by another replica are not a failure
+	revokedElsewhere := &MockJWTRepository{FindActiveResult: jr.FindActiveResult, FindByAccessTokenErr: repo.ErrAccessTokenNotFound}
+	other := NewTokenService(revokedElsewhere, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+	if _, err := other.RevokeTokensByMerchant(context.Background(), "M001"); err != nil {
+		t.Fatalf("Expected already revoked token to be skipped, got %v", err)
+	}
+}
+
+// Catch up must run on the new connection, after LISTEN and before notifications are handled
+type orderedStatusRepository struct {
+	MockMerchantStatusRepository
+	events []string
+}
+
+func (m *orderedStatusRepository) Listen(ctx context.Context, listening func(), handler func(change repo.MerchantStatusChange)) error {
+	m.events = append(m.events, "listen")
+	return m.MockMerchantStatusRepository.Listen(ctx, func() {
+		listening()
+		m.events = append(m.events, "listening")
+	}, handler)
+}
+
+func TestStatusListener_CatchesUpAfterListen(t *testing.T) {
+	db := &MockMerchantRepository{Merchants: map[string]*model.Merchant{"M001": {Code: "M001"}}}
+	cache := &MockMerchantRedisRepository{Codes: map[string]bool{"M001": true}}
+	svc := newTestMerchantService(db, cache)
+	svc.listenBackoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	svc.leaseRepo = &MockLeaseRepository{}
+	listener := &orderedStatusRepository{MockMerchantStatusRepository: MockMerchantStatusRepository{Failures: 1, Changes: make(chan repo.MerchantStatusChange)}}
+	svc.StartStatusListener(ctx, listener, nil)
+
+	// Change is only accepted once listener is in its loop, catch up already ran by then
+	listener.Changes <- repo.MerchantStatusChange{Code: "M002", IsActive: false}
+
+	if cache.has("M001") {
+		t.Fatal("Expected missed deactivation repaired before notifications are handled")
+	}
+	if len(listener.events) != 3 || listener.events[1] != "listen" || listener.events[2] != "listening" {
+		t.Fatalf("Unexpected listen order: %v", listener.events)
+	}
+}
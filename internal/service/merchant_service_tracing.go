@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Wraps every MerchantService call in a span. Background jobs are started untraced,
+// each run of them starts its own trace from the repository calls it makes.
+type tracedMerchantService struct {
+	next MerchantService
+}
+
+func TraceMerchantService(next MerchantService) MerchantService {
+	return &tracedMerchantService{next: next}
+}
+
+func (t *tracedMerchantService) CachingCode(ctx context.Context) (*dto.MerchantSyncResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.CachingCode")
+	result, err := t.next.CachingCode(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (t *tracedMerchantService) ValidateMerchantCode(ctx context.Context, mCode string) (*MerchantStatus, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.ValidateMerchantCode", attribute.String("merchant.code", mCode))
+	status, err := t.next.ValidateMerchantCode(ctx, mCode)
+	if status != nil {
+		span.SetAttributes(attribute.String("merchant.status", status.Status))
+	}
+	tracing.End(span, err)
+	return status, err
+}
+
+func (t *tracedMerchantService) AddMerchantCode(ctx context.Context, mCode string) error {
+	ctx, span := tracing.Start(ctx, "MerchantService.AddMerchantCode", attribute.String("merchant.code", mCode))
+	err := t.next.AddMerchantCode(ctx, mCode)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedMerchantService) RemoveMerchantCode(ctx context.Context, mCode string) error {
+	ctx, span := tracing.Start(ctx, "MerchantService.RemoveMerchantCode", attribute.String("merchant.code", mCode))
+	err := t.next.RemoveMerchantCode(ctx, mCode)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedMerchantService) CreateMerchant(ctx context.Context, req dto.MerchantCreateRequest) (*dto.MerchantResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.CreateMerchant", attribute.String("merchant.code", req.Code))
+	merchant, err := t.next.CreateMerchant(ctx, req)
+	tracing.End(span, err)
+	return merchant, err
+}
+
+func (t *tracedMerchantService) UpdateMerchant(ctx context.Context, req dto.MerchantUpdateRequest) (*dto.MerchantResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.UpdateMerchant", attribute.String("merchant.code", req.Code))
+	merchant, err := t.next.UpdateMerchant(ctx, req)
+	tracing.End(span, err)
+	return merchant, err
+}
+
+func (t *tracedMerchantService) GetMerchant(ctx context.Context, mCode string) (*dto.MerchantResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.GetMerchant", attribute.String("merchant.code", mCode))
+	merchant, err := t.next.GetMerchant(ctx, mCode)
+	tracing.End(span, err)
+	return merchant, err
+}
+
+func (t *tracedMerchantService) ListMerchants(ctx context.Context, req dto.MerchantListRequest) (*dto.MerchantListResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.ListMerchants")
+	merchants, err := t.next.ListMerchants(ctx, req)
+	tracing.End(span, err)
+	return merchants, err
+}
+
+func (t *tracedMerchantService) StartCache(ctx context.Context) {
+	t.next.StartCache(ctx)
+}
+
+func (t *tracedMerchantService) CacheReady() bool {
+	return t.next.CacheReady()
+}
+
+func (t *tracedMerchantService) ActiveMerchants() int {
+	return t.next.ActiveMerchants()
+}
+
+func (t *tracedMerchantService) StartReconciler(ctx context.Context, leases repo.LeaseRepository, interval time.Duration) {
+	t.next.StartReconciler(ctx, leases, interval)
+}
+
+func (t *tracedMerchantService) ReconcileDrift(ctx context.Context) (*dto.MerchantSyncResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.ReconcileDrift")
+	result, err := t.next.ReconcileDrift(ctx)
+	if result != nil {
+		span.SetAttributes(
+			attribute.Int("merchant.added", len(result.Added)),
+			attribute.Int("merchant.removed", len(result.Removed)),
+		)
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func (t *tracedMerchantService) DriftStats() MerchantDriftStats {
+	return t.next.DriftStats()
+}
+
+func (t *tracedMerchantService) StartStatusListener(ctx context.Context, listener repo.MerchantStatusRepository, tokens TokenService) {
+	t.next.StartStatusListener(ctx, listener, tokens)
+}
+
+func (t *tracedMerchantService) SuspendMerchant(ctx context.Context, req dto.MerchantSuspendRequest) (*dto.MerchantResponse, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.SuspendMerchant", attribute.String("merchant.code", req.MerchantCode))
+	merchant, err := t.next.SuspendMerchant(ctx, req)
+	tracing.End(span, err)
+	return merchant, err
+}
+
+func (t *tracedMerchantService) ReactivateSuspended(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "MerchantService.ReactivateSuspended")
+	codes, err := t.next.ReactivateSuspended(ctx)
+	span.SetAttributes(attribute.Int("merchant.reactivated", len(codes)))
+	tracing.End(span, err)
+	return codes, err
+}
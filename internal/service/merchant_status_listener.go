@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	log "briefcash-jwt/internal/helper/loghelper"
+	repo "briefcash-jwt/internal/repository"
+)
+
+const (
+	defaultListenBackoff = time.Second
+	maxListenBackoff     = 30 * time.Second
+	revokeQueueBatch     = 100
+)
+
+// Follow merchant status notifications from postgres, applying them to redis and revoking tokens of deactivated merchants
+// under the reconciler lease.
+// Listen connection is re-established with exponential backoff, notifications missed meanwhile are repaired from db
+// once the new LISTEN is in place, so nothing sent during the repair is lost.
+func (s *merchantService) StartStatusListener(ctx context.Context, listener repo.MerchantStatusRepository, tokens TokenService) {
+	s.tokens = tokens
+	rec := log.Logger.WithField("component", "merchant_status_listener")
+	rec.Info("Merchant status listener started")
+
+	go func() {
+		backoff := s.listenBackoff
+		reconnecting := false
+
+		for {
+			connectedAt := time.Now()
+			err := listener.Listen(ctx, func() {
+				if reconnecting {
+					s.catchUpStatus(ctx)
+				}
+			}, func(change repo.MerchantStatusChange) {
+				s.applyStatusChange(ctx, change)
+			})
+
+			if ctx.Err() != nil {
+				rec.Info("Merchant status listener stopped")
+				return
+			}
+
+			// Connection that stayed up for a while starts over from the shortest wait
+			if time.Since(connectedAt) > maxListenBackoff {
+				backoff = s.listenBackoff
+			}
+
+			rec.WithError(err).Warnf("Merchant status listener interrupted, reconnecting in %s", backoff)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = min(backoff*2, maxListenBackoff)
+			reconnecting = true
+		}
+	}()
+}
+
+func (s *merchantService) applyStatusChange(ctx context.Context, change repo.MerchantStatusChange) {
+	rec := log.Logger.WithFields(map[string]any{
+		"merchant_code": change.Code,
+		"active":        change.IsActive,
+	})
+	rec.Info("Merchant status changed in db")
+
+	if err := s.writeCache(ctx, change.Code, change.IsActive); err != nil {
+		rec.WithError(err).Error("Failed to apply merchant status to redis, reconciling from db")
+		s.reconcileLater(change.Code)
+	}
+
+	if change.IsActive {
+		return
+	}
+
+	// Every replica receives the notification, tokens are revoked once by the reconciler lease holder
+	s.queueTokenRevocation(ctx, change.Code)
+	s.withReconcileLease(ctx, s.revokeQueuedTokens)
+}
+
+// Notifications sent while listener was disconnected are lost, diff db against redis instead.
+// Replica holding the reconciler lease already repairs drift, so catch up is skipped when the lease is taken.
+func (s *merchantService) catchUpStatus(ctx context.Context) {
+	s.withReconcileLease(ctx, func(ctx context.Context) {
+		s.reconcileAndRevoke(ctx)
+	})
+}
+
+// Repair drift, then revoke tokens of merchants found deactivated in db but still active in redis
+func (s *merchantService) reconcileAndRevoke(ctx context.Context) {
+	result, err := s.ReconcileDrift(ctx)
+	if err != nil {
+		log.Logger.WithError(err).Warn("Failed to reconcile merchant status")
+	}
+
+	if result != nil {
+		for _, code := range result.Removed {
+			s.queueTokenRevocation(ctx, code)
+		}
+	}
+
+	s.revokeQueuedTokens(ctx)
+}
+
+// Run fn only when this replica gets the reconciler lease
+func (s *merchantService) withReconcileLease(ctx context.Context, fn func(ctx context.Context)) {
+	rec := log.Logger.WithFields(map[string]any{
+		"job":   merchantReconcileLease,
+		"owner": s.owner,
+	})
+
+	if s.leaseRepo == nil {
+		rec.Warn("Merchant reconcile lease is not configured, skipping")
+		return
+	}
+
+	acquired, err := s.leaseRepo.Acquire(ctx, merchantReconcileLease, s.owner, s.leaseTTL)
+	if err != nil {
+		rec.WithError(err).Error("Failed to acquire merchant reconcile lease")
+		return
+	}
+
+	// Queued revocations stay in redis, current holder or next reconcile run takes them
+	if !acquired {
+		rec.Debug("Merchant reconcile lease held by another instance, skipping")
+		return
+	}
+
+	defer func() {
+		if err := s.leaseRepo.Release(context.Background(), merchantReconcileLease, s.owner); err != nil {
+			rec.WithError(err).Warn("Failed to release merchant reconcile lease")
+		}
+	}()
+
+	fn(ctx)
+}
+
+func (s *merchantService) queueTokenRevocation(ctx context.Context, mCode string) {
+	if s.tokens == nil {
+		return
+	}
+
+	if err := s.redisRepo.QueueTokenRevocation(ctx, mCode); err != nil {
+		log.Logger.WithError(err).WithField("merchant_code", mCode).Error("Failed to queue token revocation of deactivated merchant")
+	}
+}
+
+// Revoke tokens of queued merchants, codes that fail are queued again for the next lease holder
+func (s *merchantService) revokeQueuedTokens(ctx context.Context) {
+	if s.tokens == nil {
+		return
+	}
+
+	for {
+		codes, err := s.redisRepo.PopTokenRevocations(ctx, revokeQueueBatch)
+		if err != nil {
+			log.Logger.WithError(err).Error("Failed to read queued merchant token revocations")
+			return
+		}
+
+		var failed []string
+		for _, code := range codes {
+			if _, err := s.tokens.RevokeTokensByMerchant(ctx, code); err != nil {
+				log.Logger.WithError(err).WithField("merchant_code", code).Error("Failed to revoke tokens of deactivated merchant")
+				failed = append(failed, code)
+			}
+		}
+
+		for _, code := range failed {
+			s.queueTokenRevocation(ctx, code)
+		}
+
+		if len(codes) < revokeQueueBatch || len(failed) > 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	log "briefcash-jwt/internal/helper/loghelper"
+	repo "briefcash-jwt/internal/repository"
+)
+
+// Result of merchant code validation, reason and until are only set for suspended merchant
+type MerchantStatus struct {
+	Status         string
+	Reason         string
+	SuspendedUntil *time.Time
+}
+
+// Suspend merchant with a reason, merchant stays inactive until suspended_until passes or it's reactivated through AddMerchantCode.
+// Suspending an already suspended merchant replaces reason and end of the suspension.
+func (s *merchantService) SuspendMerchant(ctx context.Context, req dto.MerchantSuspendRequest) (*dto.MerchantResponse, error) {
+	rec := log.Logger.WithFields(map[string]any{
+		"merchant_code": req.MerchantCode,
+		"suspended_by":  req.SuspendedBy,
+	})
+
+	if req.MerchantCode == "" {
+		return nil, fmt.Errorf("%w: merchant_code is required", ErrInvalidRequest)
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len(reason) > 200 {
+		return nil, fmt.Errorf("%w: reason is required and must be at most 200 characters", ErrInvalidRequest)
+	}
+
+	now := time.Now()
+	var until *time.Time
+	if req.SuspendedUntil != "" {
+		parsed, err := time.Parse(time.RFC3339, req.SuspendedUntil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: suspended_until must use RFC 3339 format", ErrInvalidRequest)
+		}
+		if !parsed.After(now) {
+			return nil, fmt.Errorf("%w: suspended_until must be in the future", ErrInvalidRequest)
+		}
+		until = &parsed
+	}
+
+	var merchant *model.Merchant
+	err := s.inTransaction(func(merchants repo.MerchantRepository, _ repo.MerchantSettingsRepository) error {
+		current, err := merchants.GetByCodeForUpdate(ctx, req.MerchantCode)
+		if err != nil {
+			return err
+		}
+
+		if current == nil {
+			return ErrMerchantNotFound
+		}
+
+		// Merchant switched off for good is not suspended, it has to be activated first
+		if !current.IsActive && current.SuspendedAt == nil {
+			return ErrMerchantNotActive
+		}
+
+		if err := merchants.Update(ctx, req.MerchantCode, map[string]any{
+			"is_active":        false,
+			"suspended_reason": reason,
+			"suspended_by":     req.SuspendedBy,
+			"suspended_at":     now,
+			"suspended_until":  until,
+		}); err != nil {
+			return err
+		}
+
+		current.IsActive = false
+		current.SuspendedReason = &reason
+		current.SuspendedBy = &req.SuspendedBy
+		current.SuspendedAt = &now
+		current.SuspendedUntil = until
+		merchant = current
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrMerchantNotFound) || errors.Is(err, ErrMerchantNotActive) {
+			rec.WithError(err).Warn("Merchant cannot be suspended")
+			return nil, err
+		}
+		rec.WithError(err).Error("Failed to suspend merchant in db")
+		return nil, fmt.Errorf("failed to suspend merchant: %w", err)
+	}
+
+	if err := s.writeSuspension(ctx, req.MerchantCode, merchant); err != nil {
+		rec.WithError(err).Error("Failed to suspend merchant code in redis, reconciling from db")
+		s.reconcileLater(req.MerchantCode)
+		return nil, fmt.Errorf("%w: %v", ErrMerchantCacheStale, err)
+	}
+
+	rec.WithField("reason", reason).Info("Merchant suspended")
+
+	return merchantResponse(merchant, nil, nil), nil
+}
+
+// Reactivate merchants whose suspension ended, returns reactivated codes.
+// Each merchant is re-checked under row lock, so a suspension extended meanwhile is kept.
+func (s *merchantService) ReactivateSuspended(ctx context.Context) ([]string, error) {
+	now := time.Now()
+
+	codes, err := s.dbRepo.GetSuspensionEndedCodes(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ended suspensions: %w", err)
+	}
+
+	reactivated := make([]string, 0, len(codes))
+	for _, code := range codes {
+		rec := log.Logger.WithField("merchant_code", code)
+
+		due := false
+		err := s.inTransaction(func(merchants repo.MerchantRepository, _ repo.MerchantSettingsRepository) error {
+			merchant, err := merchants.GetByCodeForUpdate(ctx, code)
+			if err != nil || merchant == nil {
+				return err
+			}
+
+			if merchant.IsActive || merchant.SuspendedUntil == nil || merchant.SuspendedUntil.After(now) {
+				return nil
+			}
+
+			due = true
+			return merchants.SetActive(ctx, code, true)
+		})
+		if err != nil {
+			rec.WithError(err).Error("Failed to reactivate merchant after suspension")
+			continue
+		}
+
+		if !due {
+			continue
+		}
+
+		if err := s.writeCache(ctx, code, true); err != nil {
+			rec.WithError(err).Error("Failed to add reactivated merchant code to redis, reconciling from db")
+			s.reconcileLater(code)
+		}
+
+		rec.Info("Merchant reactivated after suspension ended")
+		reactivated = append(reactivated, code)
+	}
+
+	return reactivated, nil
+}
+
+func (s *merchantService) writeSuspension(ctx context.Context, mCode string, merchant *model.Merchant) error {
+	suspension := repo.MerchantSuspension{
+		Reason: valueOf(merchant.SuspendedReason),
+		Until:  merchant.SuspendedUntil,
+	}
+
+	err := s.retryCacheWrite(ctx, func() error {
+		return s.redisRepo.SuspendMerchantCode(ctx, mCode, suspension)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.set(mCode, false)
+	return nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	model "briefcash-jwt/internal/entity"
+	logs "briefcash-jwt/internal/helper/loghelper"
+	metrics "briefcash-jwt/internal/helper/metricshelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// Opaque handle never contains dot, so it can't be mistaken for a JWT
+	opaqueTokenPrefix = "ot_"
+	opaqueKeyPrefix   = "opaque:"
+)
+
+// Get token format configured on merchant settings, default to deployment format when merchant has no settings
+func (ts *tokenService) resolveTokenFormat(ctx context.Context, mCode string) (string, error) {
+	if ts.settingsRepo == nil {
+		return ts.defaultFormat, nil
+	}
+
+	settings, err := ts.settingsRepo.GetByMerchantCode(ctx, mCode)
+	if err != nil {
+		return "", fmt.Errorf("failed to get merchant settings: %w", err)
+	}
+
+	if settings == nil || settings.TokenFormat == "" {
+		return ts.defaultFormat, nil
+	}
+
+	return settings.TokenFormat, nil
+}
+
+// Generate random access and refresh handle, access claims are kept server side
+func issueOpaqueTokens(claims jwt.MapClaims) (string, string, *string, error) {
+	accessHandle, err := mask.RandomID(32)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	refreshHandle, err := mask.RandomID(32)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to encode opaque token claims: %w", err)
+	}
+
+	serverClaims := string(encoded)
+	return opaqueTokenPrefix + accessHandle, opaqueTokenPrefix + refreshHandle, &serverClaims, nil
+}
+
+// Cache access token state in redis, opaque token also keep its claims
+func (ts *tokenService) cacheToken(ctx context.Context, token *model.JwtToken) error {
+	ttl := time.Until(token.ExpiresAt)
+	if token.TokenFormat == TokenFormatOpaque && token.Claims != nil {
+		return ts.redisRepo.SetToken(ctx, opaqueKey(token.AccessToken), *token.Claims, ttl)
+	}
+	return ts.redisRepo.SetToken(ctx, token.AccessToken, "valid", ttl)
+}
+
+// Resolve opaque handle back to its claims from redis, fallback to database
+func (ts *tokenService) validateOpaqueToken(ctx context.Context, handle string) (*jwt.Token, error) {
+	log := logs.Logger.WithField("token", mask.MaskToken(handle))
+
+	log.Info("Resolve opaque token claims in redis")
+	rawClaims, err := ts.redisRepo.GetToken(ctx, opaqueKey(handle))
+
+	if errors.Is(err, repo.ErrTokenNotFound) {
+		metrics.RecordCacheLookup(metrics.CacheMiss)
+		log.Warn("Opaque token not found in redis, checking in database")
+
+		tokenData, dbErr := ts.jwtRepo.FindByAccessToken(ctx, handle)
+		if dbErr != nil || tokenData == nil || tokenData.IsRevoke || tokenData.Claims == nil {
+			log.WithError(dbErr).Error("Opaque token not found in database")
+			return nil, fmt.Errorf("token invalid or blacklisted")
+		}
+
+		go func() {
+			if err := ts.cacheToken(context.Background(), tokenData); err != nil {
+				log.WithError(err).Warn("Failed to cache opaque token into redis")
+			}
+		}()
+
+		rawClaims = *tokenData.Claims
+	} else if err != nil {
+		metrics.RecordCacheLookup(metrics.CacheError)
+		log.WithError(err).Error("Redis error while resolving opaque token")
+		return nil, fmt.Errorf("temporary cache issue, please retry")
+	} else {
+		metrics.RecordCacheLookup(metrics.CacheHit)
+	}
+
+	claims := jwt.MapClaims{}
+	if err := json.Unmarshal([]byte(rawClaims), &claims); err != nil {
+		log.WithError(err).Error("Failed to decode opaque token claims")
+		return nil, fmt.Errorf("token invalid or blacklisted")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || time.Now().After(exp.Time) {
+		log.Warn("Token has expired")
+		return nil, fmt.Errorf("token expired")
+	}
+
+	log.Info("Token is valid")
+
+	return &jwt.Token{Raw: handle, Claims: claims, Valid: true}, nil
+}
+
+func isOpaqueToken(token string) bool {
+	return strings.HasPrefix(token, opaqueTokenPrefix) && !strings.Contains(token, ".")
+}
+
+func opaqueKey(handle string) string {
+	return opaqueKeyPrefix + handle
+}
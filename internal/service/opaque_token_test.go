@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	repo "briefcash-jwt/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+type MockMerchantSettingsRepository struct {
+	Settings map[string]*model.MerchantSettings
+	Err      error
+}
+
+func (m *MockMerchantSettingsRepository) GetByMerchantCode(ctx context.Context, mCode string) (*model.MerchantSettings, error) {
+	return m.Settings[mCode], m.Err
+}
+
+func (m *MockMerchantSettingsRepository) Create(ctx context.Context, settings *model.MerchantSettings) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Settings == nil {
+		m.Settings = make(map[string]*model.MerchantSettings)
+	}
+	m.Settings[settings.MerchantCode] = settings
+	return nil
+}
+
+func (m *MockMerchantSettingsRepository) WithTransaction(tx *gorm.DB) repo.MerchantSettingsRepository {
+	return m
+}
+
+func TestGenerateToken_OpaqueRoundTrip(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sr := &MockMerchantSettingsRepository{Settings: map[string]*model.MerchantSettings{
+		"STARK-1225": {MerchantCode: "STARK-1225", TokenFormat: TokenFormatOpaque},
+	}}
+	svc := NewTokenService(jr, rr, sr, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	resp, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !strings.HasPrefix(resp.AccessToken, opaqueTokenPrefix) || strings.Contains(resp.AccessToken, ".") {
+		t.Fatalf("Expected opaque handle, got %s", resp.AccessToken)
+	}
+
+	token, err := svc.ValidateToken(context.Background(), resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if claims := token.Claims.(jwt.MapClaims); claims["user_id"] != "STARK-1225" {
+		t.Fatalf("Unexpected claims: %v", claims)
+	}
+}
+
+func TestValidateToken_OpaqueUnknownHandle(t *testing.T) {
+	jr := &MockJWTRepository{FindByAccessTokenErr: context.Canceled}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	if _, err := svc.ValidateToken(context.Background(), opaqueTokenPrefix+"deadbeef"); err == nil {
+		t.Fatal("Expected error but got nil")
+	}
+}
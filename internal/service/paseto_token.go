@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	logs "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	pasetoPublicPrefix = "v4.public."
+	pasetoLocalPrefix  = "v4.local."
+)
+
+// PASETO registered time claims are RFC 3339 strings, JWT use unix seconds
+var pasetoTimeClaims = []string{"exp", "iat", "nbf"}
+
+// PASETO v4.public, signed with Ed25519
+type pasetoPublicFormat struct {
+	secretKey paseto.V4AsymmetricSecretKey
+	publicKey paseto.V4AsymmetricPublicKey
+}
+
+func NewPasetoPublicFormat(secretKey paseto.V4AsymmetricSecretKey) TokenFormat {
+	return &pasetoPublicFormat{secretKey: secretKey, publicKey: secretKey.Public()}
+}
+
+func (f *pasetoPublicFormat) Name() string {
+	return TokenFormatPasetoPublic
+}
+
+func (f *pasetoPublicFormat) Detect(token string) bool {
+	return strings.HasPrefix(token, pasetoPublicPrefix)
+}
+
+func (f *pasetoPublicFormat) Sign(claims jwt.MapClaims) (string, error) {
+	token, err := pasetoToken(claims)
+	if err != nil {
+		return "", err
+	}
+	return token.V4Sign(f.secretKey, nil), nil
+}
+
+func (f *pasetoPublicFormat) Verify(stringToken string) (*jwt.Token, error) {
+	parser := paseto.NewParser()
+	token, err := parser.ParseV4Public(f.publicKey, stringToken, nil)
+	return pasetoResult(stringToken, token, err)
+}
+
+// PASETO v4.local, encrypted with XChaCha20 and authenticated with BLAKE2b
+type pasetoLocalFormat struct {
+	key paseto.V4SymmetricKey
+}
+
+func NewPasetoLocalFormat(key paseto.V4SymmetricKey) TokenFormat {
+	return &pasetoLocalFormat{key: key}
+}
+
+func (f *pasetoLocalFormat) Name() string {
+	return TokenFormatPasetoLocal
+}
+
+func (f *pasetoLocalFormat) Detect(token string) bool {
+	return strings.HasPrefix(token, pasetoLocalPrefix)
+}
+
+func (f *pasetoLocalFormat) Sign(claims jwt.MapClaims) (string, error) {
+	token, err := pasetoToken(claims)
+	if err != nil {
+		return "", err
+	}
+	return token.V4Encrypt(f.key, nil), nil
+}
+
+func (f *pasetoLocalFormat) Verify(stringToken string) (*jwt.Token, error) {
+	parser := paseto.NewParser()
+	token, err := parser.ParseV4Local(f.key, stringToken, nil)
+	return pasetoResult(stringToken, token, err)
+}
+
+// Convert jwt claims into paseto token, time claims are converted to RFC 3339
+func pasetoToken(claims jwt.MapClaims) (*paseto.Token, error) {
+	token := paseto.NewToken()
+	for key, value := range claims {
+		if unix, ok := value.(int64); ok && isPasetoTimeClaim(key) {
+			token.SetTime(key, time.Unix(unix, 0))
+			continue
+		}
+
+		if err := token.Set(key, value); err != nil {
+			return nil, fmt.Errorf("failed to set paseto claim: %w", err)
+		}
+	}
+	return &token, nil
+}
+
+// Convert verified paseto token back into jwt claims, so callers read the same shape for every format
+func pasetoResult(stringToken string, token *paseto.Token, err error) (*jwt.Token, error) {
+	log := logs.Logger.WithField("token", mask.MaskToken(stringToken))
+
+	if err != nil {
+		// Parser only carries the expiry rule, so any rule failure means expired token
+		if errors.Is(err, paseto.RuleError{}) {
+			log.Warn("Token has expired")
+			return nil, fmt.Errorf("token expired")
+		}
+
+		log.WithError(err).Error("Unexpected error while parsing token")
+		return nil, err
+	}
+
+	claims := jwt.MapClaims(token.Claims())
+	for _, key := range pasetoTimeClaims {
+		if value, err := token.GetTime(key); err == nil {
+			claims[key] = float64(value.Unix())
+		}
+	}
+
+	return &jwt.Token{Raw: stringToken, Claims: claims, Valid: true}, nil
+}
+
+func isPasetoTimeClaim(key string) bool {
+	for _, claim := range pasetoTimeClaims {
+		if claim == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isPasetoToken(token string) bool {
+	return strings.HasPrefix(token, pasetoPublicPrefix) || strings.HasPrefix(token, pasetoLocalPrefix)
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestPasetoFormat_SignVerify(t *testing.T) {
+	formats := []TokenFormat{
+		NewPasetoPublicFormat(paseto.NewV4AsymmetricSecretKey()),
+		NewPasetoLocalFormat(paseto.NewV4SymmetricKey()),
+	}
+
+	for _, format := range formats {
+		signed, err := format.Sign(jwt.MapClaims{
+			"user_id": "STARK-1225",
+			"exp":     time.Now().Add(15 * time.Minute).Unix(),
+		})
+		if err != nil {
+			t.Fatalf("%s: expected no error: %v", format.Name(), err)
+		}
+
+		if !format.Detect(signed) || !strings.HasPrefix(signed, "v4.") {
+			t.Fatalf("%s: unexpected token %s", format.Name(), signed)
+		}
+
+		token, err := format.Verify(signed)
+		if err != nil {
+			t.Fatalf("%s: expected no error: %v", format.Name(), err)
+		}
+
+		exp, err := token.Claims.GetExpirationTime()
+		if err != nil || exp == nil {
+			t.Fatalf("%s: expected unix exp claim, got %v", format.Name(), token.Claims)
+		}
+	}
+}
+
+func TestPasetoFormat_Expired(t *testing.T) {
+	format := NewPasetoPublicFormat(paseto.NewV4AsymmetricSecretKey())
+	signed, _ := format.Sign(jwt.MapClaims{
+		"user_id": "STARK-1225",
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+	})
+
+	if _, err := format.Verify(signed); err == nil || err.Error() != "token expired" {
+		t.Fatalf("Expected token expired error, got %v", err)
+	}
+}
+
+func TestGenerateToken_PasetoPerMerchant(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sr := &MockMerchantSettingsRepository{Settings: map[string]*model.MerchantSettings{
+		"STARK-1225": {MerchantCode: "STARK-1225", TokenFormat: TokenFormatPasetoLocal},
+	}}
+	svc := NewTokenService(jr, rr, sr, nil, nil, TokenConfig{
+		Secret:      "imamfahruzi",
+		PasetoLocal: NewPasetoLocalFormat(paseto.NewV4SymmetricKey()),
+	})
+
+	resp, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !strings.HasPrefix(resp.AccessToken, pasetoLocalPrefix) {
+		t.Fatalf("Expected paseto local token, got %s", resp.AccessToken)
+	}
+
+	if _, err := svc.ValidateToken(context.Background(), resp.AccessToken); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	// Other merchants keep the default jwt format
+	other, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "WAYNE-0042", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if strings.Count(other.AccessToken, ".") != 2 || isPasetoToken(other.AccessToken) {
+		t.Fatalf("Expected jwt token, got %s", other.AccessToken)
+	}
+}
+
+func TestGenerateToken_FormatNotEnabled(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{
+		Secret:        "imamfahruzi",
+		DefaultFormat: TokenFormatPasetoPublic,
+	})
+
+	if _, err := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"}); err == nil {
+		t.Fatal("Expected error but got nil")
+	}
+}
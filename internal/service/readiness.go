@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	log "briefcash-jwt/internal/helper/loghelper"
+)
+
+const (
+	defaultWarmUpBackoff = time.Second
+	maxWarmUpBackoff     = 30 * time.Second
+	healthCheckTimeout   = 2 * time.Second
+
+	HealthStatusUp       = "up"
+	HealthStatusDown     = "down"
+	HealthStatusReady    = "ready"
+	HealthStatusNotReady = "not_ready"
+	HealthStatusAlive    = "alive"
+)
+
+// Dependency check or cache load which has to succeed once before service reports ready
+type WarmUpStep struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Dependency checked on every readiness probe
+type HealthCheck struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Tracks startup warm-up and dependency checks. Service accepts traffic right away,
+// but readiness probe fails until every warm-up step passed and while any check is down.
+type Readiness struct {
+	mu      sync.RWMutex
+	pending map[string]string
+	checks  []HealthCheck
+	backoff time.Duration
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{
+		pending: make(map[string]string),
+		backoff: defaultWarmUpBackoff,
+	}
+}
+
+// Run steps in order in background, each one is retried with exponential backoff until it succeeds.
+// Steps are marked pending right away, so readiness fails from the moment WarmUp is called.
+func (r *Readiness) WarmUp(ctx context.Context, steps ...WarmUpStep) {
+	r.mu.Lock()
+	for _, step := range steps {
+		r.pending[step.Name] = "not started"
+	}
+	r.mu.Unlock()
+
+	go func() {
+		for _, step := range steps {
+			if !r.runStep(ctx, step) {
+				return
+			}
+		}
+		log.Logger.Info("Warm-up finished, service is ready")
+	}()
+}
+
+func (r *Readiness) runStep(ctx context.Context, step WarmUpStep) bool {
+	rec := log.Logger.WithField("step", step.Name)
+	backoff := r.backoff
+
+	for {
+		err := step.Run(ctx)
+		if err == nil {
+			r.mu.Lock()
+			delete(r.pending, step.Name)
+			r.mu.Unlock()
+
+			rec.Info("Warm-up step succeeded")
+			return true
+		}
+
+		r.mu.Lock()
+		r.pending[step.Name] = err.Error()
+		r.mu.Unlock()
+
+		rec.WithError(err).Warnf("Warm-up step failed, retrying in %s", backoff)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxWarmUpBackoff)
+	}
+}
+
+// Ready reports whether warm-up finished, otherwise returns last error of every pending step
+func (r *Readiness) Ready() (bool, map[string]string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.pending) == 0 {
+		return true, nil
+	}
+
+	pending := make(map[string]string, len(r.pending))
+	for name, reason := range r.pending {
+		pending[name] = reason
+	}
+	return false, pending
+}
+
+func (r *Readiness) AddChecks(checks ...HealthCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, checks...)
+}
+
+// Run every check concurrently, each one bounded by healthCheckTimeout, and report status and latency of each
+func (r *Readiness) Check(ctx context.Context) *dto.HealthResponse {
+	r.mu.RLock()
+	checks := slices.Clone(r.checks)
+	r.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	results := make([]dto.HealthCheckResponse, len(checks))
+	var wg sync.WaitGroup
+	for index, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Run(ctx)
+			results[index] = dto.HealthCheckResponse{
+				Status:    HealthStatusUp,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[index].Status = HealthStatusDown
+				results[index].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	response := &dto.HealthResponse{
+		Status: HealthStatusReady,
+		Checks: make(map[string]dto.HealthCheckResponse, len(checks)+1),
+	}
+
+	for index, check := range checks {
+		response.Checks[check.Name] = results[index]
+		if results[index].Status != HealthStatusUp {
+			response.Status = HealthStatusNotReady
+		}
+	}
+
+	warmUp := dto.HealthCheckResponse{Status: HealthStatusUp}
+	if ready, pending := r.Ready(); !ready {
+		steps := make([]string, 0, len(pending))
+		for name, reason := range pending {
+			steps = append(steps, name+": "+reason)
+		}
+		slices.Sort(steps)
+
+		warmUp.Status = HealthStatusDown
+		warmUp.Error = strings.Join(steps, "; ")
+		response.Status = HealthStatusNotReady
+	}
+	response.Checks["warm_up"] = warmUp
+
+	return response
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWarmUp_RetriesUntilReady(t *testing.T) {
+	readiness := NewReadiness()
+	readiness.backoff = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failures := 2
+	cacheLoaded := make(chan struct{})
+	readiness.WarmUp(ctx,
+		WarmUpStep{Name: "redis", Run: func(ctx context.Context) error {
+			if failures > 0 {
+				failures--
+				return errors.New("connection refused")
+			}
+			return nil
+		}},
+		WarmUpStep{Name: "merchant_cache", Run: func(ctx context.Context) error {
+			<-cacheLoaded
+			return nil
+		}},
+	)
+
+	if ready, pending := readiness.Ready(); ready || len(pending) != 2 {
+		t.Fatalf("Expected both steps pending, got %v", pending)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, pending := readiness.Ready()
+		if _, ok := pending["redis"]; !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected redis step to succeed after retries")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if ready, pending := readiness.Ready(); ready || pending["merchant_cache"] == "" {
+		t.Fatalf("Expected merchant cache still pending, got %v", pending)
+	}
+
+	close(cacheLoaded)
+	for ready, _ := readiness.Ready(); !ready; ready, _ = readiness.Ready() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected service ready after warm-up")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCheck_ReportsEveryDependency(t *testing.T) {
+	readiness := NewReadiness()
+	readiness.AddChecks(
+		HealthCheck{Name: "postgres", Run: func(ctx context.Context) error { return nil }},
+		HealthCheck{Name: "redis", Run: func(ctx context.Context) error { return errors.New("connection refused") }},
+	)
+
+	result := readiness.Check(context.Background())
+	if result.Status != HealthStatusNotReady {
+		t.Fatalf("Expected not ready, got %s", result.Status)
+	}
+	if result.Checks["postgres"].Status != HealthStatusUp || result.Checks["warm_up"].Status != HealthStatusUp {
+		t.Fatalf("Unexpected checks: %+v", result.Checks)
+	}
+	if redis := result.Checks["redis"]; redis.Status != HealthStatusDown || redis.Error != "connection refused" {
+		t.Fatalf("Expected redis down, got %+v", redis)
+	}
+}
+
+func TestCheckSigningKeys(t *testing.T) {
+	if err := NewTokenService(nil, nil, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"}).CheckSigningKeys(); err != nil {
+		t.Fatalf("Expected signing key usable: %v", err)
+	}
+
+	if err := NewTokenService(nil, nil, nil, nil, nil, TokenConfig{}).CheckSigningKeys(); err == nil {
+		t.Fatal("Expected empty signing key rejected")
+	}
+}
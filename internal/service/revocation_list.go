@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	logs "briefcash-jwt/internal/helper/loghelper"
+	repo "briefcash-jwt/internal/repository"
+)
+
+// In-memory list of recently revoked jti, kept in sync with redis through pub/sub.
+// Entries are dropped once the token itself has expired.
+type RevocationList struct {
+	repo         repo.RevocationRepository
+	syncInterval time.Duration
+
+	mu        sync.RWMutex
+	entries   map[string]time.Time
+	following bool
+}
+
+func NewRevocationList(rr repo.RevocationRepository, syncInterval time.Duration) *RevocationList {
+	return &RevocationList{
+		repo:         rr,
+		syncInterval: syncInterval,
+		entries:      make(map[string]time.Time),
+	}
+}
+
+// Load current revocations, then follow the pub/sub channel and resync periodically until context is cancelled
+func (l *RevocationList) Start(ctx context.Context) {
+	log := logs.Logger.WithField("component", "revocation_list")
+
+	l.mu.Lock()
+	l.following = true
+	l.mu.Unlock()
+
+	if err := l.Sync(ctx); err != nil {
+		log.WithError(err).Warn("Failed initial revocation list sync, will retry")
+	}
+
+	go func() {
+		for {
+			if err := l.repo.Subscribe(ctx, func(token repo.RevokedToken) {
+				l.add(token.JTI, time.Unix(token.ExpiresAt, 0))
+			}); err != nil {
+				log.WithError(err).Warn("Revocation subscription interrupted, reconnecting")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+
+			// Catch up on revocations published while disconnected
+			if err := l.Sync(ctx); err != nil {
+				log.WithError(err).Warn("Failed to resync revocation list")
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(l.syncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := l.Sync(ctx); err != nil {
+					log.WithError(err).Warn("Failed to resync revocation list")
+				}
+				l.prune()
+			}
+		}
+	}()
+}
+
+// Merge revocations stored in redis into local list
+func (l *RevocationList) Sync(ctx context.Context) error {
+	revoked, err := l.repo.ListRevoked(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for jti, exp := range revoked {
+		l.entries[jti] = exp
+	}
+
+	return nil
+}
+
+// Publish jti to other replicas, it is only kept locally when the list is followed,
+// since pruning runs alongside the subscription
+func (l *RevocationList) Revoke(ctx context.Context, jti string, exp time.Time) error {
+	l.mu.RLock()
+	following := l.following
+	l.mu.RUnlock()
+	if following {
+		l.add(jti, exp)
+	}
+	return l.repo.Revoke(ctx, jti, exp)
+}
+
+func (l *RevocationList) IsRevoked(jti string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.entries[jti]
+	return ok
+}
+
+func (l *RevocationList) add(jti string, exp time.Time) {
+	if jti == "" || time.Now().After(exp) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[jti] = exp
+}
+
+func (l *RevocationList) prune() {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for jti, exp := range l.entries {
+		if now.After(exp) {
+			delete(l.entries, jti)
+		}
+	}
+}
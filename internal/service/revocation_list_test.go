@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	model "briefcash-jwt/internal/entity"
+	repo "briefcash-jwt/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type MockRevocationRepository struct {
+	Revoked map[string]time.Time
+	Err     error
+}
+
+func (m *MockRevocationRepository) Revoke(ctx context.Context, jti string, exp time.Time) error {
+	if m.Revoked == nil {
+		m.Revoked = make(map[string]time.Time)
+	}
+	m.Revoked[jti] = exp
+	return m.Err
+}
+
+func (m *MockRevocationRepository) ListRevoked(ctx context.Context) (map[string]time.Time, error) {
+	return m.Revoked, m.Err
+}
+
+func (m *MockRevocationRepository) Subscribe(ctx context.Context, handler func(token repo.RevokedToken)) error {
+	<-ctx.Done()
+	return nil
+}
+
+func newStatelessTokenService(rr *MockRedisRepository, revocations *RevocationList) TokenService {
+	return NewTokenService(&MockJWTRepository{}, rr, nil, nil, revocations, TokenConfig{
+		Secret:         "imamfahruzi",
+		ValidationMode: ValidationStateless,
+	})
+}
+
+func TestValidateToken_StatelessWithoutStore(t *testing.T) {
+	// Redis error must not affect stateless validation
+	rr := &MockRedisRepository{Store: make(map[string]string), Err: context.DeadlineExceeded}
+	svc := newStatelessTokenService(rr, NewRevocationList(&MockRevocationRepository{}, time.Minute))
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":     "a1b2c3",
+		"user_id": "STARK-1225",
+		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+	}).SignedString([]byte("imamfahruzi"))
+
+	if _, err := svc.ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+}
+
+func TestValidateToken_StatelessRevoked(t *testing.T) {
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	revocations := NewRevocationList(&MockRevocationRepository{
+		Revoked: map[string]time.Time{"a1b2c3": time.Now().Add(15 * time.Minute)},
+	}, time.Minute)
+	if err := revocations.Sync(context.Background()); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+	svc := newStatelessTokenService(rr, revocations)
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":     "a1b2c3",
+		"user_id": "STARK-1225",
+		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+	}).SignedString([]byte("imamfahruzi"))
+
+	if _, err := svc.ValidateToken(context.Background(), token); err == nil {
+		t.Fatal("Expected error but got nil")
+	}
+}
+
+func TestRevocationList_IgnoreExpired(t *testing.T) {
+	list := NewRevocationList(&MockRevocationRepository{}, time.Minute)
+
+	if err := list.Revoke(context.Background(), "expired", time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if list.IsRevoked("expired") {
+		t.Fatal("Expected expired jti not to be kept")
+	}
+}
+
+func TestBlacklistToken_StatefulPublishesRevocation(t *testing.T) {
+	// Stateful replicas still publish, so replicas switched to stateless reject the token
+	exp := time.Now().Add(15 * time.Minute)
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":     "a1b2c3",
+		"user_id": "STARK-1225",
+		"exp":     exp.Unix(),
+	}).SignedString([]byte("imamfahruzi"))
+	jr := &MockJWTRepository{
+		FindByAccessTokenResult: &model.JwtToken{AccessToken: token, ExpiresAt: exp},
+	}
+	revocationRepo := &MockRevocationRepository{}
+	svc := NewTokenService(jr, &MockRedisRepository{Store: make(map[string]string)}, nil, nil,
+		NewRevocationList(revocationRepo, time.Minute), TokenConfig{Secret: "imamfahruzi"})
+
+	if err := svc.BlacklistToken(context.Background(), token); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if _, ok := revocationRepo.Revoked["a1b2c3"]; !ok {
+		t.Fatal("Expected revoked jti to be published")
+	}
+}
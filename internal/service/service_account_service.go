@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	log "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrServiceAccountExists   = errors.New("service account already exists")
+	ErrServiceAccountNotFound = errors.New("service account not found")
+
+	clientIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,49}$`)
+)
+
+type ServiceAccountService interface {
+	CreateServiceAccount(ctx context.Context, req dto.ServiceAccountCreateRequest) (*dto.ServiceAccountResponse, error)
+	SetServiceAccountActive(ctx context.Context, clientID string, active bool) error
+}
+
+type serviceAccountService struct {
+	repo   repo.ServiceAccountRepository
+	tokens TokenService
+}
+
+func NewServiceAccountService(repo repo.ServiceAccountRepository, tokens TokenService) ServiceAccountService {
+	return &serviceAccountService{repo: repo, tokens: tokens}
+}
+
+// Register service account and return its client secret, secret is shown only once
+func (s *serviceAccountService) CreateServiceAccount(ctx context.Context, req dto.ServiceAccountCreateRequest) (*dto.ServiceAccountResponse, error) {
+	rec := log.Logger.WithField("service_account", req.ClientID)
+
+	if !clientIDPattern.MatchString(req.ClientID) {
+		return nil, fmt.Errorf("%w: client_id must be 3-50 lowercase letters, digits, - or _", ErrInvalidRequest)
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidRequest)
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidRequest)
+	}
+
+	existing, err := s.repo.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		rec.WithError(err).Error("Failed to check service account")
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	if existing != nil {
+		return nil, ErrServiceAccountExists
+	}
+
+	secret, err := mask.RandomID(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	hash, err := mask.HashSecret(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	account := &model.ServiceAccount{
+		ClientID:   req.ClientID,
+		Name:       req.Name,
+		SecretHash: hash,
+		Scopes:     strings.Join(req.Scopes, " "),
+		IsActive:   true,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.repo.Create(ctx, account); err != nil {
+		rec.WithError(err).Error("Failed to save service account")
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	rec.WithField("scopes", account.Scopes).Info("Service account registered")
+
+	return &dto.ServiceAccountResponse{
+		ClientID:     account.ClientID,
+		Name:         account.Name,
+		Scopes:       req.Scopes,
+		IsActive:     account.IsActive,
+		ClientSecret: secret,
+	}, nil
+}
+
+// Enable or disable service account, disabled account can no longer request token and its issued tokens are revoked
+func (s *serviceAccountService) SetServiceAccountActive(ctx context.Context, clientID string, active bool) error {
+	rec := log.Logger.WithField("service_account", clientID)
+
+	if err := s.repo.SetActive(ctx, clientID, active); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrServiceAccountNotFound
+		}
+		rec.WithError(err).Error("Failed to update service account status")
+		return fmt.Errorf("failed to update service account: %w", err)
+	}
+
+	if !active {
+		revoked, err := s.tokens.RevokeTokensByServiceAccount(ctx, clientID)
+		if err != nil {
+			rec.WithError(err).WithField("revoked_tokens", revoked).Error("Service account disabled but not all of its tokens revoked")
+			return err
+		}
+		rec = rec.WithField("revoked_tokens", revoked)
+	}
+
+	rec.Infof("Service account active status set to %t", active)
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	logs "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	tokenTypeService = "service"
+
+	// Scope granting access to merchant, session and service account management
+	ScopeAdmin = "admin"
+)
+
+var ErrInvalidClient = errors.New("invalid client")
+
+// Issue access token for service account using client credentials, no refresh token is issued
+func (ts *tokenService) GenerateServiceToken(ctx context.Context, req dto.ServiceTokenRequest) (*dto.ServiceTokenResponse, error) {
+	log := logs.Logger.WithField("service_account", req.ClientID)
+
+	if ts.serviceAccounts == nil {
+		return nil, fmt.Errorf("%w: service accounts are not enabled", ErrInvalidClient)
+	}
+
+	if req.ClientID == "" || req.ClientSecret == "" {
+		return nil, fmt.Errorf("%w: client_id and client_secret are required", ErrInvalidRequest)
+	}
+
+	account, err := ts.serviceAccounts.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve service account")
+		return nil, fmt.Errorf("failed to generate service token")
+	}
+
+	if account == nil || !account.IsActive || !mask.VerifySecret(account.SecretHash, req.ClientSecret) {
+		log.Warn("Service account authentication failed")
+		return nil, ErrInvalidClient
+	}
+
+	scopes, err := downScope(strings.Fields(account.Scopes), req.Scope)
+	if err != nil {
+		log.WithError(err).Warn("Service account requested scope it doesn't hold")
+		return nil, err
+	}
+
+	confirmation, err := ts.requestConfirmation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ts.issueToken(ctx, dto.JwtRequest{UserID: account.ClientID, Type: tokenTypeService}, issueOptions{
+		confirmation:   confirmation,
+		claims:         jwt.MapClaims{"scope": strings.Join(scopes, " ")},
+		accessOnly:     true,
+		serviceAccount: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ts.serviceAccounts.UpdateLastUsed(ctx, account.ClientID, time.Now()); err != nil {
+		log.WithError(err).Warn("Failed to record service account last usage")
+	}
+
+	log.WithField("scope", strings.Join(scopes, " ")).Info("Service account token issued")
+
+	return &dto.ServiceTokenResponse{
+		ClientID:    account.ClientID,
+		AccessToken: resp.AccessToken,
+		TokenType:   resp.TokenType,
+		TokenFormat: resp.TokenFormat,
+		Scope:       strings.Join(scopes, " "),
+		CreatedAt:   resp.CreatedAt,
+		ExpiresAt:   resp.ExpiresAt,
+	}, nil
+}
+
+// Blacklist every active token of service account, used when account is disabled
+func (ts *tokenService) RevokeTokensByServiceAccount(ctx context.Context, clientID string) (int, error) {
+	log := logs.Logger.WithField("service_account", clientID)
+
+	tokens, err := ts.jwtRepo.FindActiveByServiceAccountID(ctx, clientID)
+	if err != nil {
+		log.WithError(err).Error("Failed to retrieve active tokens of service account")
+		return 0, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	revoked, err := ts.revokeTokens(ctx, tokens, log)
+	if err != nil {
+		return revoked, err
+	}
+
+	log.Infof("Revoked %d tokens of service account", revoked)
+
+	return revoked, nil
+}
+
+// Tell whether validated token belongs to a service account
+func IsServiceToken(token *jwt.Token) bool {
+	return claimString(token, "type") == tokenTypeService
+}
+
+// Tell whether service account still exists and is enabled, lookup failure counts as inactive
+func (ts *tokenService) IsServiceAccountActive(ctx context.Context, clientID string) bool {
+	if ts.serviceAccounts == nil || clientID == "" {
+		return false
+	}
+
+	account, err := ts.serviceAccounts.GetByClientID(ctx, clientID)
+	if err != nil {
+		logs.Logger.WithError(err).WithField("service_account", clientID).Error("Failed to retrieve service account")
+		return false
+	}
+
+	return account != nil && account.IsActive
+}
+
+// Tell whether validated token is a service account token holding admin scope
+func IsAdminToken(token *jwt.Token) bool {
+	if !IsServiceToken(token) {
+		return false
+	}
+	return slices.Contains(strings.Fields(claimString(token, "scope")), ScopeAdmin)
+}
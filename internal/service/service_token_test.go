@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type MockServiceAccountRepository struct {
+	Accounts map[string]*model.ServiceAccount
+	Err      error
+}
+
+func (m *MockServiceAccountRepository) Create(ctx context.Context, account *model.ServiceAccount) error {
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Accounts == nil {
+		m.Accounts = make(map[string]*model.ServiceAccount)
+	}
+	m.Accounts[account.ClientID] = account
+	return nil
+}
+
+func (m *MockServiceAccountRepository) GetByClientID(ctx context.Context, clientID string) (*model.ServiceAccount, error) {
+	return m.Accounts[clientID], m.Err
+}
+
+func (m *MockServiceAccountRepository) SetActive(ctx context.Context, clientID string, active bool) error {
+	if account, ok := m.Accounts[clientID]; ok {
+		account.IsActive = active
+	}
+	return m.Err
+}
+
+func (m *MockServiceAccountRepository) UpdateLastUsed(ctx context.Context, clientID string, usedAt time.Time) error {
+	if account, ok := m.Accounts[clientID]; ok {
+		account.LastUsedAt = &usedAt
+	}
+	return m.Err
+}
+
+func newServiceAccountRepository(t *testing.T, clientID, secret, scopes string) *MockServiceAccountRepository {
+	t.Helper()
+
+	hash, err := mask.HashSecret(secret)
+	if err != nil {
+		t.Fatalf("Failed to hash secret: %v", err)
+	}
+
+	return &MockServiceAccountRepository{Accounts: map[string]*model.ServiceAccount{
+		clientID: {ClientID: clientID, Name: clientID, SecretHash: hash, Scopes: scopes, IsActive: true},
+	}}
+}
+
+func TestGenerateServiceToken_Success(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sa := newServiceAccountRepository(t, "reversal-worker", "s3cret", "reversal:create reversal:read")
+	svc := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", ServiceAccounts: sa})
+
+	resp, err := svc.GenerateServiceToken(context.Background(), dto.ServiceTokenRequest{
+		ClientID:     "reversal-worker",
+		ClientSecret: "s3cret",
+		Scope:        "reversal:read",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if jr.Saved == nil || jr.Saved.MerchantID != "" || jr.Saved.ServiceAccountID == nil || jr.Saved.RefreshToken != "" {
+		t.Fatalf("Unexpected stored token: %+v", jr.Saved)
+	}
+
+	token, err := svc.ValidateToken(context.Background(), resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if !IsServiceToken(token) {
+		t.Fatal("Expected token to be recognised as service token")
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	if claims["sub"] != "reversal-worker" || claims["scope"] != "reversal:read" || claims["user_id"] != nil {
+		t.Fatalf("Unexpected claims: %v", claims)
+	}
+
+	if sa.Accounts["reversal-worker"].LastUsedAt == nil {
+		t.Fatal("Expected last usage to be recorded")
+	}
+}
+
+func TestGenerateServiceToken_InvalidSecret(t *testing.T) {
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sa := newServiceAccountRepository(t, "reversal-worker", "s3cret", "reversal:create")
+	svc := NewTokenService(&MockJWTRepository{}, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", ServiceAccounts: sa})
+
+	_, err := svc.GenerateServiceToken(context.Background(), dto.ServiceTokenRequest{ClientID: "reversal-worker", ClientSecret: "wrong"})
+	if !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("Expected invalid client, got %v", err)
+	}
+}
+
+func TestIsServiceToken_MerchantToken(t *testing.T) {
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(&MockJWTRepository{}, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	resp, _ := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	token, err := svc.ValidateToken(context.Background(), resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if IsServiceToken(token) {
+		t.Fatal("Expected merchant token not to be a service token")
+	}
+}
+
+func TestIsAdminToken(t *testing.T) {
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sa := newServiceAccountRepository(t, "ops-console", "s3cret", "admin")
+	svc := NewTokenService(&MockJWTRepository{}, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", ServiceAccounts: sa})
+
+	admin, _ := svc.GenerateServiceToken(context.Background(), dto.ServiceTokenRequest{ClientID: "ops-console", ClientSecret: "s3cret"})
+	token, err := svc.ValidateToken(context.Background(), admin.AccessToken)
+	if err != nil || !IsAdminToken(token) {
+		t.Fatalf("Expected admin token, got err %v", err)
+	}
+
+	merchant, _ := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	token, _ = svc.ValidateToken(context.Background(), merchant.AccessToken)
+	if IsAdminToken(token) {
+		t.Fatal("Expected merchant token not to be admin")
+	}
+}
+
+func TestSetServiceAccountActive_RevokesTokens(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	sa := newServiceAccountRepository(t, "ops-console", "s3cret", "admin")
+	tokens := NewTokenService(jr, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi", ServiceAccounts: sa})
+
+	resp, err := tokens.GenerateServiceToken(context.Background(), dto.ServiceTokenRequest{ClientID: "ops-console", ClientSecret: "s3cret"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if _, err := tokens.ValidateToken(context.Background(), resp.AccessToken); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	jr.FindActiveResult = []model.JwtToken{*jr.Saved}
+	jr.FindByAccessTokenResult = jr.Saved
+
+	if err := NewServiceAccountService(sa, tokens).SetServiceAccountActive(context.Background(), "ops-console", false); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if _, err := tokens.ValidateToken(context.Background(), resp.AccessToken); err == nil {
+		t.Fatal("Expected token of disabled service account to be rejected")
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	log "briefcash-jwt/internal/helper/loghelper"
+	metrics "briefcash-jwt/internal/helper/metricshelper"
+)
+
+const defaultSessionMetricsInterval = 30 * time.Second
+
+// Refresh active sessions gauge in background, counting on every scrape would put scrape load on database
+func StartSessionMetrics(ctx context.Context, tokens TokenService, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultSessionMetricsInterval
+	}
+
+	rec := log.Logger.WithField("job", "session_metrics")
+	rec.Infof("Session metrics job started (interval: %s)", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			refreshSessionMetrics(ctx, tokens)
+
+			select {
+			case <-ctx.Done():
+				rec.Info("Session metrics job stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func refreshSessionMetrics(ctx context.Context, tokens TokenService) {
+	count, err := tokens.CountActiveSessions(ctx)
+	if err != nil {
+		log.Logger.WithError(err).Warn("Failed to refresh active sessions gauge")
+		return
+	}
+
+	metrics.SetActiveSessions(count)
+}
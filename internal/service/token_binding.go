@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	logs "briefcash-jwt/internal/helper/loghelper"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenTypeBearer = "Bearer"
+	TokenTypeDPoP   = "DPoP"
+
+	confirmationClaim   = "cnf"
+	jwkThumbprintClaim  = "jkt"
+	certThumbprintClaim = "x5t#S256"
+)
+
+var ErrCertificateMismatch = errors.New("client certificate does not match token")
+
+// Proof of possession presented by the client on current request
+type TokenBinding struct {
+	DPoPProof string
+	Method    string
+	URL       string
+
+	// SHA-256 thumbprint of verified client certificate, empty when connection is not mutual TLS
+	CertThumbprint string
+
+	// Audience and scopes the request is made for, exchanged token must hold both
+	Audience string
+	Scopes   []string
+}
+
+type tokenBindingKey struct{}
+
+// Attach request proof of possession to context, read back by GenerateToken and ValidateToken
+func WithTokenBinding(ctx context.Context, binding TokenBinding) context.Context {
+	return context.WithValue(ctx, tokenBindingKey{}, binding)
+}
+
+func tokenBindingFromContext(ctx context.Context) TokenBinding {
+	binding, _ := ctx.Value(tokenBindingKey{}).(TokenBinding)
+	return binding
+}
+
+// Base64url encoded SHA-256 of DER certificate, as used by x5t#S256 confirmation (RFC 8705 section 3.1)
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Keys the access token is bound to, written into cnf claim
+type tokenConfirmation struct {
+	JKT            string
+	CertThumbprint string
+}
+
+// Build confirmation from request, DPoP proof is verified and recorded before its key is trusted
+func (ts *tokenService) requestConfirmation(ctx context.Context) (tokenConfirmation, error) {
+	binding := tokenBindingFromContext(ctx)
+	confirmation := tokenConfirmation{CertThumbprint: binding.CertThumbprint}
+
+	if binding.DPoPProof != "" {
+		thumbprint, err := ts.checkDPoPProof(ctx, binding, "")
+		if err != nil {
+			return tokenConfirmation{}, err
+		}
+		confirmation.JKT = thumbprint
+	}
+
+	return confirmation, nil
+}
+
+func (c tokenConfirmation) claims() map[string]interface{} {
+	cnf := map[string]interface{}{}
+	if c.JKT != "" {
+		cnf[jwkThumbprintClaim] = c.JKT
+	}
+	if c.CertThumbprint != "" {
+		cnf[certThumbprintClaim] = c.CertThumbprint
+	}
+	return cnf
+}
+
+func (c tokenConfirmation) isEmpty() bool {
+	return c.JKT == "" && c.CertThumbprint == ""
+}
+
+// Refreshed token keeps the binding of the old token, so request must prove the same keys
+func (c tokenConfirmation) matches(token *tokenConfirmation) error {
+	if token.JKT != "" && c.JKT != token.JKT {
+		return fmt.Errorf("%w: proof key does not match token", ErrInvalidDPoPProof)
+	}
+	if token.CertThumbprint != "" && c.CertThumbprint != token.CertThumbprint {
+		return ErrCertificateMismatch
+	}
+	return nil
+}
+
+// Reject token bound to a key when request doesn't carry matching proof of possession
+func (ts *tokenService) verifyTokenBinding(ctx context.Context, token *jwt.Token, stringToken string) error {
+	binding := tokenBindingFromContext(ctx)
+
+	if x5t := confirmationMember(token, certThumbprintClaim); x5t != "" && x5t != binding.CertThumbprint {
+		logs.Logger.WithField("x5t#S256", x5t).Warn("Certificate bound token presented over different client certificate")
+		return ErrCertificateMismatch
+	}
+
+	if jkt := confirmationMember(token, jwkThumbprintClaim); jkt != "" {
+		return ts.verifyDPoPBinding(ctx, binding, jkt, stringToken)
+	}
+
+	return nil
+}
+
+// Authorization header scheme validated token must be sent with, DPoP bound token uses DPoP scheme (RFC 9449 section 7.1)
+func AuthorizationScheme(token *jwt.Token) string {
+	if confirmationMember(token, jwkThumbprintClaim) != "" {
+		return TokenTypeDPoP
+	}
+	return TokenTypeBearer
+}
+
+// Read member of cnf claim from parsed token, empty when token is not bound
+func confirmationMember(token *jwt.Token, name string) string {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	cnf, _ := claims[confirmationClaim].(map[string]interface{})
+	value, _ := cnf[name].(string)
+	return value
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	dto "briefcash-jwt/internal/dto"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidateToken_CertificateBound(t *testing.T) {
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := NewTokenService(&MockJWTRepository{}, rr, nil, nil, nil, TokenConfig{Secret: "imamfahruzi"})
+
+	partnerCtx := WithTokenBinding(context.Background(), TokenBinding{CertThumbprint: "partner-cert"})
+	resp, err := svc.GenerateToken(partnerCtx, dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	token, err := svc.ValidateToken(partnerCtx, resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	cnf := token.Claims.(jwt.MapClaims)[confirmationClaim].(map[string]interface{})
+	if cnf[certThumbprintClaim] != "partner-cert" {
+		t.Fatalf("Unexpected cnf claim: %v", cnf)
+	}
+
+	otherCtx := WithTokenBinding(context.Background(), TokenBinding{CertThumbprint: "other-cert"})
+	if _, err := svc.ValidateToken(otherCtx, resp.AccessToken); !errors.Is(err, ErrCertificateMismatch) {
+		t.Fatalf("Expected certificate mismatch, got %v", err)
+	}
+
+	if _, err := svc.ValidateToken(context.Background(), resp.AccessToken); !errors.Is(err, ErrCertificateMismatch) {
+		t.Fatalf("Expected certificate mismatch without client certificate, got %v", err)
+	}
+}
+
+func TestTokenConfirmation_Matches(t *testing.T) {
+	bound := &tokenConfirmation{CertThumbprint: "partner-cert"}
+
+	if err := (tokenConfirmation{CertThumbprint: "partner-cert"}).matches(bound); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if err := (tokenConfirmation{}).matches(bound); !errors.Is(err, ErrCertificateMismatch) {
+		t.Fatalf("Expected certificate mismatch, got %v", err)
+	}
+}
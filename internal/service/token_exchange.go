@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	logs "briefcash-jwt/internal/helper/loghelper"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+
+	tokenTypeExchange  = "exchange"
+	defaultExchangeTTL = 5 * time.Minute
+)
+
+var (
+	ErrUnsupportedGrantType = errors.New("unsupported grant type")
+	ErrInvalidGrant         = errors.New("invalid grant")
+	ErrInvalidTarget        = errors.New("invalid target")
+	ErrInvalidScope         = errors.New("invalid scope")
+	ErrAudienceMismatch     = errors.New("token is not valid for this audience or scope")
+)
+
+// Audiences reachable through token exchange and the scopes each may receive
+type exchangePolicy struct {
+	audiences map[string][]string
+	ttl       time.Duration
+}
+
+func newExchangePolicy(audiences map[string][]string, ttl time.Duration) exchangePolicy {
+	if ttl <= 0 {
+		ttl = defaultExchangeTTL
+	}
+	return exchangePolicy{audiences: audiences, ttl: ttl}
+}
+
+// Swap merchant token for a short lived, down scoped token for one audience,
+// calling service is recorded in act claim (RFC 8693 section 4.1)
+func (ts *tokenService) ExchangeToken(ctx context.Context, req dto.TokenExchangeRequest) (*dto.TokenExchangeResponse, error) {
+	log := logs.Logger.WithField("audience", req.Audience)
+
+	if req.GrantType != GrantTypeTokenExchange {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedGrantType, req.GrantType)
+	}
+
+	if req.SubjectToken == "" || req.ActorToken == "" {
+		return nil, fmt.Errorf("%w: subject_token and actor_token are required", ErrInvalidRequest)
+	}
+
+	if !isAccessTokenType(req.SubjectTokenType) || !isAccessTokenType(req.ActorTokenType) {
+		return nil, fmt.Errorf("%w: token type must be %s", ErrInvalidRequest, TokenTypeAccessToken)
+	}
+
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		return nil, fmt.Errorf("%w: only %s can be issued", ErrInvalidRequest, TokenTypeAccessToken)
+	}
+
+	allowedScopes, ok := ts.exchange.audiences[req.Audience]
+	if !ok {
+		log.Warn("Token exchange requested for unknown audience")
+		return nil, fmt.Errorf("%w: audience %q is not allowed", ErrInvalidTarget, req.Audience)
+	}
+
+	// Calling service presents actor token, so request proof of possession applies to it
+	actor, err := ts.ValidateToken(ctx, req.ActorToken)
+	if err != nil {
+		log.WithError(err).Warn("Actor token rejected")
+		return nil, fmt.Errorf("%w: actor token is invalid", ErrInvalidGrant)
+	}
+
+	actorID := claimString(actor, "sub")
+	if claimString(actor, "type") != tokenTypeService || actorID == "" {
+		return nil, fmt.Errorf("%w: actor token must belong to a service account", ErrInvalidGrant)
+	}
+
+	// Subject token is forwarded on behalf of the merchant, caller can't prove the merchant's key
+	subject, err := ts.validateToken(ctx, req.SubjectToken)
+	if err != nil {
+		log.WithError(err).Warn("Subject token rejected")
+		return nil, fmt.Errorf("%w: subject token is invalid", ErrInvalidGrant)
+	}
+
+	merchantID := claimString(subject, "user_id")
+	if merchantID == "" || claimString(subject, "type") != "access" {
+		return nil, fmt.Errorf("%w: subject token is not an access token", ErrInvalidGrant)
+	}
+
+	// Exchanged token is revoked together with the api key subject token was issued with
+	subjectData, err := ts.jwtRepo.FindByAccessToken(ctx, req.SubjectToken)
+	if err != nil || subjectData == nil {
+		log.WithError(err).Warn("Subject token not found in database")
+		return nil, fmt.Errorf("%w: subject token is invalid", ErrInvalidGrant)
+	}
+
+	// Exchanged token can only narrow what subject token already holds
+	if subjectScope := claimString(subject, "scope"); subjectScope != "" {
+		allowedScopes = intersectScopes(allowedScopes, strings.Fields(subjectScope))
+	}
+
+	scopes, err := downScope(allowedScopes, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := ts.exchange.ttl
+	if exp, err := subject.Claims.GetExpirationTime(); err == nil && exp != nil {
+		if remaining := time.Until(exp.Time); remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	log = log.WithField("user_id", merchantID).WithField("actor", actorID)
+	log.Infof("Exchanging token with scope %q", strings.Join(scopes, " "))
+
+	resp, err := ts.issueToken(ctx, dto.JwtRequest{UserID: merchantID, Type: tokenTypeExchange}, issueOptions{
+		claims: jwt.MapClaims{
+			"type":  tokenTypeExchange,
+			"aud":   req.Audience,
+			"scope": strings.Join(scopes, " "),
+			"act":   map[string]interface{}{"sub": actorID},
+		},
+		// Subject binding is kept, exchanged token of bound merchant token can't be used as bearer token
+		confirmation: tokenConfirmation{
+			JKT:            confirmationMember(subject, jwkThumbprintClaim),
+			CertThumbprint: confirmationMember(subject, certThumbprintClaim),
+		},
+		ttl:        ttl,
+		accessOnly: true,
+		apiKeyID:   subjectData.APIKeyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenExchangeResponse{
+		AccessToken:     resp.AccessToken,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       TokenTypeBearer,
+		ExpiresIn:       int64(ttl.Seconds()),
+		Scope:           strings.Join(scopes, " "),
+		Audience:        req.Audience,
+	}, nil
+}
+
+// Exchanged token is only accepted at its own audience and for scopes it was granted,
+// audience and scopes are told by the gateway validating on behalf of that service
+func verifyTokenAudience(ctx context.Context, token *jwt.Token) error {
+	if claimString(token, "type") != tokenTypeExchange {
+		return nil
+	}
+
+	binding := tokenBindingFromContext(ctx)
+	audience, _ := token.Claims.GetAudience()
+	if binding.Audience == "" || !slices.Contains(audience, binding.Audience) {
+		logs.Logger.WithField("audience", binding.Audience).Warn("Exchanged token presented outside its audience")
+		return ErrAudienceMismatch
+	}
+
+	granted := strings.Fields(claimString(token, "scope"))
+	for _, scope := range binding.Scopes {
+		if !slices.Contains(granted, scope) {
+			logs.Logger.WithField("scope", scope).Warn("Exchanged token presented without required scope")
+			return ErrAudienceMismatch
+		}
+	}
+
+	return nil
+}
+
+func isAccessTokenType(tokenType string) bool {
+	return tokenType == TokenTypeAccessToken || tokenType == TokenTypeJWT
+}
+
+// Resolve requested scope against allowed scopes, empty request grants every allowed scope
+func downScope(allowed []string, requested string) ([]string, error) {
+	if strings.TrimSpace(requested) == "" {
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("%w: no scope available for this audience", ErrInvalidScope)
+		}
+		return allowed, nil
+	}
+
+	scopes := strings.Fields(requested)
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return nil, fmt.Errorf("%w: %s is not allowed", ErrInvalidScope, scope)
+		}
+	}
+
+	return scopes, nil
+}
+
+func intersectScopes(left, right []string) []string {
+	result := make([]string, 0, len(left))
+	for _, scope := range left {
+		if slices.Contains(right, scope) {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	model "briefcash-jwt/internal/entity"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newExchangeTokenService(t *testing.T, jr *MockJWTRepository, rr *MockRedisRepository) TokenService {
+	return NewTokenService(jr, rr, nil, nil, nil, TokenConfig{
+		Secret: "imamfahruzi",
+		ExchangeAudiences: map[string][]string{
+			"transfer-service": {"transfer:create", "transfer:read"},
+		},
+		ExchangeTTL:     2 * time.Minute,
+		ServiceAccounts: newServiceAccountRepository(t, "disbursement-orchestrator", "s3cret", "exchange"),
+	})
+}
+
+// Exchange merchant token for transfer-service, merchant token row carries the api key it was issued with
+func exchangeMerchantToken(t *testing.T, jr *MockJWTRepository, svc TokenService, apiKeyID int64) *dto.TokenExchangeResponse {
+	merchant, _ := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	jr.FindByAccessTokenResult = &model.JwtToken{AccessToken: merchant.AccessToken, MerchantID: "STARK-1225", APIKeyID: &apiKeyID}
+	orchestrator, _ := svc.GenerateServiceToken(context.Background(), dto.ServiceTokenRequest{ClientID: "disbursement-orchestrator", ClientSecret: "s3cret"})
+
+	resp, err := svc.ExchangeToken(context.Background(), dto.TokenExchangeRequest{
+		GrantType:        GrantTypeTokenExchange,
+		SubjectToken:     merchant.AccessToken,
+		SubjectTokenType: TokenTypeAccessToken,
+		ActorToken:       orchestrator.AccessToken,
+		ActorTokenType:   TokenTypeAccessToken,
+		Audience:         "transfer-service",
+		Scope:            "transfer:create",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	return resp
+}
+
+func TestExchangeToken_Success(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := newExchangeTokenService(t, jr, rr)
+
+	resp := exchangeMerchantToken(t, jr, svc, 7)
+
+	if resp.ExpiresIn > 120 || resp.Scope != "transfer:create" {
+		t.Fatalf("Unexpected exchange response: %+v", resp)
+	}
+
+	ctx := WithTokenBinding(context.Background(), TokenBinding{Audience: "transfer-service", Scopes: []string{"transfer:create"}})
+	token, err := svc.ValidateToken(ctx, resp.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	act := claims["act"].(map[string]interface{})
+	if claims["user_id"] != "STARK-1225" || claims["aud"] != "transfer-service" || act["sub"] != "disbursement-orchestrator" {
+		t.Fatalf("Unexpected claims: %v", claims)
+	}
+
+	if jr.Saved.APIKeyID == nil || *jr.Saved.APIKeyID != 7 {
+		t.Fatalf("Expected exchanged token to keep api key of subject token, got %v", jr.Saved.APIKeyID)
+	}
+}
+
+func TestExchangeToken_RejectedAtOtherAudience(t *testing.T) {
+	jr := &MockJWTRepository{}
+	svc := newExchangeTokenService(t, jr, &MockRedisRepository{Store: make(map[string]string)})
+
+	resp := exchangeMerchantToken(t, jr, svc, 7)
+
+	cases := map[string]TokenBinding{
+		"other audience":    {Audience: "ledger-service"},
+		"no audience":       {},
+		"scope not granted": {Audience: "transfer-service", Scopes: []string{"transfer:read"}},
+	}
+	for name, binding := range cases {
+		_, err := svc.ValidateToken(WithTokenBinding(context.Background(), binding), resp.AccessToken)
+		if !errors.Is(err, ErrAudienceMismatch) {
+			t.Fatalf("%s: expected audience mismatch, got %v", name, err)
+		}
+	}
+}
+
+func TestExchangeToken_ScopeNotAllowed(t *testing.T) {
+	jr := &MockJWTRepository{}
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := newExchangeTokenService(t, jr, rr)
+
+	merchant, _ := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+	jr.FindByAccessTokenResult = &model.JwtToken{AccessToken: merchant.AccessToken, MerchantID: "STARK-1225"}
+	orchestrator, _ := svc.GenerateServiceToken(context.Background(), dto.ServiceTokenRequest{ClientID: "disbursement-orchestrator", ClientSecret: "s3cret"})
+
+	_, err := svc.ExchangeToken(context.Background(), dto.TokenExchangeRequest{
+		GrantType:        GrantTypeTokenExchange,
+		SubjectToken:     merchant.AccessToken,
+		SubjectTokenType: TokenTypeAccessToken,
+		ActorToken:       orchestrator.AccessToken,
+		ActorTokenType:   TokenTypeAccessToken,
+		Audience:         "transfer-service",
+		Scope:            "reversal:create",
+	})
+	if !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("Expected invalid scope, got %v", err)
+	}
+}
+
+func TestExchangeToken_UnknownAudience(t *testing.T) {
+	svc := newExchangeTokenService(t, &MockJWTRepository{}, &MockRedisRepository{Store: make(map[string]string)})
+
+	_, err := svc.ExchangeToken(context.Background(), dto.TokenExchangeRequest{
+		GrantType:        GrantTypeTokenExchange,
+		SubjectToken:     "subject",
+		SubjectTokenType: TokenTypeAccessToken,
+		ActorToken:       "actor",
+		ActorTokenType:   TokenTypeAccessToken,
+		Audience:         "ledger-service",
+	})
+	if !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("Expected invalid target, got %v", err)
+	}
+}
+
+func TestExchangeToken_MerchantActorRejected(t *testing.T) {
+	rr := &MockRedisRepository{Store: make(map[string]string)}
+	svc := newExchangeTokenService(t, &MockJWTRepository{}, rr)
+
+	merchant, _ := svc.GenerateToken(context.Background(), dto.JwtRequest{UserID: "STARK-1225", Type: "access"})
+
+	_, err := svc.ExchangeToken(context.Background(), dto.TokenExchangeRequest{
+		GrantType:        GrantTypeTokenExchange,
+		SubjectToken:     merchant.AccessToken,
+		SubjectTokenType: TokenTypeAccessToken,
+		ActorToken:       merchant.AccessToken,
+		ActorTokenType:   TokenTypeAccessToken,
+		Audience:         "transfer-service",
+	})
+	if !errors.Is(err, ErrInvalidGrant) {
+		t.Fatalf("Expected invalid grant, got %v", err)
+	}
+}
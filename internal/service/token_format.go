@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	logs "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TokenFormatJWT          = "jwt"
+	TokenFormatOpaque       = "opaque"
+	TokenFormatJWE          = "jwe"
+	TokenFormatPasetoPublic = "paseto.v4.public"
+	TokenFormatPasetoLocal  = "paseto.v4.local"
+)
+
+// Self-contained token format. Sign turns claims into token string, Verify checks
+// the token cryptographically and its expiry, then returns the claims.
+type TokenFormat interface {
+	Name() string
+	Detect(token string) bool
+	Sign(claims jwt.MapClaims) (string, error)
+	Verify(token string) (*jwt.Token, error)
+}
+
+// Signed JWT using HS256
+type jwtFormat struct {
+	secret []byte
+}
+
+func (f *jwtFormat) Name() string {
+	return TokenFormatJWT
+}
+
+func (f *jwtFormat) Detect(token string) bool {
+	return strings.Count(token, ".") == 2 && !isPasetoToken(token)
+}
+
+func (f *jwtFormat) Sign(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(f.secret)
+}
+
+func (f *jwtFormat) Verify(stringToken string) (*jwt.Token, error) {
+	masked := mask.MaskToken(stringToken)
+	log := logs.Logger.WithField("token", masked)
+
+	log.Infof("Parsing JWT for token %s", masked)
+	token, err := jwt.Parse(stringToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return f.secret, nil
+	})
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			log.Warn("Token has expired")
+			return nil, fmt.Errorf("token expired")
+		}
+
+		log.WithError(err).Error("Unexpected error while parsing token")
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Signed JWT nested in JWE
+type jweFormat struct {
+	signer    *jwtFormat
+	encrypter *TokenEncrypter
+}
+
+func (f *jweFormat) Name() string {
+	return TokenFormatJWE
+}
+
+func (f *jweFormat) Detect(token string) bool {
+	return isEncryptedToken(token)
+}
+
+func (f *jweFormat) Sign(claims jwt.MapClaims) (string, error) {
+	signed, err := f.signer.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	return f.encrypter.Encrypt(signed)
+}
+
+func (f *jweFormat) Verify(token string) (*jwt.Token, error) {
+	signed, err := f.encrypter.Decrypt(token)
+	if err != nil {
+		logs.Logger.WithError(err).WithField("token", mask.MaskToken(token)).Error("Failed to decrypt token")
+		return nil, fmt.Errorf("token invalid or blacklisted")
+	}
+	return f.signer.Verify(signed)
+}
+
+// Pick format able to read the given token, nil when the token shape is unknown
+func (ts *tokenService) detectFormat(token string) TokenFormat {
+	for _, format := range ts.formats {
+		if format.Detect(token) {
+			return format
+		}
+	}
+	return nil
+}
+
+func (ts *tokenService) format(name string) (TokenFormat, error) {
+	for _, format := range ts.formats {
+		if format.Name() == name {
+			return format, nil
+		}
+	}
+	return nil, fmt.Errorf("token format %q is not enabled", name)
+}
+
+// Sign and verify a probe token with every enabled format, proves signing keys are loaded and usable
+func (ts *tokenService) CheckSigningKeys() error {
+	claims := jwt.MapClaims{
+		"sub": "readiness_probe",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+
+	for _, format := range ts.formats {
+		// HMAC signs with an empty secret without complaint
+		if signer, ok := format.(*jwtFormat); ok && len(signer.secret) == 0 {
+			return fmt.Errorf("%s signing key is empty", format.Name())
+		}
+
+		token, err := format.Sign(claims)
+		if err != nil {
+			return fmt.Errorf("%s signing key is not usable: %w", format.Name(), err)
+		}
+
+		if _, err := format.Verify(token); err != nil {
+			return fmt.Errorf("%s signing key is not usable: %w", format.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// Verify token with its own format and return the claims
+func (ts *tokenService) verifyToken(token string) (*jwt.Token, error) {
+	format := ts.detectFormat(token)
+	if format == nil {
+		return nil, fmt.Errorf("token invalid or blacklisted")
+	}
+	return format.Verify(token)
+}
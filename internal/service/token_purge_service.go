@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	logs "briefcash-jwt/internal/helper/loghelper"
+	metrics "briefcash-jwt/internal/helper/metricshelper"
+	repo "briefcash-jwt/internal/repository"
+)
+
+const tokenPurgeLease = "jwt_token_purge"
+
+type TokenPurgeService interface {
+	Start(ctx context.Context)
+	Purge(ctx context.Context) (int64, error)
+	Stats() PurgeStats
+}
+
+type PurgeConfig struct {
+	Interval  time.Duration
+	Retention time.Duration
+	BatchSize int
+
+	// Move rows into jwt_token_history instead of deleting them
+	Archive          bool
+	HistoryRetention time.Duration
+}
+
+// Counters of purge job, exposed for monitoring
+type PurgeStats struct {
+	Runs       int64     `json:"runs"`
+	Skipped    int64     `json:"skipped"`
+	Failures   int64     `json:"failures"`
+	RowsPurged int64     `json:"rows_purged"`
+	LastPurged int64     `json:"last_purged"`
+	LastRunAt  time.Time `json:"last_run_at"`
+}
+
+type tokenPurgeService struct {
+	jwtRepo   repo.JwtRepository
+	leaseRepo repo.LeaseRepository
+	cfg       PurgeConfig
+	owner     string
+
+	mu    sync.Mutex
+	stats PurgeStats
+}
+
+func NewTokenPurgeService(jr repo.JwtRepository, lr repo.LeaseRepository, cfg PurgeConfig) TokenPurgeService {
+	return &tokenPurgeService{
+		jwtRepo:   jr,
+		leaseRepo: lr,
+		cfg:       cfg,
+		owner:     instanceID(),
+	}
+}
+
+// Run purge job periodically until context is cancelled
+func (s *tokenPurgeService) Start(ctx context.Context) {
+	log := logs.Logger.WithField("job", tokenPurgeLease)
+	log.Infof("Token purge job started (interval: %s, retention: %s, batch: %d)", s.cfg.Interval, s.cfg.Retention, s.cfg.BatchSize)
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("Token purge job stopped")
+				return
+			case <-ticker.C:
+				s.runOnce(ctx)
+			}
+		}
+	}()
+}
+
+func (s *tokenPurgeService) runOnce(ctx context.Context) {
+	log := logs.Logger.WithFields(map[string]interface{}{
+		"job":   tokenPurgeLease,
+		"owner": s.owner,
+	})
+
+	acquired, err := s.leaseRepo.Acquire(ctx, tokenPurgeLease, s.owner, s.cfg.Interval)
+	if err != nil {
+		log.WithError(err).Error("Failed to acquire token purge lease")
+		s.record(func(st *PurgeStats) { st.Failures++ })
+		return
+	}
+
+	if !acquired {
+		log.Info("Token purge lease held by another instance, skipping")
+		s.record(func(st *PurgeStats) { st.Skipped++ })
+		return
+	}
+
+	defer func() {
+		if err := s.leaseRepo.Release(context.Background(), tokenPurgeLease, s.owner); err != nil {
+			log.WithError(err).Warn("Failed to release token purge lease")
+		}
+	}()
+
+	if _, err := s.Purge(ctx); err != nil {
+		log.WithError(err).Error("Token purge job failed")
+	}
+}
+
+// Delete expired and revoked token in batches, return total deleted rows
+func (s *tokenPurgeService) Purge(ctx context.Context) (int64, error) {
+	start := time.Now()
+	before := start.Add(-s.cfg.Retention)
+	log := logs.Logger.WithFields(map[string]interface{}{
+		"job":    tokenPurgeLease,
+		"before": before.Format(time.RFC3339),
+	})
+
+	purgeBatch := s.jwtRepo.DeleteExpiredBatch
+	if s.cfg.Archive {
+		if err := s.prepareHistory(ctx, before); err != nil {
+			s.finishRun(start, 0, true)
+			return 0, err
+		}
+		purgeBatch = s.jwtRepo.ArchiveExpiredBatch
+	}
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			s.finishRun(start, total, true)
+			return total, err
+		}
+
+		deleted, err := purgeBatch(ctx, before, s.cfg.BatchSize)
+		if err != nil {
+			s.finishRun(start, total, true)
+			return total, fmt.Errorf("failed to purge expired token: %w", err)
+		}
+
+		total += deleted
+		if deleted < int64(s.cfg.BatchSize) {
+			break
+		}
+
+		if _, err := s.leaseRepo.Renew(ctx, tokenPurgeLease, s.owner, s.cfg.Interval); err != nil {
+			log.WithError(err).Warn("Failed to renew token purge lease")
+		}
+	}
+
+	if s.cfg.Archive && s.cfg.HistoryRetention > 0 {
+		dropped, err := s.jwtRepo.DropHistoryPartitionsBefore(ctx, start.Add(-s.cfg.HistoryRetention))
+		if err != nil {
+			log.WithError(err).Warn("Failed to drop old token history partitions")
+		}
+		if len(dropped) > 0 {
+			log.Infof("Dropped token history partitions: %v", dropped)
+		}
+	}
+
+	s.finishRun(start, total, false)
+	log.WithField("duration", time.Since(start).String()).Infof("Token purge finished, %d rows purged", total)
+
+	return total, nil
+}
+
+// Make sure monthly history partition exist for every row going to be archived
+func (s *tokenPurgeService) prepareHistory(ctx context.Context, before time.Time) error {
+	oldest, newest, err := s.jwtRepo.FindExpiredRange(ctx, before)
+	if err != nil {
+		return fmt.Errorf("failed to check archive range: %w", err)
+	}
+
+	// Always keep partition for current and next month ready
+	from, to := time.Now(), time.Now().AddDate(0, 1, 0)
+	if oldest != nil && oldest.Before(from) {
+		from = *oldest
+	}
+	if newest != nil && newest.After(to) {
+		to = *newest
+	}
+
+	if err := s.jwtRepo.EnsureHistoryPartitions(ctx, from, to); err != nil {
+		return fmt.Errorf("failed to prepare history partition: %w", err)
+	}
+
+	return nil
+}
+
+func (s *tokenPurgeService) Stats() PurgeStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Export purge counters, read from Stats on every scrape
+func RegisterPurgeMetrics(purge TokenPurgeService) {
+	runs := "Token purge runs by result, skipped when another replica holds the lease."
+	metrics.RegisterCounterFunc("token_purge_runs_total", runs, map[string]string{"result": "run"}, func() float64 {
+		return float64(purge.Stats().Runs)
+	})
+	metrics.RegisterCounterFunc("token_purge_runs_total", runs, map[string]string{"result": "skipped"}, func() float64 {
+		return float64(purge.Stats().Skipped)
+	})
+	metrics.RegisterCounterFunc("token_purge_runs_total", runs, map[string]string{"result": "failed"}, func() float64 {
+		return float64(purge.Stats().Failures)
+	})
+	metrics.RegisterCounterFunc("token_purge_rows_total", "Token rows deleted or archived by purge job.", nil, func() float64 {
+		return float64(purge.Stats().RowsPurged)
+	})
+	metrics.RegisterGaugeFunc("token_purge_last_run_timestamp_seconds", "Start of last purge run on this replica.", func() float64 {
+		lastRun := purge.Stats().LastRunAt
+		if lastRun.IsZero() {
+			return 0
+		}
+		return float64(lastRun.Unix())
+	})
+}
+
+func (s *tokenPurgeService) finishRun(start time.Time, purged int64, failed bool) {
+	s.record(func(st *PurgeStats) {
+		st.Runs++
+		st.RowsPurged += purged
+		st.LastPurged = purged
+		st.LastRunAt = start
+		if failed {
+			st.Failures++
+		}
+	})
+}
+
+func (s *tokenPurgeService) record(update func(st *PurgeStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.stats)
+}
+
+// Identify current replica as lease owner
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
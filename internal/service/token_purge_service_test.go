@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type MockLeaseRepository struct {
+	Owners map[string]string
+	Err    error
+}
+
+func (l *MockLeaseRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	if l.Err != nil {
+		return false, l.Err
+	}
+	if l.Owners == nil {
+		l.Owners = make(map[string]string)
+	}
+	if _, ok := l.Owners[name]; ok {
+		return false, nil
+	}
+	l.Owners[name] = owner
+	return true, nil
+}
+
+func (l *MockLeaseRepository) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return l.Owners[name] == owner, l.Err
+}
+
+func (l *MockLeaseRepository) Release(ctx context.Context, name, owner string) error {
+	if l.Owners[name] == owner {
+		delete(l.Owners, name)
+	}
+	return l.Err
+}
+
+func TestPurge_DeletesInBatches(t *testing.T) {
+	jr := &MockJWTRepository{DeleteExpiredResults: []int64{100, 100, 42}}
+	svc := NewTokenPurgeService(jr, &MockLeaseRepository{}, PurgeConfig{
+		Interval:  time.Minute,
+		Retention: time.Hour,
+		BatchSize: 100,
+	})
+
+	total, err := svc.Purge(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if total != 242 {
+		t.Fatalf("Expected 242 purged rows, got %d", total)
+	}
+
+	if stats := svc.Stats(); stats.RowsPurged != 242 || stats.Runs != 1 {
+		t.Fatalf("Unexpected purge stats: %+v", stats)
+	}
+}
+
+func TestPurge_Failed(t *testing.T) {
+	jr := &MockJWTRepository{DeleteExpiredErr: fmt.Errorf("connection reset")}
+	svc := NewTokenPurgeService(jr, &MockLeaseRepository{}, PurgeConfig{
+		Interval:  time.Minute,
+		Retention: time.Hour,
+		BatchSize: 100,
+	})
+
+	if _, err := svc.Purge(context.Background()); err == nil {
+		t.Fatal("Expected error but got nil")
+	}
+
+	if stats := svc.Stats(); stats.Failures != 1 {
+		t.Fatalf("Expected 1 failure, got %d", stats.Failures)
+	}
+}
+
+func TestRunOnce_SkipWhenLeaseHeld(t *testing.T) {
+	jr := &MockJWTRepository{DeleteExpiredResults: []int64{10}}
+	lr := &MockLeaseRepository{Owners: map[string]string{tokenPurgeLease: "other-replica"}}
+	svc := NewTokenPurgeService(jr, lr, PurgeConfig{
+		Interval:  time.Minute,
+		Retention: time.Hour,
+		BatchSize: 100,
+	}).(*tokenPurgeService)
+
+	svc.runOnce(context.Background())
+
+	if stats := svc.Stats(); stats.Skipped != 1 || stats.Runs != 0 {
+		t.Fatalf("Unexpected purge stats: %+v", stats)
+	}
+}
+
+func TestPurge_ArchivePreparesPartitions(t *testing.T) {
+	jr := &MockJWTRepository{DeleteExpiredResults: []int64{5}}
+	svc := NewTokenPurgeService(jr, &MockLeaseRepository{}, PurgeConfig{
+		Interval:  time.Minute,
+		Retention: time.Hour,
+		BatchSize: 100,
+		Archive:   true,
+	})
+
+	total, err := svc.Purge(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if total != 5 {
+		t.Fatalf("Expected 5 archived rows, got %d", total)
+	}
+
+	if len(jr.ArchivedPartitions) == 0 {
+		t.Fatal("Expected history partitions to be prepared before archiving")
+	}
+}
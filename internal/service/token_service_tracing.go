@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+
+	dto "briefcash-jwt/internal/dto"
+	tracing "briefcash-jwt/internal/helper/tracehelper"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Wraps every TokenService call in a span, tokens themselves are never recorded
+type tracedTokenService struct {
+	next TokenService
+}
+
+func TraceTokenService(next TokenService) TokenService {
+	return &tracedTokenService{next: next}
+}
+
+func (t *tracedTokenService) GenerateToken(ctx context.Context, req dto.JwtRequest) (*dto.JwtResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.GenerateToken", attribute.String("merchant.id", req.UserID))
+	response, err := t.next.GenerateToken(ctx, req)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (t *tracedTokenService) ValidateToken(ctx context.Context, stringToken string) (*jwt.Token, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ValidateToken")
+	token, err := t.next.ValidateToken(ctx, stringToken)
+	tracing.End(span, err)
+	return token, err
+}
+
+func (t *tracedTokenService) BlacklistToken(ctx context.Context, stringToken string) error {
+	ctx, span := tracing.Start(ctx, "TokenService.BlacklistToken")
+	err := t.next.BlacklistToken(ctx, stringToken)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedTokenService) RefreshToken(ctx context.Context, refreshToken string) (*dto.JwtResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.RefreshToken")
+	response, err := t.next.RefreshToken(ctx, refreshToken)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (t *tracedTokenService) ListActiveSessions(ctx context.Context, merchantID string) ([]dto.SessionResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ListActiveSessions", attribute.String("merchant.id", merchantID))
+	sessions, err := t.next.ListActiveSessions(ctx, merchantID)
+	tracing.End(span, err)
+	return sessions, err
+}
+
+func (t *tracedTokenService) TerminateSession(ctx context.Context, sessionID int64) error {
+	ctx, span := tracing.Start(ctx, "TokenService.TerminateSession", attribute.Int64("session.id", sessionID))
+	err := t.next.TerminateSession(ctx, sessionID)
+	tracing.End(span, err)
+	return err
+}
+
+func (t *tracedTokenService) ListSessionHistory(ctx context.Context, req dto.SessionHistoryRequest) ([]dto.SessionHistoryResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ListSessionHistory", attribute.String("merchant.id", req.MerchantSettingsID))
+	histories, err := t.next.ListSessionHistory(ctx, req)
+	tracing.End(span, err)
+	return histories, err
+}
+
+func (t *tracedTokenService) ExchangeToken(ctx context.Context, req dto.TokenExchangeRequest) (*dto.TokenExchangeResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.ExchangeToken", attribute.String("token.audience", req.Audience))
+	response, err := t.next.ExchangeToken(ctx, req)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (t *tracedTokenService) GenerateServiceToken(ctx context.Context, req dto.ServiceTokenRequest) (*dto.ServiceTokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.GenerateServiceToken", attribute.String("service_account.client_id", req.ClientID))
+	response, err := t.next.GenerateServiceToken(ctx, req)
+	tracing.End(span, err)
+	return response, err
+}
+
+func (t *tracedTokenService) IsServiceAccountActive(ctx context.Context, clientID string) bool {
+	ctx, span := tracing.Start(ctx, "TokenService.IsServiceAccountActive", attribute.String("service_account.client_id", clientID))
+	defer span.End()
+	return t.next.IsServiceAccountActive(ctx, clientID)
+}
+
+func (t *tracedTokenService) RevokeTokensByAPIKey(ctx context.Context, apiKeyID int64) (int, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeTokensByAPIKey", attribute.Int64("api_key.id", apiKeyID))
+	revoked, err := t.next.RevokeTokensByAPIKey(ctx, apiKeyID)
+	span.SetAttributes(attribute.Int("token.revoked", revoked))
+	tracing.End(span, err)
+	return revoked, err
+}
+
+func (t *tracedTokenService) RevokeTokensByServiceAccount(ctx context.Context, clientID string) (int, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeTokensByServiceAccount", attribute.String("service_account.client_id", clientID))
+	revoked, err := t.next.RevokeTokensByServiceAccount(ctx, clientID)
+	span.SetAttributes(attribute.Int("token.revoked", revoked))
+	tracing.End(span, err)
+	return revoked, err
+}
+
+func (t *tracedTokenService) RevokeTokensByMerchant(ctx context.Context, merchantCode string) (int, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.RevokeTokensByMerchant", attribute.String("merchant.code", merchantCode))
+	revoked, err := t.next.RevokeTokensByMerchant(ctx, merchantCode)
+	span.SetAttributes(attribute.Int("token.revoked", revoked))
+	tracing.End(span, err)
+	return revoked, err
+}
+
+func (t *tracedTokenService) CountActiveSessions(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.CountActiveSessions")
+	count, err := t.next.CountActiveSessions(ctx)
+	tracing.End(span, err)
+	return count, err
+}
+
+// Served from memory, not worth a span
+func (t *tracedTokenService) EncryptionKeys() jose.JSONWebKeySet {
+	return t.next.EncryptionKeys()
+}
+
+func (t *tracedTokenService) CheckSigningKeys() error {
+	return t.next.CheckSigningKeys()
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraceTokenService_SpansValidation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "STARK-1225",
+		"exp":     time.Now().Add(15 * time.Minute).Unix(),
+	}).SignedString([]byte("imamfahruzi"))
+
+	rr := &MockRedisRepository{Store: map[string]string{token: "valid"}}
+	svc := TraceTokenService(NewMockTokenService(&MockJWTRepository{}, rr, "imamfahruzi"))
+
+	if _, err := svc.ValidateToken(context.Background(), token); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	rr.Store[token] = "invalid"
+	if _, err := svc.ValidateToken(context.Background(), token); err == nil {
+		t.Fatal("Expected blacklisted token to be rejected")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected verify span and two validation spans, got %d", len(spans))
+	}
+
+	verify, valid, rejected := spans[0], spans[1], spans[2]
+	if verify.Name() != "TokenFormat.Verify" || valid.Name() != "TokenService.ValidateToken" {
+		t.Fatalf("Unexpected spans %q and %q", verify.Name(), valid.Name())
+	}
+
+	if verify.Parent().SpanID() != valid.SpanContext().SpanID() {
+		t.Fatal("Expected signature verification to be child of validation span")
+	}
+
+	if valid.Status().Code == codes.Error || rejected.Status().Code != codes.Error {
+		t.Fatalf("Expected only rejected validation to be marked failed, got %v and %v", valid.Status(), rejected.Status())
+	}
+}
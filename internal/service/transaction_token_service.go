@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	log "briefcash-jwt/internal/helper/loghelper"
+	mask "briefcash-jwt/internal/helper/securityhelper"
+	clock "briefcash-jwt/internal/helper/timehelper"
+	repo "briefcash-jwt/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	transactionTokenPrefix     = "txn_"
+	defaultTransactionTokenTTL = 2 * time.Minute
+)
+
+var (
+	ErrTransactionTokenRejected = errors.New("transaction token rejected")
+
+	amountPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,2})?$`)
+)
+
+// Access token is the caller token already validated together with its proof of possession
+type TransactionTokenService interface {
+	IssueTransactionToken(ctx context.Context, accessToken *jwt.Token, req dto.TransactionTokenRequest) (*dto.TransactionTokenResponse, error)
+	ConsumeTransactionToken(ctx context.Context, accessToken *jwt.Token, req dto.TransactionTokenConsumeRequest) (*dto.TransactionTokenConsumeResponse, error)
+}
+
+type transactionTokenService struct {
+	repo repo.TransactionTokenRepository
+	ttl  time.Duration
+}
+
+func NewTransactionTokenService(repo repo.TransactionTokenRepository, ttl time.Duration) TransactionTokenService {
+	if ttl <= 0 {
+		ttl = defaultTransactionTokenTTL
+	}
+	return &transactionTokenService{repo: repo, ttl: ttl}
+}
+
+// Issue single use token for one transfer, caller must hold a valid merchant access token
+func (s *transactionTokenService) IssueTransactionToken(ctx context.Context, accessToken *jwt.Token, req dto.TransactionTokenRequest) (*dto.TransactionTokenResponse, error) {
+	rec := log.Logger.WithField("merchant_code", req.MerchantCode)
+
+	merchantID := claimString(accessToken, "user_id")
+	if merchantID == "" || claimString(accessToken, "type") != "access" || merchantID != req.MerchantCode {
+		rec.Warn("Transaction token requested with token of another subject")
+		return nil, fmt.Errorf("%w: access token does not belong to merchant", ErrTransactionTokenRejected)
+	}
+
+	payloadHash, err := transactionHash(req.Amount, req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := mask.RandomID(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate transaction token: %w", err)
+	}
+
+	now := time.Now()
+	record := repo.TransactionToken{
+		MerchantID:  merchantID,
+		PayloadHash: payloadHash,
+		Amount:      req.Amount,
+		IssuedAt:    now,
+	}
+
+	if err := s.repo.Save(ctx, transactionTokenPrefix+handle, record, s.ttl); err != nil {
+		rec.WithError(err).Error("Failed to store transaction token")
+		return nil, fmt.Errorf("failed to generate transaction token")
+	}
+
+	rec.WithField("amount", req.Amount).Info("Transaction token issued")
+
+	return &dto.TransactionTokenResponse{
+		Token:       transactionTokenPrefix + handle,
+		PayloadHash: payloadHash,
+		Amount:      req.Amount,
+		ExpiresAt:   clock.FormatTimeToISO7(now.Add(s.ttl)),
+	}, nil
+}
+
+// Verify payload against token and consume it, a token can authorize exactly one transfer.
+// Merchant is taken from caller access token, only the merchant the token was issued to can consume it.
+func (s *transactionTokenService) ConsumeTransactionToken(ctx context.Context, accessToken *jwt.Token, req dto.TransactionTokenConsumeRequest) (*dto.TransactionTokenConsumeResponse, error) {
+	rec := log.Logger.WithField("transaction_token", mask.MaskToken(req.Token))
+
+	if req.Token == "" {
+		return nil, fmt.Errorf("%w: transaction_token is required", ErrInvalidRequest)
+	}
+
+	merchantID := claimString(accessToken, "user_id")
+	if merchantID == "" || claimString(accessToken, "type") != "access" {
+		rec.Warn("Transaction token consumed without merchant access token")
+		return nil, fmt.Errorf("%w: access token does not belong to merchant", ErrTransactionTokenRejected)
+	}
+
+	if req.MerchantCode != "" && req.MerchantCode != merchantID {
+		rec.WithField("merchant_code", req.MerchantCode).Warn("Transaction token consumed for another merchant")
+		return nil, fmt.Errorf("%w: access token does not belong to merchant", ErrTransactionTokenRejected)
+	}
+
+	payloadHash, err := transactionHash(req.Amount, req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	rec = rec.WithField("merchant_code", merchantID)
+	record, err := s.repo.Consume(ctx, req.Token, merchantID, payloadHash)
+	if err != nil {
+		if errors.Is(err, repo.ErrTransactionTokenNotFound) || errors.Is(err, repo.ErrTransactionTokenConsumed) ||
+			errors.Is(err, repo.ErrTransactionTokenMismatch) || errors.Is(err, repo.ErrTransactionTokenMerchant) {
+			rec.WithError(err).Warn("Transaction token rejected")
+			return nil, fmt.Errorf("%w: %v", ErrTransactionTokenRejected, err)
+		}
+		rec.WithError(err).Error("Failed to consume transaction token")
+		return nil, err
+	}
+	record.MerchantID = merchantID
+
+	rec.Info("Transaction token consumed")
+
+	return &dto.TransactionTokenConsumeResponse{
+		MerchantCode: record.MerchantID,
+		PayloadHash:  record.PayloadHash,
+		Amount:       record.Amount,
+		IssuedAt:     clock.FormatTimeToISO7(record.IssuedAt),
+		ConsumedAt:   clock.FormatTimeToISO7(time.Now()),
+	}, nil
+}
+
+// SHA-256 of amount and canonical payload, key order and whitespace do not change the hash
+func transactionHash(amount string, payload json.RawMessage) (string, error) {
+	if !amountPattern.MatchString(amount) {
+		return "", fmt.Errorf("%w: amount must be a decimal with at most 2 fraction digits", ErrInvalidRequest)
+	}
+
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return "", fmt.Errorf("%w: payload is required", ErrInvalidRequest)
+	}
+
+	var decoded any
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return "", fmt.Errorf("%w: payload must be valid JSON", ErrInvalidRequest)
+	}
+
+	// Map keys are sorted when encoded, so equal payloads always produce the same bytes
+	canonical, err := json.Marshal(decoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: payload must be valid JSON", ErrInvalidRequest)
+	}
+
+	sum := sha256.New()
+	sum.Write([]byte(amount))
+	sum.Write([]byte{'\n'})
+	sum.Write(canonical)
+
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
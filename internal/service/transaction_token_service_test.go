@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	dto "briefcash-jwt/internal/dto"
+	repo "briefcash-jwt/internal/repository"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type MockTransactionTokenRepository struct {
+	Tokens   map[string]repo.TransactionToken
+	Consumed map[string]bool
+}
+
+func (m *MockTransactionTokenRepository) Save(ctx context.Context, token string, record repo.TransactionToken, ttl time.Duration) error {
+	if m.Tokens == nil {
+		m.Tokens = make(map[string]repo.TransactionToken)
+		m.Consumed = make(map[string]bool)
+	}
+	m.Tokens[token] = record
+	return nil
+}
+
+func (m *MockTransactionTokenRepository) Consume(ctx context.Context, token, merchantID, payloadHash string) (*repo.TransactionToken, error) {
+	record, ok := m.Tokens[token]
+	if !ok {
+		return nil, repo.ErrTransactionTokenNotFound
+	}
+	if record.MerchantID != merchantID {
+		return nil, repo.ErrTransactionTokenMerchant
+	}
+	if m.Consumed[token] {
+		return nil, repo.ErrTransactionTokenConsumed
+	}
+	m.Consumed[token] = true
+	if record.PayloadHash != payloadHash {
+		return nil, repo.ErrTransactionTokenMismatch
+	}
+	return &record, nil
+}
+
+// Access token as validated by AuthMiddleware
+func merchantAccessToken(merchantID string) *jwt.Token {
+	return &jwt.Token{Claims: jwt.MapClaims{"user_id": merchantID, "type": "access"}, Valid: true}
+}
+
+func TestTransactionToken_ConsumeOnce(t *testing.T) {
+	svc := NewTransactionTokenService(&MockTransactionTokenRepository{}, time.Minute)
+
+	access := merchantAccessToken("STARK-1225")
+
+	issued, err := svc.IssueTransactionToken(context.Background(), access, dto.TransactionTokenRequest{
+		MerchantCode: "STARK-1225",
+		Amount:       "250000000.00",
+		Payload:      json.RawMessage(`{"beneficiary_account":"1234567890","bank_code":"014"}`),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	// Same payload with different key order and whitespace
+	consume := dto.TransactionTokenConsumeRequest{
+		Token:        issued.Token,
+		MerchantCode: "STARK-1225",
+		Amount:       "250000000.00",
+		Payload:      json.RawMessage(`{ "bank_code": "014", "beneficiary_account": "1234567890" }`),
+	}
+	if _, err := svc.ConsumeTransactionToken(context.Background(), access, consume); err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	if _, err := svc.ConsumeTransactionToken(context.Background(), access, consume); !errors.Is(err, ErrTransactionTokenRejected) {
+		t.Fatalf("Expected replay to be rejected, got %v", err)
+	}
+}
+
+func TestTransactionToken_PayloadMismatch(t *testing.T) {
+	svc := NewTransactionTokenService(&MockTransactionTokenRepository{}, time.Minute)
+
+	access := merchantAccessToken("STARK-1225")
+
+	issued, err := svc.IssueTransactionToken(context.Background(), access, dto.TransactionTokenRequest{
+		MerchantCode: "STARK-1225",
+		Amount:       "250000000.00",
+		Payload:      json.RawMessage(`{"beneficiary_account":"1234567890"}`),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	_, err = svc.ConsumeTransactionToken(context.Background(), access, dto.TransactionTokenConsumeRequest{
+		Token:   issued.Token,
+		Amount:  "990000000.00",
+		Payload: json.RawMessage(`{"beneficiary_account":"1234567890"}`),
+	})
+	if !errors.Is(err, ErrTransactionTokenRejected) {
+		t.Fatalf("Expected mismatch to be rejected, got %v", err)
+	}
+}
+
+func TestTransactionToken_OtherMerchant(t *testing.T) {
+	svc := NewTransactionTokenService(&MockTransactionTokenRepository{}, time.Minute)
+
+	access := merchantAccessToken("STARK-1225")
+
+	_, err := svc.IssueTransactionToken(context.Background(), access, dto.TransactionTokenRequest{
+		MerchantCode: "WAYNE-0001",
+		Amount:       "1000.00",
+		Payload:      json.RawMessage(`{}`),
+	})
+	if !errors.Is(err, ErrTransactionTokenRejected) {
+		t.Fatalf("Expected rejection, got %v", err)
+	}
+}
+
+func TestTransactionToken_ConsumeByOtherMerchant(t *testing.T) {
+	svc := NewTransactionTokenService(&MockTransactionTokenRepository{}, time.Minute)
+
+	owner := merchantAccessToken("STARK-1225")
+	other := merchantAccessToken("WAYNE-0001")
+
+	issued, err := svc.IssueTransactionToken(context.Background(), owner, dto.TransactionTokenRequest{
+		MerchantCode: "STARK-1225",
+		Amount:       "1000.00",
+		Payload:      json.RawMessage(`{"beneficiary_account":"1234567890"}`),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error: %v", err)
+	}
+
+	// Merchant code in body is ignored, caller is taken from access token
+	consume := dto.TransactionTokenConsumeRequest{
+		Token:        issued.Token,
+		MerchantCode: "STARK-1225",
+		Amount:       "1000.00",
+		Payload:      json.RawMessage(`{"beneficiary_account":"1234567890"}`),
+	}
+	if _, err := svc.ConsumeTransactionToken(context.Background(), other, consume); !errors.Is(err, ErrTransactionTokenRejected) {
+		t.Fatalf("Expected other merchant to be rejected, got %v", err)
+	}
+
+	consume.MerchantCode = ""
+	if _, err := svc.ConsumeTransactionToken(context.Background(), other, consume); !errors.Is(err, ErrTransactionTokenRejected) {
+		t.Fatalf("Expected other merchant to be rejected, got %v", err)
+	}
+
+	// Rejected attempts must not burn the token
+	if _, err := svc.ConsumeTransactionToken(context.Background(), owner, consume); err != nil {
+		t.Fatalf("Expected owner to consume token, got %v", err)
+	}
+}
@@ -23,15 +23,26 @@ type MockJWTRepository struct {
 	FindByAccessTokenErr     error
 	FindByRefreshTokenResult *entity.JwtToken
 	FindByRefreshTokenErr    error
-	DeleteByAccessTokenErr   error
+	RevokeByAccessTokenErr   error
+	FindByIDResult           *entity.JwtToken
+	FindByIDErr              error
+	FindActiveResult         []entity.JwtToken
+	FindActiveErr            error
+	DeleteExpiredResults     []int64
+	DeleteExpiredErr         error
+	ArchivedPartitions       []time.Time
+	FindHistoryResult        []entity.JwtTokenHistory
+	FindHistoryErr           error
 	WithTransactionCancelled bool
 	mock.Mock
 }
 
 func (m *MockJWTRepository) Save(ctx context.Context, token *entity.JwtToken) error {
+	if len(m.ExpectedCalls) == 0 {
+		return m.CreateErr
+	}
 	args := m.Called(ctx, token)
 	return args.Error(0)
-	//return m.CreateErr
 }
 
 func (m *MockJWTRepository) WithTransaction(tx *gorm.DB) repo.JwtRepository {
@@ -49,8 +60,58 @@ func (m *MockJWTRepository) FindByRefreshToken(ctx context.Context, token string
 	return m.FindByRefreshTokenResult, m.FindByRefreshTokenErr
 }
 
-func (m *MockJWTRepository) DeleteByAccessToken(ctx context.Context, token string) error {
-	return m.DeleteByAccessTokenErr
+func (m *MockJWTRepository) RevokeByAccessToken(ctx context.Context, token string) error {
+	return m.RevokeByAccessTokenErr
+}
+
+func (m *MockJWTRepository) FindByID(ctx context.Context, id int64) (*entity.JwtToken, error) {
+	return m.FindByIDResult, m.FindByIDErr
+}
+
+func (m *MockJWTRepository) FindActiveByMerchantID(ctx context.Context, merchantID string) ([]entity.JwtToken, error) {
+	return m.FindActiveResult, m.FindActiveErr
+}
+
+func (m *MockJWTRepository) FindActiveByAPIKeyID(ctx context.Context, apiKeyID int64) ([]entity.JwtToken, error) {
+	return m.FindActiveResult, m.FindActiveErr
+}
+
+func (m *MockJWTRepository) FindActiveByServiceAccountID(ctx context.Context, clientID string) ([]entity.JwtToken, error) {
+	return m.FindActiveResult, m.FindActiveErr
+}
+
+func (m *MockJWTRepository) CountActive(ctx context.Context) (int64, error) {
+	return int64(len(m.FindActiveResult)), m.FindActiveErr
+}
+
+func (m *MockJWTRepository) DeleteExpiredBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	if m.DeleteExpiredErr != nil || len(m.DeleteExpiredResults) == 0 {
+		return 0, m.DeleteExpiredErr
+	}
+	deleted := m.DeleteExpiredResults[0]
+	m.DeleteExpiredResults = m.DeleteExpiredResults[1:]
+	return deleted, nil
+}
+
+func (m *MockJWTRepository) ArchiveExpiredBatch(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return m.DeleteExpiredBatch(ctx, before, limit)
+}
+
+func (m *MockJWTRepository) FindExpiredRange(ctx context.Context, before time.Time) (*time.Time, *time.Time, error) {
+	return nil, nil, nil
+}
+
+func (m *MockJWTRepository) EnsureHistoryPartitions(ctx context.Context, from, to time.Time) error {
+	m.ArchivedPartitions = append(m.ArchivedPartitions, from, to)
+	return nil
+}
+
+func (m *MockJWTRepository) DropHistoryPartitionsBefore(ctx context.Context, before time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (m *MockJWTRepository) FindHistory(ctx context.Context, merchantID string, from, to time.Time, limit, offset int) ([]entity.JwtTokenHistory, error) {
+	return m.FindHistoryResult, m.FindHistoryErr
 }
 
 type MockRedisRepository struct {
@@ -70,6 +131,20 @@ func (r *MockRedisRepository) SetToken(ctx context.Context, key, value string, t
 	return nil
 }
 
+func (r *MockRedisRepository) SetTokenIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if r.Store == nil {
+		r.Store = make(map[string]string)
+	}
+	if r.Err != nil {
+		return false, r.Err
+	}
+	if _, ok := r.Store[key]; ok {
+		return false, nil
+	}
+	r.Store[key] = value
+	return true, nil
+}
+
 func (r *MockRedisRepository) GetToken(ctx context.Context, key string) (string, error) {
 	if r.Err != nil {
 		return "", r.Err
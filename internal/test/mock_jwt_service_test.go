@@ -21,7 +21,7 @@ func TestGenerateToken_Success(t *testing.T) {
 	}
 
 	jwtRepo.On("WithTransaction", mock.Anything).Return(jwtRepo)
-	jwtRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.JwtToken")).Return(nil)
+	jwtRepo.On("Save", mock.Anything, mock.AnythingOfType("*entity.JwtToken")).Return(nil)
 	redisRepo.On("SetToken", mock.Anything, mock.Anything, "valid", mock.Anything).Return(nil)
 	resp, err := svc.GenerateToken(context.Background(), req)
 
@@ -29,6 +29,6 @@ func TestGenerateToken_Success(t *testing.T) {
 	assert.NotNil(t, resp)
 	assert.NotEmpty(t, resp.AccessToken)
 	assert.NotEmpty(t, resp.RefreshToken)
-	jwtRepo.AssertCalled(t, "Create", mock.Anything, mock.AnythingOfType("*entity.JwtToken"))
+	jwtRepo.AssertCalled(t, "Save", mock.Anything, mock.AnythingOfType("*entity.JwtToken"))
 
 }
@@ -5,19 +5,28 @@ import (
 	controller "briefcash-jwt/internal/controller"
 	gormHelper "briefcash-jwt/internal/helper/dbhelper"
 	logHelper "briefcash-jwt/internal/helper/loghelper"
+	metricsHelper "briefcash-jwt/internal/helper/metricshelper"
 	redisHelper "briefcash-jwt/internal/helper/redishelper"
+	securityHelper "briefcash-jwt/internal/helper/securityhelper"
+	traceHelper "briefcash-jwt/internal/helper/tracehelper"
 	middleware "briefcash-jwt/internal/middleware"
 	repo "briefcash-jwt/internal/repository"
 	service "briefcash-jwt/internal/service"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"aidanwoods.dev/go-paseto"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
@@ -41,10 +50,16 @@ func main() {
 		logHelper.Logger.WithError(err).Fatal("Failed to load configuration")
 	}
 
-	// Build redis connection
+	// Install tracer before any client is built, so redis and gorm instrumentation pick it up
+	shutdownTracer, err := traceHelper.InitTracer(ctx, cfg.TraceExporter)
+	if err != nil {
+		logHelper.Logger.WithError(err).Fatal("Failed to initialize tracing")
+	}
+
+	// Build redis connection, unreachable redis is retried by warm-up below
 	redisClient, err := redisHelper.NewRedisAdapter(cfg)
 	if err != nil {
-		logHelper.Logger.WithError(err).Fatal("failed to connect to redis")
+		logHelper.Logger.WithError(err).Fatal("Invalid redis configuration")
 	}
 	defer redisClient.Close()
 
@@ -64,32 +79,164 @@ func main() {
 	}
 	defer dbHelper.Close()
 
+	// Dedicated connection for postgres notifications, merchant status changes are pushed by trigger
+	dbPort, err := strconv.Atoi(cfg.DbPort)
+	if err != nil {
+		logHelper.Logger.WithError(err).Fatal("Invalid DB_PORT value")
+	}
+
+	pgxHelper, err := gormHelper.NewPgxHelper(gormHelper.PgxConfig{
+		Host:     cfg.DbAddress,
+		Port:     dbPort,
+		User:     cfg.DbUsername,
+		Password: cfg.DbPassword,
+		DBName:   cfg.DbName,
+		SSLMode:  "disable",
+	})
+	if err != nil {
+		logHelper.Logger.WithError(err).Fatal("failed to connect to database")
+	}
+	defer pgxHelper.Close()
+
 	// Create repository instance
 	jwtRepo := repo.NewJwtRepository(dbHelper.DB)
 	merchantRepo := repo.NewMerchantRepository(dbHelper.DB)
+	merchantSettingsRepo := repo.NewMerchantSettingsRepository(dbHelper.DB)
 	redisRepo := repo.NewRedisRepository(redisClient.Client)
 	merchantRedisRepo := repo.NewMerchantRedisRepository(redisClient.Client)
+	leaseRepo := repo.NewLeaseRepository(redisClient.Client)
+	revocationRepo := repo.NewRevocationRepository(redisClient.Client)
+	serviceAccountRepo := repo.NewServiceAccountRepository(dbHelper.DB)
+	merchantKeyRepo := repo.NewMerchantAPIKeyRepository(dbHelper.DB)
+	transactionTokenRepo := repo.NewTransactionTokenRepository(redisClient.Client)
+	merchantStatusRepo := repo.NewMerchantStatusRepository(pgxHelper.Pool)
 
 	// Create service instance
-	jwtService := service.NewTokenService(jwtRepo, redisRepo, dbHelper.DB, cfg.JWTSecret)
-	merchantService := service.NewMerchantService(merchantRepo, merchantRedisRepo)
+	// Revocations are always published so replicas switched to stateless mode see them
+	revocationList := service.NewRevocationList(revocationRepo, cfg.RevocationSyncInterval)
+	tokenEncrypter, err := loadTokenEncrypter(cfg.JWEKeys)
+	if err != nil {
+		logHelper.Logger.WithError(err).Fatal("Failed to load token encryption keys")
+	}
 
-	// Load list of merchant code from database to redis
-	if err := merchantService.CachingCode(ctx); err != nil {
-		logHelper.Logger.WithError(err).Fatal("Failed to load merchant code to redis")
+	pasetoPublic, pasetoLocal, err := loadPasetoFormats(cfg)
+	if err != nil {
+		logHelper.Logger.WithError(err).Fatal("Failed to load paseto keys")
+	}
+
+	jwtService := service.TraceTokenService(service.NewTokenService(jwtRepo, redisRepo, merchantSettingsRepo, dbHelper.DB, revocationList, service.TokenConfig{
+		Secret:          cfg.JWTSecret,
+		ValidationMode:  cfg.ValidationMode,
+		DefaultFormat:   cfg.TokenFormat,
+		Encrypter:       tokenEncrypter,
+		PasetoPublic:    pasetoPublic,
+		PasetoLocal:     pasetoLocal,
+		DPoPProofMaxAge: cfg.DPoPProofMaxAge,
+
+		ExchangeAudiences: cfg.ExchangeAudiences,
+		ExchangeTTL:       cfg.ExchangeTTL,
+
+		ServiceAccounts:    serviceAccountRepo,
+		MerchantKeys:       merchantKeyRepo,
+		RequireMerchantKey: cfg.MerchantKeyRequired,
+	}))
+	merchantService := service.TraceMerchantService(service.NewMerchantService(merchantRepo, merchantRedisRepo, merchantSettingsRepo, dbHelper.DB))
+	serviceAccountService := service.NewServiceAccountService(serviceAccountRepo, jwtService)
+	merchantKeyService := service.NewMerchantKeyService(merchantKeyRepo, merchantRepo, jwtService, dbHelper.DB, cfg.MerchantKeyGrace)
+	transactionTokenService := service.NewTransactionTokenService(transactionTokenRepo, cfg.TransactionTokenTTL)
+	purgeService := service.NewTokenPurgeService(jwtRepo, leaseRepo, service.PurgeConfig{
+		Interval:  cfg.TokenPurgeInterval,
+		Retention: cfg.TokenPurgeRetention,
+		BatchSize: cfg.TokenPurgeBatchSize,
+
+		Archive:          cfg.TokenPurgeArchive,
+		HistoryRetention: cfg.TokenHistoryRetention,
+	})
+
+	// Reach redis and database, then load merchant codes to redis. Service starts degraded and
+	// reports not ready until every step succeeded, failed steps are retried with backoff.
+	readiness := service.NewReadiness()
+	readiness.WarmUp(ctx,
+		service.WarmUpStep{Name: "redis", Run: redisClient.Ping},
+		service.WarmUpStep{Name: "database", Run: dbHelper.Ping},
+		service.WarmUpStep{Name: "merchant_cache", Run: func(ctx context.Context) error {
+			_, err := merchantService.CachingCode(ctx)
+			return err
+		}},
+	)
+
+	// Checked on every readiness probe, each one reported with its latency
+	readiness.AddChecks(
+		service.HealthCheck{Name: "postgres", Run: dbHelper.Ping},
+		service.HealthCheck{Name: "redis", Run: redisClient.Ping},
+		service.HealthCheck{Name: "signing_key", Run: func(ctx context.Context) error {
+			return jwtService.CheckSigningKeys()
+		}},
+		service.HealthCheck{Name: "merchant_cache", Run: func(ctx context.Context) error {
+			if !merchantService.CacheReady() {
+				return fmt.Errorf("merchant cache is not loaded")
+			}
+			return nil
+		}},
+	)
+
+	// Keep merchant allow-list in memory, following changes published by every replica
+	merchantService.StartCache(ctx)
+
+	// Repair drift between merchant table and redis set, only one replica runs it at a time
+	merchantService.StartReconciler(ctx, leaseRepo, cfg.MerchantSyncInterval)
+	service.RegisterDriftMetrics(merchantService)
+
+	// Apply merchant activation changes made anywhere in db right away, revoking tokens of deactivated merchants
+	merchantService.StartStatusListener(ctx, merchantStatusRepo, jwtService)
+
+	// Start background job for purging expired token
+	purgeService.Start(ctx)
+	service.RegisterPurgeMetrics(purgeService)
+
+	// Export active sessions and merchants, sessions are counted in background to keep scrapes cheap
+	service.StartSessionMetrics(ctx, jwtService, 0)
+	metricsHelper.RegisterGaugeFunc("active_merchants", "Active merchant codes held in memory.", func() float64 {
+		return float64(merchantService.ActiveMerchants())
+	})
+
+	// Follow revoked token list, only read when validating without redis/db lookup
+	if cfg.ValidationMode == service.ValidationStateless {
+		revocationList.Start(ctx)
 	}
 
 	// Create controller instance
 	jwtController := controller.NewTokenController(jwtService)
 	merchantController := controller.NewMerchantController(merchantService)
+	sessionController := controller.NewSessionController(jwtService)
+	serviceAccountController := controller.NewServiceAccountController(serviceAccountService)
+	merchantKeyController := controller.NewMerchantKeyController(merchantKeyService)
+	transactionTokenController := controller.NewTransactionTokenController(transactionTokenService)
+	healthController := controller.NewHealthController(readiness)
 
 	// Create middleware instance
-	mw := middleware.NewMiddleware(merchantService)
+	mw := middleware.NewMiddleware(merchantService, jwtService, cfg.AdminAPIKeys)
 
 	// Init http connection
 	router := gin.New()
 	router.Use(gin.Recovery())
+
+	// Forwarded client address and original request are honoured only from configured proxies
+	trustedProxies := make([]string, 0, len(cfg.TrustedProxies))
+	for _, proxy := range cfg.TrustedProxies {
+		trustedProxies = append(trustedProxies, proxy.String())
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		logHelper.Logger.WithError(err).Fatal("Failed to set trusted proxies")
+	}
+	router.Use(middleware.TrustedProxyMiddleware(cfg.TrustedProxies))
+	router.Use(otelgin.Middleware(traceHelper.ServiceName, otelgin.WithFilter(tracedRequest)))
 	router.Use(RequestLoggerMiddleware())
+	router.Use(MetricsMiddleware())
+
+	router.GET("/healthz", healthController.Alive)
+	router.GET("/readyz", healthController.Ready)
+	router.GET("/metrics", gin.WrapH(metricsHelper.Handler()))
 
 	api := router.Group("/api/v1")
 	{
@@ -97,27 +244,72 @@ func main() {
 		{
 			token.POST("/generate", jwtController.GenerateToken)
 			token.POST("/refresh", jwtController.RefreshToken)
+			token.POST("/exchange", jwtController.ExchangeToken)
+			token.POST("/service", jwtController.GenerateServiceToken)
 			token.POST("/validate", mw.AuthMiddleware(), jwtController.ValidateToken)
 			token.POST("/logout", mw.AuthMiddleware(), jwtController.Logout)
+			token.GET("/jwks", jwtController.EncryptionKeys)
+			token.POST("/transaction/issue", mw.AuthMiddleware(), transactionTokenController.IssueTransactionToken)
+			token.POST("/transaction/consume", mw.AuthMiddleware(), transactionTokenController.ConsumeTransactionToken)
 		}
 
-		merchant := api.Group("/merchant")
+		session := api.Group("/session", mw.AdminMiddleware())
+		{
+			session.POST("/list", sessionController.ListSessions)
+			session.POST("/terminate", sessionController.TerminateSession)
+			session.POST("/history", sessionController.SessionHistory)
+		}
+
+		serviceAccount := api.Group("/service-account", mw.AdminMiddleware())
+		{
+			serviceAccount.POST("/create", serviceAccountController.CreateServiceAccount)
+			serviceAccount.POST("/enable", serviceAccountController.EnableServiceAccount)
+			serviceAccount.POST("/disable", serviceAccountController.DisableServiceAccount)
+		}
+
+		merchant := api.Group("/merchant", mw.AdminMiddleware())
 		{
 			merchant.POST("/sync", gin.WrapF(merchantController.SyncMerchantCode))
 			merchant.POST("/add", gin.WrapF(merchantController.AddMerchantCode))
 			merchant.POST("/remove", gin.WrapF(merchantController.RemoveMerchantCode))
+			merchant.POST("/suspend", gin.WrapF(merchantController.SuspendMerchant))
+			merchant.POST("/create", gin.WrapF(merchantController.CreateMerchant))
+			merchant.POST("/update", gin.WrapF(merchantController.UpdateMerchant))
+			merchant.POST("/detail", gin.WrapF(merchantController.GetMerchant))
+			merchant.POST("/list", gin.WrapF(merchantController.ListMerchants))
+			merchant.POST("/api-key/generate", merchantKeyController.GenerateKey)
+			merchant.POST("/api-key/rotate", merchantKeyController.RotateKey)
+			merchant.POST("/api-key/revoke", merchantKeyController.RevokeKey)
+			merchant.POST("/api-key/list", merchantKeyController.ListKeys)
 		}
 	}
 
+	tlsConfig, err := loadTLSConfig(cfg)
+	if err != nil {
+		logHelper.Logger.WithError(err).Fatal("Failed to load TLS configuration")
+	}
+
 	server := &http.Server{
-		Addr:    cfg.AppPort,
-		Handler: router,
+		Addr:      cfg.AppPort,
+		Handler:   router,
+		TLSConfig: tlsConfig,
 	}
 
 	// Create goroutine for openning http connection
 	go func() {
-		logHelper.Logger.WithField("port", cfg.AppPort).Info("JWT Service is running...")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logHelper.Logger.WithFields(logrus.Fields{
+			"port": cfg.AppPort,
+			"tls":  tlsConfig != nil,
+		}).Info("JWT Service is running...")
+
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+
+		if err != nil && err != http.ErrServerClosed {
 			logHelper.Logger.WithError(err).Fatal("Failed to start JWT Service")
 		}
 	}()
@@ -137,6 +329,11 @@ func main() {
 		logHelper.Logger.Info("JWT Service shutdown completed")
 	}
 
+	// Flush spans still buffered by exporter
+	if err := shutdownTracer(shutdownCtx); err != nil {
+		logHelper.Logger.WithError(err).Warn("Failed to flush pending traces")
+	}
+
 }
 
 // Middleware function for logging http activity
@@ -155,6 +352,111 @@ func RequestLoggerMiddleware() gin.HandlerFunc {
 			"status":   status,
 			"duration": duration.String(),
 			"clientIp": c.ClientIP(),
+			"traceId":  traceHelper.TraceID(c.Request.Context()),
 		}).Info("Handled request")
 	}
 }
+
+// Probes and scrapes are not traced, they would crowd out real requests
+func tracedRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/healthz", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
+// Middleware function for counting and timing http requests per route
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// Unknown paths share one label, raw path would make every probe a new series
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metricsHelper.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// Load private keys for jwe token, encryption is disabled when no key is configured
+func loadTokenEncrypter(keyFiles []config.KeyFile) (*service.TokenEncrypter, error) {
+	if len(keyFiles) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]service.EncryptionKey, 0, len(keyFiles))
+	for _, keyFile := range keyFiles {
+		privateKey, err := securityHelper.LoadPrivateKey(keyFile.Path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, service.EncryptionKey{KeyID: keyFile.KeyID, PrivateKey: privateKey})
+	}
+
+	return service.NewTokenEncrypter(keys)
+}
+
+// Load PASETO v4 keys from hex encoded config, each format is disabled when its key is empty
+func loadPasetoFormats(cfg *config.Config) (service.TokenFormat, service.TokenFormat, error) {
+	var public, local service.TokenFormat
+
+	if cfg.PasetoSecretKey != "" {
+		secretKey, err := paseto.NewV4AsymmetricSecretKeyFromHex(cfg.PasetoSecretKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid PASETO_V4_SECRET_KEY: %w", err)
+		}
+		public = service.NewPasetoPublicFormat(secretKey)
+	}
+
+	if cfg.PasetoLocalKey != "" {
+		localKey, err := paseto.V4SymmetricKeyFromHex(cfg.PasetoLocalKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid PASETO_V4_LOCAL_KEY: %w", err)
+		}
+		local = service.NewPasetoLocalFormat(localKey)
+	}
+
+	switch {
+	case cfg.TokenFormat == service.TokenFormatPasetoPublic && public == nil:
+		return nil, nil, fmt.Errorf("TOKEN_FORMAT %s requires PASETO_V4_SECRET_KEY", cfg.TokenFormat)
+	case cfg.TokenFormat == service.TokenFormatPasetoLocal && local == nil:
+		return nil, nil, fmt.Errorf("TOKEN_FORMAT %s requires PASETO_V4_LOCAL_KEY", cfg.TokenFormat)
+	}
+
+	return public, local, nil
+}
+
+// Build TLS server config, client certificate is verified against CA bundle when configured
+func loadTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	bundle, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS_CLIENT_CA_FILE: %w", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("TLS_CLIENT_CA_FILE contains no PEM certificate")
+	}
+
+	tlsConfig.ClientCAs = clientCAs
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	if cfg.TLSClientAuth == "required" {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,8 @@
+-- Capture client information at issuance so active sessions can be listed per merchant
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS refreshed_at timestamp without time zone,
+    ADD COLUMN IF NOT EXISTS client_ip character varying(60),
+    ADD COLUMN IF NOT EXISTS user_agent character varying(255);
+
+CREATE INDEX IF NOT EXISTS jwt_token_merchant_settings_id_idx
+    ON public.jwt_token (merchant_settings_id, expires_at);
@@ -0,0 +1,3 @@
+-- Support batch purge of expired token
+CREATE INDEX IF NOT EXISTS jwt_token_expires_at_idx
+    ON public.jwt_token (expires_at);
@@ -0,0 +1,19 @@
+-- Archive of expired and revoked token, partitioned monthly by created_at.
+-- Monthly partitions are created by the purge job before rows are moved.
+CREATE TABLE IF NOT EXISTS public.jwt_token_history (
+    id bigint NOT NULL,
+    merchant_settings_id character varying(20),
+    access_token_hash character varying(64),
+    refresh_token_hash character varying(64),
+    client_ip character varying(60),
+    user_agent character varying(255),
+    created_at timestamp without time zone NOT NULL,
+    expires_at timestamp without time zone,
+    refreshed_at timestamp without time zone,
+    is_revoke boolean,
+    archived_at timestamp without time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX IF NOT EXISTS jwt_token_history_merchant_created_idx
+    ON public.jwt_token_history (merchant_settings_id, created_at);
@@ -0,0 +1,8 @@
+-- Token format chosen per merchant: jwt (default) or opaque reference token
+ALTER TABLE public.merchant_settings
+    ADD COLUMN IF NOT EXISTS token_format character varying(20) NOT NULL DEFAULT 'jwt';
+
+-- Opaque token keep its claims server side
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS token_format character varying(20) NOT NULL DEFAULT 'jwt',
+    ADD COLUMN IF NOT EXISTS claims jsonb;
@@ -0,0 +1,3 @@
+-- Encrypted token (nested signed-then-encrypted JWE) is a new merchant_settings.token_format value: jwe
+-- JWE is longer than the signed JWT, access_token and refresh_token are already text so no column change is required.
+COMMENT ON COLUMN public.merchant_settings.token_format IS 'jwt, opaque or jwe';
@@ -0,0 +1,2 @@
+-- PASETO v4 formats are new merchant_settings.token_format values
+COMMENT ON COLUMN public.merchant_settings.token_format IS 'jwt, opaque, jwe, paseto.v4.public or paseto.v4.local';
@@ -0,0 +1,3 @@
+-- Thumbprint of DPoP key the access token is bound to (RFC 9449), empty for bearer token
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS dpop_jkt character varying(64) NOT NULL DEFAULT '';
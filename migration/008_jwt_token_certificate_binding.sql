@@ -0,0 +1,3 @@
+-- Thumbprint of mutual TLS client certificate the access token is bound to (RFC 8705), empty for bearer token
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS cert_thumbprint character varying(64) NOT NULL DEFAULT '';
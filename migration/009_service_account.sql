@@ -0,0 +1,19 @@
+-- Machine identities for internal services (reconciliation, reversal workers), separate from merchants
+CREATE TABLE IF NOT EXISTS public.service_account (
+    id bigserial PRIMARY KEY,
+    client_id character varying(50) NOT NULL UNIQUE,
+    name character varying(100) NOT NULL,
+    secret_hash character varying(100) NOT NULL,
+    scopes text NOT NULL DEFAULT '',
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    last_used_at timestamp without time zone
+);
+
+-- Token issued to service account keeps merchant_settings_id empty
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS service_account_id character varying(50);
+
+CREATE INDEX IF NOT EXISTS jwt_token_service_account_id_idx
+    ON public.jwt_token (service_account_id)
+    WHERE service_account_id IS NOT NULL;
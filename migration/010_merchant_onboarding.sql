@@ -0,0 +1,21 @@
+-- Merchant onboarding creates details and settings rows through the API, ids come from sequences
+CREATE SEQUENCE IF NOT EXISTS public.merchant_details_sq OWNED BY public.merchant_details.id;
+SELECT setval('public.merchant_details_sq', COALESCE((SELECT MAX(id) FROM public.merchant_details), 0) + 1, false);
+ALTER TABLE public.merchant_details
+    ALTER COLUMN id SET DEFAULT nextval('public.merchant_details_sq'::regclass);
+
+CREATE SEQUENCE IF NOT EXISTS public.merchant_settings_sq OWNED BY public.merchant_settings.id;
+SELECT setval('public.merchant_settings_sq', COALESCE((SELECT MAX(id) FROM public.merchant_settings), 0) + 1, false);
+ALTER TABLE public.merchant_settings
+    ALTER COLUMN id SET DEFAULT nextval('public.merchant_settings_sq'::regclass);
+
+-- One details and one settings row per merchant
+CREATE UNIQUE INDEX IF NOT EXISTS merchant_details_merchant_code_uidx
+    ON public.merchant_details (merchant_code);
+
+CREATE UNIQUE INDEX IF NOT EXISTS merchant_settings_merchant_code_uidx
+    ON public.merchant_settings (merchant_code);
+
+-- Merchant list filters by status and date joined
+CREATE INDEX IF NOT EXISTS merchant_is_active_date_joined_idx
+    ON public.merchant (is_active, date_joined);
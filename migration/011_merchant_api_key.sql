@@ -0,0 +1,35 @@
+-- Merchant API credentials, secret is kept only as bcrypt hash.
+-- A merchant has one current key, plus the previous key until its rotation grace window ends.
+CREATE TABLE IF NOT EXISTS public.merchant_api_key (
+    id bigserial PRIMARY KEY,
+    merchant_code character varying(20) NOT NULL,
+    api_key character varying(100) NOT NULL UNIQUE,
+    secret_hash character varying(100) NOT NULL,
+    created_at timestamp without time zone NOT NULL DEFAULT now(),
+    expires_at timestamp without time zone,
+    revoked_at timestamp without time zone,
+    revoked_by character varying(100)
+);
+
+CREATE INDEX IF NOT EXISTS merchant_api_key_merchant_code_idx
+    ON public.merchant_api_key (merchant_code)
+    WHERE revoked_at IS NULL;
+
+-- Move plaintext credentials over, pgcrypto bf hashes are readable by bcrypt
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+INSERT INTO public.merchant_api_key (merchant_code, api_key, secret_hash)
+SELECT merchant_code, api_key, crypt(api_secret, gen_salt('bf', 10))
+FROM public.merchant_settings
+WHERE COALESCE(api_key, '') <> '' AND COALESCE(api_secret, '') <> ''
+ON CONFLICT (api_key) DO NOTHING;
+
+UPDATE public.merchant_settings SET api_secret = NULL WHERE api_secret IS NOT NULL;
+
+-- Key used to issue token, revoking the key revokes its tokens
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS api_key_id bigint;
+
+CREATE INDEX IF NOT EXISTS jwt_token_api_key_id_idx
+    ON public.jwt_token (api_key_id)
+    WHERE api_key_id IS NOT NULL;
@@ -0,0 +1,18 @@
+-- Publish merchant activation changes so every change, including ones made directly in the back office, reaches redis right away
+CREATE OR REPLACE FUNCTION public.notify_merchant_status() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('merchant_status', json_build_object(
+        'code', NEW.code,
+        'is_active', NEW.is_active
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS merchant_status_notify ON public.merchant;
+
+CREATE TRIGGER merchant_status_notify
+    AFTER UPDATE OF is_active ON public.merchant
+    FOR EACH ROW
+    WHEN (OLD.is_active IS DISTINCT FROM NEW.is_active)
+    EXECUTE FUNCTION public.notify_merchant_status();
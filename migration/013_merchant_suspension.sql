@@ -0,0 +1,11 @@
+-- Temporary suspension keeps merchant inactive with its context, merchant is reactivated once suspended_until passes
+ALTER TABLE public.merchant
+    ADD COLUMN IF NOT EXISTS suspended_reason character varying(200),
+    ADD COLUMN IF NOT EXISTS suspended_by character varying(100),
+    ADD COLUMN IF NOT EXISTS suspended_at timestamp without time zone,
+    ADD COLUMN IF NOT EXISTS suspended_until timestamp without time zone;
+
+-- Reactivation job looks up suspensions which already ended
+CREATE INDEX IF NOT EXISTS merchant_suspended_until_idx
+    ON public.merchant (suspended_until)
+    WHERE suspended_until IS NOT NULL AND is_active = false;
@@ -0,0 +1,15 @@
+-- Keep token row until its refresh token expires, purge used access token expiry before
+ALTER TABLE public.jwt_token
+    ADD COLUMN IF NOT EXISTS refresh_expires_at timestamp without time zone;
+
+-- Existing refresh token lives 7 days from issuance
+UPDATE public.jwt_token
+    SET refresh_expires_at = CASE
+        WHEN COALESCE(refresh_token, '') <> '' THEN COALESCE(refreshed_at, created_at, expires_at) + interval '7 days'
+        ELSE expires_at
+    END
+    WHERE refresh_expires_at IS NULL;
+
+-- Support batch purge of expired token
+CREATE INDEX IF NOT EXISTS jwt_token_refresh_expires_at_idx
+    ON public.jwt_token ((COALESCE(refresh_expires_at, expires_at)));